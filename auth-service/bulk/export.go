@@ -0,0 +1,135 @@
+package bulk
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+// exportPageSize — скільки користувачів читається з БД за один запит.
+const exportPageSize = 500
+
+// ExportedUser — користувач в експорті. Хеш пароля не вивантажується.
+type ExportedUser struct {
+	ID              string     `json:"id"`
+	Login           string     `json:"login"`
+	Email           string     `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Status          string     `json:"status" example:"active"`
+	Roles           []string   `json:"roles"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
+var csvHeader = []string{"id", "login", "email", "email_verified_at", "status", "roles", "created_at", "updated_at"}
+
+// Export пише користувачів, що відповідають filter (Limit і Offset
+// ігноруються), у форматі FormatCSV або FormatJSON. Результат можна знову
+// імпортувати: колонки id, created_at та інші службові пропускаються.
+func Export(ctx context.Context, w io.Writer, format string, filter db.UserFilter) error {
+	if format != FormatCSV && format != FormatJSON {
+		return fmt.Errorf("invalid format %q: expected csv or json", format)
+	}
+
+	var write func(u *ExportedUser) error
+	var finish func() error
+	if format == FormatCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		write = func(u *ExportedUser) error {
+			return cw.Write([]string{
+				u.ID,
+				u.Login,
+				utils.CSVCell(u.Email),
+				formatTime(u.EmailVerifiedAt),
+				u.Status,
+				strings.Join(u.Roles, ","),
+				u.CreatedAt.UTC().Format(time.RFC3339),
+				formatTime(u.UpdatedAt),
+			})
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	} else {
+		enc := json.NewEncoder(w)
+		sep := "["
+		write = func(u *ExportedUser) error {
+			if _, err := io.WriteString(w, sep); err != nil {
+				return err
+			}
+			sep = ","
+			return enc.Encode(u)
+		}
+		finish = func() error {
+			if sep == "[" {
+				if _, err := io.WriteString(w, sep); err != nil {
+					return err
+				}
+			}
+			_, err := io.WriteString(w, "]\n")
+			return err
+		}
+	}
+
+	users := db.NewUserRepository(db.DB)
+	roles := db.NewRoleRepository(db.DB)
+	filter.Limit = exportPageSize
+	for filter.Offset = 0; ; filter.Offset += exportPageSize {
+		page, err := users.ListUsers(ctx, filter)
+		if err != nil {
+			return err
+		}
+		ids := make([]string, len(page))
+		for i := range page {
+			ids[i] = page[i].ID
+		}
+		userRoles, err := roles.GetRolesForUsers(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			if err := write(newExportedUser(&page[i], userRoles[page[i].ID])); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+	return finish()
+}
+
+func newExportedUser(u *models.User, roles []string) *ExportedUser {
+	if roles == nil {
+		roles = []string{}
+	}
+	return &ExportedUser{
+		ID:              u.ID,
+		Login:           u.Login,
+		Email:           u.Email,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		Status:          u.Status,
+		Roles:           roles,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,586 @@
+// Package bulk імпортує користувачів з CSV або JSON і експортує їх. Його
+// використовують і адміністративний ендпоінт, і команда CLI.
+package bulk
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/passwords"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+// MaxRecords обмежує кількість рядків в одному імпорті.
+const MaxRecords = 10000
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Результат імпорту рядка.
+const (
+	ActionCreated   = "created"
+	ActionUpdated   = "updated"
+	ActionUnchanged = "unchanged"
+	ActionFailed    = "failed"
+)
+
+// Поля, які імпорт змінює в існуючого користувача.
+const (
+	ChangeEmail    = "email"
+	ChangeStatus   = "status"
+	ChangeRoles    = "roles"
+	ChangePassword = "password"
+	// ChangeEmailVerified — email, що не змінився, позначено підтвердженим.
+	ChangeEmailVerified = "email_verified"
+)
+
+const (
+	// generatedPasswordBytes дає пароль з 32 символів base64url.
+	generatedPasswordBytes = 24
+	// maxGenerateAttempts — скільки разів генерувати пароль, поки він не
+	// відповідатиме політиці (наприклад, не міститиме потрібних класів символів).
+	maxGenerateAttempts = 10
+)
+
+// Record — рядок імпорту. Користувач шукається за Login; порожні поля
+// існуючого користувача не змінюються. Password перевіряється політикою
+// паролів, PasswordHash — готовий bcrypt хеш. Email з імпорту вважається
+// непідтвердженим, якщо не задано EmailVerified.
+type Record struct {
+	Login        string   `json:"login"`
+	Email        string   `json:"email,omitempty"`
+	Status       string   `json:"status,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+	Password     string   `json:"password,omitempty"`
+	PasswordHash string   `json:"password_hash,omitempty"`
+
+	EmailVerified bool `json:"email_verified,omitempty"`
+
+	// Row — номер рядка CSV (заголовок — рядок 1) або позиція в масиві JSON
+	// (з 1), щоб помилки вказували на місце у файлі.
+	Row int `json:"-"`
+}
+
+// Options керують імпортом.
+type Options struct {
+	// DryRun лише перевіряє рядки і показує, що буде зроблено.
+	DryRun bool
+	// GeneratePasswords генерує пароль новим користувачам без password і
+	// password_hash; пароль повертається в RowResult один раз.
+	GeneratePasswords bool
+	// ActorID — хто виконує імпорт: він не може вимкнути себе чи зняти з
+	// себе роль admin.
+	ActorID string
+	// HasPermission повідомляє, чи має автор імпорту дозвіл. Імпорт не
+	// надає ролей з дозволами, яких немає в автора, і не змінює користувачів
+	// з такими дозволами. nil — без обмежень (команда CLI).
+	HasPermission func(permission string) bool
+}
+
+type RowResult struct {
+	Row    int    `json:"row"`
+	Login  string `json:"login"`
+	UserID string `json:"user_id,omitempty"`
+	Action string `json:"action" example:"created"`
+	// Changes — змінені поля існуючого користувача.
+	Changes []string `json:"changes,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	// Password — згенерований пароль; показується лише в цій відповіді.
+	Password string `json:"password,omitempty"`
+}
+
+// Summary описує результат рядка для журналу аудиту, наприклад
+// "updated: email, roles".
+func (r RowResult) Summary() string {
+	if len(r.Changes) == 0 {
+		return r.Action
+	}
+	return r.Action + ": " + strings.Join(r.Changes, ", ")
+}
+
+type Result struct {
+	DryRun    bool        `json:"dry_run"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Failed    int         `json:"failed"`
+	Rows      []RowResult `json:"rows"`
+}
+
+// Parse читає рядки імпорту у форматі FormatCSV або FormatJSON.
+func Parse(r io.Reader, format string) ([]Record, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r)
+	case FormatJSON:
+		return ParseJSON(r)
+	}
+	return nil, fmt.Errorf("invalid format %q: expected csv or json", format)
+}
+
+// ParseCSV читає CSV із заголовком. Обов'язкова лише колонка login; невідомі
+// колонки (наприклад, id і created_at з експорту) пропускаються. Ролі в
+// комірці roles розділяються комами, крапками з комою або пробілами.
+func ParseCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("CSV header is missing")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			// Excel додає BOM на початок файлу UTF-8.
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["login"]; !ok {
+		return nil, errors.New("CSV header must contain a login column")
+	}
+
+	records := []Record{}
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(records) == MaxRecords {
+			return nil, fmt.Errorf("too many rows: at most %d", MaxRecords)
+		}
+		cell := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(fields) {
+				return ""
+			}
+			return strings.TrimSpace(fields[i])
+		}
+		line, _ := cr.FieldPos(0)
+		verified := false
+		if v := cell("email_verified"); v != "" {
+			if verified, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("invalid email_verified in row %d: expected true or false", line)
+			}
+		}
+		records = append(records, Record{
+			Login:        cell("login"),
+			Email:        cell("email"),
+			Status:       cell("status"),
+			Roles:        splitRoles(cell("roles")),
+			Password:     cell("password"),
+			PasswordHash: cell("password_hash"),
+			Row:          line,
+
+			EmailVerified: verified,
+		})
+	}
+	return records, nil
+}
+
+func splitRoles(cell string) []string {
+	return strings.FieldsFunc(cell, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t'
+	})
+}
+
+// ParseJSON читає масив об'єктів Record. Невідомі поля пропускаються, тож
+// можна імпортувати результат експорту.
+func ParseJSON(r io.Reader) ([]Record, error) {
+	var records []Record
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if len(records) > MaxRecords {
+		return nil, fmt.Errorf("too many rows: at most %d", MaxRecords)
+	}
+	for i := range records {
+		records[i].Row = i + 1
+		records[i].Login = strings.TrimSpace(records[i].Login)
+	}
+	return records, nil
+}
+
+// Importer створює або оновлює користувачів за логіном, тож повторний імпорт
+// того самого файлу нічого не змінює.
+type Importer struct {
+	policy *passwords.Policy
+}
+
+func NewImporter(policy *passwords.Policy) *Importer {
+	return &Importer{policy: policy}
+}
+
+// Import обробляє рядки по одному: помилка в рядку не зупиняє імпорт, а
+// записується в його результат. Помилка повертається лише при збої БД;
+// рядки, оброблені до неї, уже збережено.
+func (im *Importer) Import(ctx context.Context, records []Record, opts Options) (*Result, error) {
+	roles, err := db.NewRoleRepository(db.DB).ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string][]string{}
+	for _, role := range roles {
+		known[role.Name] = role.Permissions
+	}
+
+	result := &Result{DryRun: opts.DryRun, Rows: make([]RowResult, 0, len(records))}
+	logins := map[string]int{}
+	emails := map[string]int{}
+	for _, rec := range records {
+		var row RowResult
+		email, _ := utils.NormalizeEmail(rec.Email)
+		if prev, ok := logins[rec.Login]; ok {
+			row = failed(rec, fmt.Sprintf("Duplicate login, see row %d", prev))
+		} else if prev, ok := emails[email]; ok && email != "" {
+			row = failed(rec, fmt.Sprintf("Duplicate email, see row %d", prev))
+		} else {
+			logins[rec.Login] = rec.Row
+			if email != "" {
+				emails[email] = rec.Row
+			}
+			row, err = im.importRecord(ctx, rec, opts, known)
+			if err != nil {
+				return result, err
+			}
+		}
+
+		switch row.Action {
+		case ActionCreated:
+			result.Created++
+		case ActionUpdated:
+			result.Updated++
+		case ActionUnchanged:
+			result.Unchanged++
+		default:
+			result.Failed++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+func failed(rec Record, message string) RowResult {
+	return RowResult{Row: rec.Row, Login: rec.Login, Action: ActionFailed, Error: message}
+}
+
+func (im *Importer) importRecord(ctx context.Context, rec Record, opts Options, known map[string][]string) (RowResult, error) {
+	if !utils.ValidLogin(rec.Login) {
+		return failed(rec, "Login must be 3-32 characters: letters, digits, '.', '_' or '-', starting with a letter or digit"), nil
+	}
+	email, ok := utils.NormalizeEmail(rec.Email)
+	if !ok {
+		return failed(rec, "Invalid email"), nil
+	}
+	switch rec.Status {
+	case "", models.UserStatusPending, models.UserStatusActive, models.UserStatusDisabled:
+	default:
+		return failed(rec, "Invalid status: expected active, pending or disabled"), nil
+	}
+	if rec.Password != "" && rec.PasswordHash != "" {
+		return failed(rec, "Specify either password or password_hash, not both"), nil
+	}
+	if rec.PasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(rec.PasswordHash)); err != nil {
+			return failed(rec, "password_hash must be a bcrypt hash"), nil
+		}
+	}
+	for _, role := range rec.Roles {
+		if _, ok := known[role]; !ok {
+			return failed(rec, "Unknown role: "+role), nil
+		}
+	}
+
+	users := db.NewUserRepository(db.DB)
+	user, err := users.GetUserByLogin(ctx, rec.Login)
+	if errors.Is(err, sql.ErrNoRows) {
+		user = nil
+	} else if err != nil {
+		return RowResult{}, err
+	}
+	if email != "" {
+		owner, err := users.GetUserByEmail(ctx, email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return RowResult{}, err
+		}
+		if owner != nil && (user == nil || owner.ID != user.ID) {
+			return failed(rec, "Email is already in use"), nil
+		}
+	}
+
+	if user == nil {
+		if message := ungrantableRole(rec.Roles, nil, known, opts); message != "" {
+			return failed(rec, message), nil
+		}
+		return im.create(ctx, rec, email, opts)
+	}
+	return im.update(ctx, rec, email, user, opts, known)
+}
+
+func (im *Importer) create(ctx context.Context, rec Record, email string, opts Options) (RowResult, error) {
+	password, hash := rec.Password, rec.PasswordHash
+	if password != "" {
+		if message, err := im.checkPassword(ctx, &models.User{Login: rec.Login}, password); err != nil || message != "" {
+			return failed(rec, message), err
+		}
+	} else if hash == "" && !opts.GeneratePasswords {
+		return failed(rec, "Password or password_hash is required for a new user"), nil
+	}
+
+	row := RowResult{Row: rec.Row, Login: rec.Login, Action: ActionCreated}
+	if opts.DryRun {
+		return row, nil
+	}
+
+	var err error
+	if password == "" && hash == "" {
+		if password, err = im.generatePassword(ctx, rec.Login); err != nil {
+			return RowResult{}, err
+		}
+		row.Password = password
+	}
+	if password != "" {
+		if hash, err = utils.HashPassword(password); err != nil {
+			return RowResult{}, err
+		}
+	}
+
+	now := time.Now()
+	user := &models.User{
+		ID:        uuid.NewString(),
+		Login:     rec.Login,
+		Email:     email,
+		Status:    rec.Status,
+		Password:  hash,
+		CreatedAt: now,
+	}
+	if email != "" && rec.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+	if err := db.NewUserRepository(db.DB).CreateUser(ctx, user); err != nil {
+		switch {
+		case errors.Is(err, db.ErrUserExists):
+			return failed(rec, "Login is already taken"), nil
+		case errors.Is(err, db.ErrEmailExists):
+			return failed(rec, "Email is already in use"), nil
+		}
+		return RowResult{}, err
+	}
+	roles := rec.Roles
+	if len(roles) == 0 {
+		roles = []string{models.RoleUser}
+	}
+	if err := db.NewRoleRepository(db.DB).SetUserRoles(ctx, user.ID, roles); err != nil {
+		return RowResult{}, err
+	}
+	if err := im.policy.Record(ctx, user.ID, hash); err != nil {
+		return RowResult{}, err
+	}
+
+	row.UserID = user.ID
+	return row, nil
+}
+
+func (im *Importer) update(ctx context.Context, rec Record, email string, user *models.User, opts Options, known map[string][]string) (RowResult, error) {
+	roleRepo := db.NewRoleRepository(db.DB)
+	current, err := roleRepo.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return RowResult{}, err
+	}
+	if user.ID == opts.ActorID {
+		if rec.Status != "" && rec.Status != models.UserStatusActive {
+			return failed(rec, "You cannot disable your own account"), nil
+		}
+		if len(rec.Roles) > 0 && slices.Contains(current, models.RoleAdmin) && !slices.Contains(rec.Roles, models.RoleAdmin) {
+			return failed(rec, "You cannot remove your own admin role"), nil
+		}
+	}
+
+	var changes []string
+	if email != "" && email != user.Email {
+		changes = append(changes, ChangeEmail)
+	} else if rec.EmailVerified && user.Email != "" && user.EmailVerifiedAt == nil {
+		changes = append(changes, ChangeEmailVerified)
+	}
+	if rec.Status != "" && rec.Status != user.Status {
+		changes = append(changes, ChangeStatus)
+	}
+	if len(rec.Roles) > 0 && !sameRoles(current, rec.Roles) {
+		changes = append(changes, ChangeRoles)
+	}
+	// Пароль, що збігається з поточним, не змінюється: інакше повторний
+	// імпорт відхилила б історія паролів.
+	if rec.Password != "" && utils.CheckPassword(rec.Password, user.Password) != nil {
+		if message, err := im.checkPassword(ctx, user, rec.Password); err != nil || message != "" {
+			return failed(rec, message), err
+		}
+		changes = append(changes, ChangePassword)
+	} else if rec.PasswordHash != "" && rec.PasswordHash != user.Password {
+		changes = append(changes, ChangePassword)
+	}
+
+	row := RowResult{Row: rec.Row, Login: rec.Login, UserID: user.ID, Action: ActionUnchanged}
+	if len(changes) == 0 {
+		return row, nil
+	}
+	if opts.HasPermission != nil {
+		permissions, err := roleRepo.GetUserPermissions(ctx, user.ID)
+		if err != nil {
+			return RowResult{}, err
+		}
+		if p := missingPermission(permissions, opts); p != "" {
+			return failed(rec, "User has a permission you do not have: "+p), nil
+		}
+	}
+	if message := ungrantableRole(rec.Roles, current, known, opts); message != "" {
+		return failed(rec, message), nil
+	}
+	row.Action, row.Changes = ActionUpdated, changes
+	if opts.DryRun {
+		return row, nil
+	}
+
+	now := time.Now()
+	if slices.Contains(changes, ChangePassword) {
+		user.Password = rec.PasswordHash
+		if rec.Password != "" {
+			if user.Password, err = utils.HashPassword(rec.Password); err != nil {
+				return RowResult{}, err
+			}
+		}
+	}
+	if slices.Contains(changes, ChangeEmail) {
+		user.Email = email
+		user.EmailVerifiedAt = nil
+	}
+	if rec.EmailVerified && user.Email != "" && user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+	}
+	if rec.Status != "" {
+		user.Status = rec.Status
+	}
+	if err := db.NewUserRepository(db.DB).UpdateUser(ctx, user); err != nil {
+		if errors.Is(err, db.ErrEmailExists) {
+			return failed(rec, "Email is already in use"), nil
+		}
+		return RowResult{}, err
+	}
+
+	if slices.Contains(changes, ChangeEmail) {
+		// Токени з листів, надісланих на стару адресу, більше не дійсні.
+		if err := db.NewUserTokenRepository(db.DB).DeleteUserTokens(ctx, user.ID); err != nil {
+			return RowResult{}, err
+		}
+	}
+	if slices.Contains(changes, ChangeRoles) {
+		if err := roleRepo.SetUserRoles(ctx, user.ID, rec.Roles); err != nil {
+			return RowResult{}, err
+		}
+	}
+	if slices.Contains(changes, ChangePassword) {
+		if err := im.policy.Record(ctx, user.ID, user.Password); err != nil {
+			return RowResult{}, err
+		}
+	}
+	// Після зміни пароля чи вимкнення облікового запису старі токени не
+	// повинні діяти.
+	if slices.Contains(changes, ChangePassword) || user.Status != models.UserStatusActive {
+		if err := db.NewRevocationRepository(db.DB).RevokeAllForUser(ctx, user.ID); err != nil {
+			return RowResult{}, err
+		}
+		if _, err := db.NewSessionRepository(db.DB).RevokeUserSessions(ctx, user.ID, ""); err != nil {
+			return RowResult{}, err
+		}
+	}
+	return row, nil
+}
+
+// checkPassword повертає повідомлення про невідповідність пароля політиці
+// або порожній рядок, якщо пароль підходить.
+func (im *Importer) checkPassword(ctx context.Context, user *models.User, password string) (string, error) {
+	err := im.policy.Check(ctx, user, password)
+	var policyErr *passwords.PolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Error(), nil
+	}
+	return "", err
+}
+
+func (im *Importer) generatePassword(ctx context.Context, login string) (string, error) {
+	buf := make([]byte, generatedPasswordBytes)
+	for range maxGenerateAttempts {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		password := base64.RawURLEncoding.EncodeToString(buf)
+		message, err := im.checkPassword(ctx, &models.User{Login: login}, password)
+		if err != nil {
+			return "", err
+		}
+		if message == "" {
+			return password, nil
+		}
+	}
+	return "", errors.New("failed to generate a password that satisfies the password policy")
+}
+
+// missingPermission повертає перший дозвіл із permissions, якого немає в
+// автора імпорту, або порожній рядок.
+func missingPermission(permissions []string, opts Options) string {
+	if opts.HasPermission == nil {
+		return ""
+	}
+	for _, p := range permissions {
+		if !opts.HasPermission(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// ungrantableRole повертає повідомлення про першу роль із roles, якої ще немає
+// серед current і яка надає дозвіл, відсутній в автора імпорту.
+func ungrantableRole(roles []string, current []string, known map[string][]string, opts Options) string {
+	for _, role := range roles {
+		if slices.Contains(current, role) {
+			continue
+		}
+		if p := missingPermission(known[role], opts); p != "" {
+			return "Role " + role + " grants a permission you do not have: " + p
+		}
+	}
+	return ""
+}
+
+func sameRoles(a []string, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/bulk"
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/keys"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/passwords"
+)
+
+const usage = `Usage:
+  auth-service                                   запуск сервісу
+  auth-service keys list                         список ключів підпису
+  auth-service keys generate [-alg ES256] [-activate]
+  auth-service keys promote <kid> [-retire-previous-at RFC3339]
+  auth-service keys retire <kid> [-at RFC3339]
+  auth-service users import <file> [-format csv|json] [-dry-run] [-generate-passwords]
+  auth-service users export [-format csv|json] [-login LOGIN] [-status STATUS] [-o FILE]`
+
+// runCommand виконує адміністративну команду замість запуску сервера.
+// Сервіс, що вже працює, підхоплює зміни ключів при наступному перечитуванні.
+func runCommand(keyManager *keys.Manager, policy *passwords.Policy, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("unknown command\n%s", usage)
+	}
+	switch args[0] {
+	case "keys":
+		return runKeysCommand(keyManager, args[1], args[2:])
+	case "users":
+		return runUsersCommand(policy, args[1], args[2:])
+	}
+	return fmt.Errorf("unknown command\n%s", usage)
+}
+
+func runKeysCommand(keyManager *keys.Manager, cmd string, args []string) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("keys "+cmd, flag.ContinueOnError)
+
+	switch cmd {
+	case "list":
+		stored, err := keyManager.List(ctx)
+		if err != nil {
+			return err
+		}
+		printKeys(stored)
+		return nil
+
+	case "generate":
+		alg := fs.String("alg", "ES256", "алгоритм: HS256, RS256, ES256 або EdDSA")
+		activate := fs.Bool("activate", false, "одразу зробити ключ активним")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		sk, err := keyManager.Generate(ctx, *alg, *activate)
+		if err != nil {
+			return err
+		}
+		printKeys([]models.SigningKey{*sk})
+		return nil
+
+	case "promote":
+		retirePreviousAt := fs.String("retire-previous-at", "", "коли вивести попередній активний ключ (RFC3339)")
+		kid, err := parseArgs(fs, args, "key id")
+		if err != nil {
+			return err
+		}
+		at, err := parseOptionalTime(*retirePreviousAt)
+		if err != nil {
+			return err
+		}
+		sk, err := keyManager.Promote(ctx, kid, at)
+		if err != nil {
+			return err
+		}
+		printKeys([]models.SigningKey{*sk})
+		return nil
+
+	case "retire":
+		retireAt := fs.String("at", "", "коли вивести ключ (RFC3339), за замовчуванням — негайно")
+		kid, err := parseArgs(fs, args, "key id")
+		if err != nil {
+			return err
+		}
+		at, err := parseOptionalTime(*retireAt)
+		if err != nil {
+			return err
+		}
+		sk, err := keyManager.Retire(ctx, kid, at)
+		if err != nil {
+			return err
+		}
+		printKeys([]models.SigningKey{*sk})
+		return nil
+	}
+
+	return fmt.Errorf("unknown keys command %q\n%s", cmd, usage)
+}
+
+// parseArgs приймає обов'язковий аргумент name першим, а прапорці — після
+// нього.
+func parseArgs(fs *flag.FlagSet, args []string, name string) (string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", fmt.Errorf("%s is required\n%s", name, usage)
+	}
+	return args[0], fs.Parse(args[1:])
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+	return &t, nil
+}
+
+func runUsersCommand(policy *passwords.Policy, cmd string, args []string) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("users "+cmd, flag.ContinueOnError)
+
+	switch cmd {
+	case "import":
+		format := fs.String("format", "", "csv або json, за замовчуванням — за розширенням файлу")
+		dryRun := fs.Bool("dry-run", false, "лише перевірити файл і показати, що буде зроблено")
+		generate := fs.Bool("generate-passwords", false, "згенерувати пароль новим користувачам без password і password_hash")
+		path, err := parseArgs(fs, args, "file")
+		if err != nil {
+			return err
+		}
+		if *format == "" {
+			*format = bulk.FormatCSV
+			if strings.EqualFold(filepath.Ext(path), ".json") {
+				*format = bulk.FormatJSON
+			}
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		records, err := bulk.Parse(f, *format)
+		if err != nil {
+			return err
+		}
+
+		result, err := bulk.NewImporter(policy).Import(ctx, records, bulk.Options{DryRun: *dryRun, GeneratePasswords: *generate})
+		if result != nil {
+			if !*dryRun {
+				auditImport(ctx, result)
+			}
+			printImportResult(result)
+		}
+		if err != nil {
+			return err
+		}
+		if result.Failed > 0 {
+			return fmt.Errorf("%d of %d rows failed", result.Failed, len(result.Rows))
+		}
+		return nil
+
+	case "export":
+		format := fs.String("format", bulk.FormatCSV, "csv або json")
+		login := fs.String("login", "", "пошук за логіном")
+		status := fs.String("status", "", "стан облікового запису: pending, active або disabled")
+		output := fs.String("o", "", "файл для запису, за замовчуванням — stdout")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		w := io.Writer(os.Stdout)
+		if *output != "" {
+			// Файл містить email користувачів, тож читати його може лише власник.
+			f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return bulk.Export(ctx, w, *format, db.UserFilter{Login: *login, Status: *status})
+	}
+
+	return fmt.Errorf("unknown users command %q\n%s", cmd, usage)
+}
+
+// auditImport записує в журнал аудиту створених і змінених командою
+// користувачів. Помилка запису лише логується, як і в обробниках HTTP.
+func auditImport(ctx context.Context, result *bulk.Result) {
+	repo := db.NewAuditRepository(db.DB)
+	now := time.Now()
+	for _, row := range result.Rows {
+		if row.Action != bulk.ActionCreated && row.Action != bulk.ActionUpdated {
+			continue
+		}
+		event := &models.AuditEvent{
+			Type:        models.AuditUserImport,
+			ActorLogin:  "cli",
+			TargetID:    row.UserID,
+			TargetLogin: row.Login,
+			Details:     row.Summary(),
+			CreatedAt:   now,
+		}
+		if err := repo.AppendEvent(ctx, event); err != nil {
+			log.Println("Audit log error:", err)
+		}
+	}
+}
+
+func printImportResult(result *bulk.Result) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROW\tLOGIN\tACTION\tDETAILS\tPASSWORD")
+	for _, row := range result.Rows {
+		details := row.Error
+		if details == "" {
+			details = strings.Join(row.Changes, ", ")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", row.Row, row.Login, row.Action, orDash(details), orDash(row.Password))
+	}
+	tw.Flush()
+
+	prefix := ""
+	if result.DryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d created, %d updated, %d unchanged, %d failed\n", prefix, result.Created, result.Updated, result.Unchanged, result.Failed)
+}
+
+func orDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
+
+func printKeys(stored []models.SigningKey) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KID\tALG\tSTATUS\tCREATED\tRETIRES")
+	for _, sk := range stored {
+		retires := "-"
+		if sk.RetiresAt != nil {
+			retires = sk.RetiresAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", sk.ID, sk.Algorithm, sk.Status, sk.CreatedAt.Format(time.RFC3339), retires)
+	}
+	tw.Flush()
+}
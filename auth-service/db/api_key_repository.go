@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+// APIKeyRepository зберігає API ключі користувачів. Scopes ключа не
+// прив'язані до таблиці permissions: дозвіл, якого вже немає, просто не
+// надається, а не перетворює ключ на ключ без обмежень.
+type APIKeyRepository struct {
+	db *DBWrapper
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.NamedExecContext(ctx, `
+        INSERT INTO api_keys (id, user_id, name, prefix, key_hash, created_at, expires_at, last_used_at, tenant_id)
+        VALUES (:id, :user_id, :name, :prefix, :key_hash, :created_at, :expires_at, :last_used_at, :tenant_id)
+    `, key)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	for _, p := range key.Scopes {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO api_key_scopes (api_key_id, permission) VALUES (?, ?)`, key.ID, p); err != nil {
+			return fmt.Errorf("failed to create api key: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByPrefix шукає ключ за відкритим префіксом.
+func (r *APIKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.GetContext(ctx, &key, `SELECT * FROM api_keys WHERE prefix = ?`, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	key.Scopes = []string{}
+	err = r.db.SelectContext(ctx, &key.Scopes, `SELECT permission FROM api_key_scopes WHERE api_key_id = ? ORDER BY permission`, key.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key scopes: %w", err)
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) ListUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := r.db.SelectContext(ctx, &keys, `SELECT * FROM api_keys WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	var rows []struct {
+		APIKeyID   string `db:"api_key_id"`
+		Permission string `db:"permission"`
+	}
+	err = r.db.SelectContext(ctx, &rows, `
+        SELECT s.api_key_id, s.permission FROM api_key_scopes s
+        JOIN api_keys k ON k.id = s.api_key_id
+        WHERE k.user_id = ?
+        ORDER BY s.permission
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api key scopes: %w", err)
+	}
+	byKey := map[string][]string{}
+	for _, row := range rows {
+		byKey[row.APIKeyID] = append(byKey[row.APIKeyID], row.Permission)
+	}
+	for i := range keys {
+		keys[i].Scopes = byKey[keys[i].ID]
+		if keys[i].Scopes == nil {
+			keys[i].Scopes = []string{}
+		}
+	}
+	return keys, nil
+}
+
+// TouchAPIKey записує час останнього використання ключа.
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, usedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update api key: %w", err)
+	}
+	return nil
+}
+
+// DeleteAPIKey видаляє ключ користувача userID. Повертає false, якщо в
+// користувача такого ключа не було.
+func (r *APIKeyRepository) DeleteAPIKey(ctx context.Context, userID string, id string) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete api key: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete api key: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete api key: %w", err)
+	}
+	if n > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM api_key_scopes WHERE api_key_id = ?`, id); err != nil {
+			return false, fmt.Errorf("failed to delete api key: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to delete api key: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteUserAPIKeys видаляє всі ключі користувача.
+func (r *APIKeyRepository) DeleteUserAPIKeys(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete user api keys: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM api_key_scopes WHERE api_key_id IN (SELECT id FROM api_keys WHERE user_id = ?)`,
+		`DELETE FROM api_keys WHERE user_id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("failed to delete user api keys: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete user api keys: %w", err)
+	}
+	return nil
+}
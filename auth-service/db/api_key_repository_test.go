@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupAPIKeyTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE api_keys (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL UNIQUE,
+		key_hash TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME,
+		last_used_at DATETIME,
+		tenant_id TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE api_key_scopes (
+		api_key_id TEXT NOT NULL,
+		permission TEXT NOT NULL,
+		PRIMARY KEY (api_key_id, permission)
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupAPIKeyTestDB(t)
+	repo := NewAPIKeyRepository(db)
+	now := time.Now().Truncate(time.Second)
+	expires := now.Add(24 * time.Hour)
+
+	t.Run("CreateAPIKey", func(t *testing.T) {
+		require.NoError(t, repo.CreateAPIKey(ctx, &models.APIKey{
+			ID: "key-1", UserID: "user-1", Name: "backup", Prefix: "ak_000000000001", KeyHash: "hash-1",
+			CreatedAt: now, ExpiresAt: &expires, Scopes: []string{models.PermissionProductRead},
+		}))
+		require.NoError(t, repo.CreateAPIKey(ctx, &models.APIKey{
+			ID: "key-2", UserID: "user-1", Name: "deploy", Prefix: "ak_000000000002", KeyHash: "hash-2",
+			CreatedAt: now.Add(time.Second),
+		}))
+		require.NoError(t, repo.CreateAPIKey(ctx, &models.APIKey{
+			ID: "key-3", UserID: "user-2", Name: "other", Prefix: "ak_000000000003", KeyHash: "hash-3",
+			CreatedAt: now, Scopes: []string{models.PermissionProductWrite},
+		}))
+
+		err := repo.CreateAPIKey(ctx, &models.APIKey{ID: "key-4", UserID: "user-1", Name: "dup", Prefix: "ak_000000000001", KeyHash: "hash-4", CreatedAt: now})
+		require.Error(t, err)
+	})
+
+	t.Run("GetAPIKeyByPrefix", func(t *testing.T) {
+		key, err := repo.GetAPIKeyByPrefix(ctx, "ak_000000000001")
+		require.NoError(t, err)
+		assert.Equal(t, "key-1", key.ID)
+		assert.Equal(t, "hash-1", key.KeyHash)
+		require.NotNil(t, key.ExpiresAt)
+		assert.True(t, key.ExpiresAt.Equal(expires))
+		assert.Nil(t, key.LastUsedAt)
+		assert.Equal(t, []string{models.PermissionProductRead}, key.Scopes)
+
+		_, err = repo.GetAPIKeyByPrefix(ctx, "ak_missing")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("ListUserAPIKeys", func(t *testing.T) {
+		keys, err := repo.ListUserAPIKeys(ctx, "user-1")
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, "key-1", keys[0].ID)
+		assert.Equal(t, []string{models.PermissionProductRead}, keys[0].Scopes)
+		assert.Equal(t, "key-2", keys[1].ID)
+		assert.Equal(t, []string{}, keys[1].Scopes)
+	})
+
+	t.Run("TouchAPIKey", func(t *testing.T) {
+		used := now.Add(time.Minute)
+		require.NoError(t, repo.TouchAPIKey(ctx, "key-2", used))
+
+		key, err := repo.GetAPIKeyByPrefix(ctx, "ak_000000000002")
+		require.NoError(t, err)
+		require.NotNil(t, key.LastUsedAt)
+		assert.True(t, key.LastUsedAt.Equal(used))
+	})
+
+	t.Run("DeleteAPIKey", func(t *testing.T) {
+		deleted, err := repo.DeleteAPIKey(ctx, "user-2", "key-1")
+		require.NoError(t, err)
+		assert.False(t, deleted, "a user must not delete another user's key")
+
+		deleted, err = repo.DeleteAPIKey(ctx, "user-1", "key-1")
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		_, err = repo.GetAPIKeyByPrefix(ctx, "ak_000000000001")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("DeleteUserAPIKeys", func(t *testing.T) {
+		require.NoError(t, repo.DeleteUserAPIKeys(ctx, "user-1"))
+
+		keys, err := repo.ListUserAPIKeys(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		keys, err = repo.ListUserAPIKeys(ctx, "user-2")
+		require.NoError(t, err)
+		assert.Len(t, keys, 1)
+	})
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+// AuditRepository веде журнал аудиту. Журнал лише доповнюється: тригери
+// схеми забороняють змінювати і видаляти записи.
+type AuditRepository struct {
+	db *DBWrapper
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *AuditRepository) AppendEvent(ctx context.Context, event *models.AuditEvent) error {
+	query := `
+        INSERT INTO audit_events (type, actor_id, actor_login, target_id, target_login, client_ip, user_agent, details, created_at)
+        VALUES (:type, :actor_id, :actor_login, :target_id, :target_login, :client_ip, :user_agent, :details, :created_at)
+    `
+	res, err := r.db.NamedExecContext(ctx, query, event)
+	if err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	event.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
+
+// AuditFilter відбирає події журналу. Actor і Target збігаються з ID або
+// логіном; From включно, To — ні. Порожні поля не обмежують вибірку.
+type AuditFilter struct {
+	Type     string
+	Actor    string
+	Target   string
+	ClientIP string
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+func (f AuditFilter) where() (string, []any) {
+	var conds []string
+	var args []any
+	if f.Type != "" {
+		conds = append(conds, `type = ?`)
+		args = append(args, f.Type)
+	}
+	if f.Actor != "" {
+		conds = append(conds, `(actor_id = ? OR actor_login = ?)`)
+		args = append(args, f.Actor, f.Actor)
+	}
+	if f.Target != "" {
+		conds = append(conds, `(target_id = ? OR target_login = ?)`)
+		args = append(args, f.Target, f.Target)
+	}
+	if f.ClientIP != "" {
+		conds = append(conds, `client_ip = ?`)
+		args = append(args, f.ClientIP)
+	}
+	if f.From != nil {
+		conds = append(conds, `created_at >= ?`)
+		args = append(args, *f.From)
+	}
+	if f.To != nil {
+		conds = append(conds, `created_at < ?`)
+		args = append(args, *f.To)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conds, ` AND `), args
+}
+
+// ListEvents повертає сторінку подій, починаючи з найновішої.
+func (r *AuditRepository) ListEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error) {
+	events := []models.AuditEvent{}
+	where, args := filter.where()
+	query := `SELECT * FROM audit_events` + where + ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	args = append(args, filter.Limit, filter.Offset)
+	err := r.db.SelectContext(ctx, &events, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *AuditRepository) CountEvents(ctx context.Context, filter AuditFilter) (int, error) {
+	var count int
+	where, args := filter.where()
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM audit_events`+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+	return count, nil
+}
+
+// ExportEvents передає fn усі події фільтра, починаючи з найновішої, не
+// завантажуючи їх у пам'ять. Limit і Offset фільтра не враховуються.
+func (r *AuditRepository) ExportEvents(ctx context.Context, filter AuditFilter, fn func(*models.AuditEvent) error) error {
+	where, args := filter.where()
+	rows, err := r.db.QueryxContext(ctx, `SELECT * FROM audit_events`+where+` ORDER BY id DESC`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.AuditEvent
+		if err := rows.StructScan(&event); err != nil {
+			return fmt.Errorf("failed to export audit events: %w", err)
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export audit events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func TestAuditRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupRoleTestDB(t)
+	repo := NewAuditRepository(db)
+	now := time.Now().Truncate(time.Second)
+
+	events := []models.AuditEvent{
+		{Type: models.AuditLoginFailure, ActorLogin: "alice", ClientIP: "203.0.113.7", Details: "invalid credentials", CreatedAt: now},
+		{Type: models.AuditLoginSuccess, ActorID: "user-1", ActorLogin: "alice", ClientIP: "203.0.113.7", UserAgent: "curl/8.0", CreatedAt: now.Add(time.Second)},
+		{Type: models.AuditRolesChange, ActorID: "admin-1", ActorLogin: "admin", TargetID: "user-1", TargetLogin: "alice", ClientIP: "198.51.100.1", Details: "roles: user -> admin", CreatedAt: now.Add(2 * time.Second)},
+		{Type: models.AuditLogout, ActorID: "user-1", ActorLogin: "alice", ClientIP: "203.0.113.7", CreatedAt: now.Add(3 * time.Second)},
+	}
+
+	t.Run("AppendEvent", func(t *testing.T) {
+		for i := range events {
+			require.NoError(t, repo.AppendEvent(ctx, &events[i]))
+		}
+		assert.Less(t, events[0].ID, events[1].ID)
+	})
+
+	t.Run("ListEvents", func(t *testing.T) {
+		list, err := repo.ListEvents(ctx, AuditFilter{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, list, 4)
+		assert.Equal(t, models.AuditLogout, list[0].Type, "newest event must come first")
+		assert.Equal(t, "curl/8.0", list[2].UserAgent)
+
+		list, err = repo.ListEvents(ctx, AuditFilter{Actor: "alice", Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, list, 3, "actor must match by login, including failed logins")
+
+		list, err = repo.ListEvents(ctx, AuditFilter{Target: "user-1", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, "roles: user -> admin", list[0].Details)
+
+		from, to := now.Add(time.Second), now.Add(3*time.Second)
+		list, err = repo.ListEvents(ctx, AuditFilter{From: &from, To: &to, ClientIP: "203.0.113.7", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, models.AuditLoginSuccess, list[0].Type)
+
+		list, err = repo.ListEvents(ctx, AuditFilter{Limit: 2, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, models.AuditRolesChange, list[0].Type)
+	})
+
+	t.Run("CountEvents", func(t *testing.T) {
+		count, err := repo.CountEvents(ctx, AuditFilter{Type: models.AuditLoginFailure})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("ExportEvents", func(t *testing.T) {
+		var types []string
+		err := repo.ExportEvents(ctx, AuditFilter{Actor: "user-1", Limit: 1}, func(e *models.AuditEvent) error {
+			types = append(types, e.Type)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{models.AuditLogout, models.AuditLoginSuccess}, types)
+	})
+
+	t.Run("AppendOnly", func(t *testing.T) {
+		_, err := db.Exec(`UPDATE audit_events SET actor_login = 'mallory'`)
+		require.Error(t, err)
+		_, err = db.Exec(`DELETE FROM audit_events`)
+		require.Error(t, err)
+
+		count, err := repo.CountEvents(ctx, AuditFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 4, count)
+	})
+}
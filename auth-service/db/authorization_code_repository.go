@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+// AuthorizationCodeRepository зберігає одноразові коди grant authorization_code.
+type AuthorizationCodeRepository struct {
+	db *DBWrapper
+}
+
+func NewAuthorizationCodeRepository(db *sqlx.DB) *AuthorizationCodeRepository {
+	return &AuthorizationCodeRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *AuthorizationCodeRepository) CreateAuthorizationCode(ctx context.Context, code *models.AuthorizationCode) error {
+	query := `
+        INSERT INTO authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge, auth_time, expires_at)
+        VALUES (:code_hash, :client_id, :user_id, :redirect_uri, :scope, :nonce, :code_challenge, :auth_time, :expires_at)
+    `
+	_, err := r.db.NamedExecContext(ctx, query, code)
+	if err != nil {
+		return fmt.Errorf("failed to create authorization code: %w", err)
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode видаляє код і повертає його. Код можна використати
+// лише раз; прострочений код не знаходиться.
+func (r *AuthorizationCodeRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string, now time.Time) (*models.AuthorizationCode, error) {
+	var code models.AuthorizationCode
+	err := r.db.GetContext(ctx, &code,
+		`DELETE FROM authorization_codes WHERE code_hash = ? AND expires_at > ? RETURNING *`,
+		codeHash, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume authorization code: %w", err)
+	}
+	return &code, nil
+}
+
+// PruneExpired видаляє прострочені коди.
+func (r *AuthorizationCodeRepository) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM authorization_codes WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune authorization codes: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupAuthorizationCodeTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE authorization_codes (
+		code_hash TEXT PRIMARY KEY,
+		client_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		redirect_uri TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		nonce TEXT NOT NULL,
+		code_challenge TEXT NOT NULL,
+		auth_time DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestAuthorizationCodeRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupAuthorizationCodeTestDB(t)
+	repo := NewAuthorizationCodeRepository(db)
+	now := time.Now().Truncate(time.Second)
+
+	newCode := func(hash string, expiresAt time.Time) *models.AuthorizationCode {
+		return &models.AuthorizationCode{
+			CodeHash:      hash,
+			ClientID:      "web",
+			UserID:        "user-1",
+			RedirectURI:   "https://app.example.com/callback",
+			Scope:         "openid profile",
+			Nonce:         "n-1",
+			CodeChallenge: "challenge",
+			AuthTime:      now,
+			ExpiresAt:     expiresAt,
+		}
+	}
+
+	t.Run("ConsumeAuthorizationCode", func(t *testing.T) {
+		require.NoError(t, repo.CreateAuthorizationCode(ctx, newCode("hash-1", now.Add(time.Minute))))
+
+		code, err := repo.ConsumeAuthorizationCode(ctx, "hash-1", now)
+		require.NoError(t, err)
+		assert.Equal(t, "web", code.ClientID)
+		assert.Equal(t, "user-1", code.UserID)
+		assert.Equal(t, "openid profile", code.Scope)
+		assert.Equal(t, "n-1", code.Nonce)
+		assert.True(t, code.AuthTime.Equal(now))
+
+		_, err = repo.ConsumeAuthorizationCode(ctx, "hash-1", now)
+		require.ErrorIs(t, err, sql.ErrNoRows, "a code must be usable only once")
+	})
+
+	t.Run("ExpiredCode", func(t *testing.T) {
+		require.NoError(t, repo.CreateAuthorizationCode(ctx, newCode("hash-2", now.Add(-time.Second))))
+
+		_, err := repo.ConsumeAuthorizationCode(ctx, "hash-2", now)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("PruneExpired", func(t *testing.T) {
+		require.NoError(t, repo.CreateAuthorizationCode(ctx, newCode("hash-3", now.Add(time.Minute))))
+
+		n, err := repo.PruneExpired(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		_, err = repo.ConsumeAuthorizationCode(ctx, "hash-3", now)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+var ErrClientExists = errors.New("client already exists")
+
+// ClientRepository зберігає OAuth клієнтів, дозволи (scopes), які вони
+// можуть отримати в токені, і дозволені redirect URI.
+type ClientRepository struct {
+	db *DBWrapper
+}
+
+func NewClientRepository(db *sqlx.DB) *ClientRepository {
+	return &ClientRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *ClientRepository) ListClients(ctx context.Context) ([]models.Client, error) {
+	clients := []models.Client{}
+	err := r.db.SelectContext(ctx, &clients, `SELECT * FROM clients ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clients: %w", err)
+	}
+
+	var rows []struct {
+		ClientID   string `db:"client_id"`
+		Permission string `db:"permission"`
+	}
+	err = r.db.SelectContext(ctx, &rows, `SELECT client_id, permission FROM client_scopes ORDER BY permission`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client scopes: %w", err)
+	}
+	byClient := map[string][]string{}
+	for _, row := range rows {
+		byClient[row.ClientID] = append(byClient[row.ClientID], row.Permission)
+	}
+
+	var uris []struct {
+		ClientID    string `db:"client_id"`
+		RedirectURI string `db:"redirect_uri"`
+	}
+	err = r.db.SelectContext(ctx, &uris, `SELECT client_id, redirect_uri FROM client_redirect_uris ORDER BY redirect_uri`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client redirect uris: %w", err)
+	}
+	urisByClient := map[string][]string{}
+	for _, row := range uris {
+		urisByClient[row.ClientID] = append(urisByClient[row.ClientID], row.RedirectURI)
+	}
+
+	for i := range clients {
+		clients[i].Scopes = byClient[clients[i].ID]
+		if clients[i].Scopes == nil {
+			clients[i].Scopes = []string{}
+		}
+		clients[i].RedirectURIs = urisByClient[clients[i].ID]
+		if clients[i].RedirectURIs == nil {
+			clients[i].RedirectURIs = []string{}
+		}
+	}
+	return clients, nil
+}
+
+func (r *ClientRepository) GetClient(ctx context.Context, id string) (*models.Client, error) {
+	var client models.Client
+	err := r.db.GetContext(ctx, &client, `SELECT * FROM clients WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	client.Scopes = []string{}
+	err = r.db.SelectContext(ctx, &client.Scopes, `SELECT permission FROM client_scopes WHERE client_id = ? ORDER BY permission`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client scopes: %w", err)
+	}
+	client.RedirectURIs = []string{}
+	err = r.db.SelectContext(ctx, &client.RedirectURIs, `SELECT redirect_uri FROM client_redirect_uris WHERE client_id = ? ORDER BY redirect_uri`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client redirect uris: %w", err)
+	}
+	return &client, nil
+}
+
+func (r *ClientRepository) CreateClient(ctx context.Context, client *models.Client) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.NamedExecContext(ctx, `
+        INSERT INTO clients (id, name, secret_hash, created_at, secret_rotated_at, public)
+        VALUES (:id, :name, :secret_hash, :created_at, :secret_rotated_at, :public)
+    `, client)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("failed to create client: %w", ErrClientExists)
+		}
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	if err := setClientScopes(ctx, tx, client.ID, client.Scopes); err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	if err := setClientRedirectURIs(ctx, tx, client.ID, client.RedirectURIs); err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	return nil
+}
+
+// UpdateClient змінює назву клієнта і замінює його scopes і redirect URI.
+func (r *ClientRepository) UpdateClient(ctx context.Context, client *models.Client) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update client: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE clients SET name = ? WHERE id = ?`, client.Name, client.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update client: %w", err)
+	}
+	if err := setClientScopes(ctx, tx, client.ID, client.Scopes); err != nil {
+		return fmt.Errorf("failed to update client: %w", err)
+	}
+	if err := setClientRedirectURIs(ctx, tx, client.ID, client.RedirectURIs); err != nil {
+		return fmt.Errorf("failed to update client: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update client: %w", err)
+	}
+	return nil
+}
+
+func setClientScopes(ctx context.Context, tx *sqlx.Tx, clientID string, scopes []string) error {
+	if err := requireExisting(ctx, tx, "permissions", scopes, ErrUnknownPermission); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM client_scopes WHERE client_id = ?`, clientID); err != nil {
+		return err
+	}
+	for _, p := range scopes {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO client_scopes (client_id, permission) VALUES (?, ?)`, clientID, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setClientRedirectURIs(ctx context.Context, tx *sqlx.Tx, clientID string, uris []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM client_redirect_uris WHERE client_id = ?`, clientID); err != nil {
+		return err
+	}
+	for _, uri := range uris {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO client_redirect_uris (client_id, redirect_uri) VALUES (?, ?)`, clientID, uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RotateSecret замінює хеш секрету клієнта. Повертає false, якщо клієнта немає.
+func (r *ClientRepository) RotateSecret(ctx context.Context, id string, secretHash string, rotatedAt time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE clients SET secret_hash = ?, secret_rotated_at = ? WHERE id = ?`, secretHash, rotatedAt, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate client secret: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate client secret: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteClient видаляє клієнта разом з його scopes, redirect URI і ще не
+// використаними кодами авторизації. Повертає false, якщо такого клієнта не було.
+func (r *ClientRepository) DeleteClient(ctx context.Context, id string) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete client: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM client_scopes WHERE client_id = ?`,
+		`DELETE FROM client_redirect_uris WHERE client_id = ?`,
+		`DELETE FROM authorization_codes WHERE client_id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return false, fmt.Errorf("failed to delete client: %w", err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM clients WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete client: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete client: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to delete client: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func TestClientRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupRoleTestDB(t)
+	repo := NewClientRepository(db)
+	now := time.Now().Truncate(time.Second)
+
+	t.Run("CreateClient", func(t *testing.T) {
+		client := &models.Client{
+			ID:              "reports",
+			Name:            "Нічні звіти",
+			SecretHash:      "hash-1",
+			CreatedAt:       now,
+			SecretRotatedAt: now,
+			Scopes:          []string{models.PermissionProductRead},
+		}
+		require.NoError(t, repo.CreateClient(ctx, client))
+
+		err := repo.CreateClient(ctx, client)
+		require.ErrorIs(t, err, ErrClientExists)
+
+		err = repo.CreateClient(ctx, &models.Client{ID: "bad", SecretHash: "x", CreatedAt: now, SecretRotatedAt: now, Scopes: []string{"missing:perm"}})
+		require.ErrorIs(t, err, ErrUnknownPermission)
+		_, err = repo.GetClient(ctx, "bad")
+		require.ErrorIs(t, err, sql.ErrNoRows, "client must not be created when a scope is unknown")
+
+		got, err := repo.GetClient(ctx, "reports")
+		require.NoError(t, err)
+		assert.Equal(t, "Нічні звіти", got.Name)
+		assert.Equal(t, "hash-1", got.SecretHash)
+		assert.Equal(t, []string{models.PermissionProductRead}, got.Scopes)
+		assert.Equal(t, []string{}, got.RedirectURIs)
+		assert.False(t, got.Public)
+	})
+
+	t.Run("PublicClient", func(t *testing.T) {
+		require.NoError(t, repo.CreateClient(ctx, &models.Client{
+			ID:              "web",
+			Name:            "Браузерний застосунок",
+			CreatedAt:       now,
+			SecretRotatedAt: now,
+			Public:          true,
+			RedirectURIs:    []string{"https://app.example.com/callback", "http://localhost:3000/callback"},
+		}))
+
+		got, err := repo.GetClient(ctx, "web")
+		require.NoError(t, err)
+		assert.True(t, got.Public)
+		assert.Empty(t, got.SecretHash)
+		assert.Equal(t, []string{"http://localhost:3000/callback", "https://app.example.com/callback"}, got.RedirectURIs)
+
+		got.RedirectURIs = []string{"https://app.example.com/callback"}
+		require.NoError(t, repo.UpdateClient(ctx, got))
+		got, err = repo.GetClient(ctx, "web")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://app.example.com/callback"}, got.RedirectURIs)
+
+		deleted, err := repo.DeleteClient(ctx, "web")
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		var uris int
+		require.NoError(t, db.Get(&uris, `SELECT COUNT(*) FROM client_redirect_uris WHERE client_id = 'web'`))
+		assert.Zero(t, uris)
+	})
+
+	t.Run("UpdateClient", func(t *testing.T) {
+		client, err := repo.GetClient(ctx, "reports")
+		require.NoError(t, err)
+		client.Name = "Звіти"
+		client.Scopes = []string{models.PermissionProductRead, models.PermissionProductWrite}
+		require.NoError(t, repo.UpdateClient(ctx, client))
+
+		clients, err := repo.ListClients(ctx)
+		require.NoError(t, err)
+		require.Len(t, clients, 1)
+		assert.Equal(t, "Звіти", clients[0].Name)
+		assert.Equal(t, []string{models.PermissionProductRead, models.PermissionProductWrite}, clients[0].Scopes)
+	})
+
+	t.Run("RotateSecret", func(t *testing.T) {
+		later := now.Add(time.Hour)
+		rotated, err := repo.RotateSecret(ctx, "reports", "hash-2", later)
+		require.NoError(t, err)
+		assert.True(t, rotated)
+
+		client, err := repo.GetClient(ctx, "reports")
+		require.NoError(t, err)
+		assert.Equal(t, "hash-2", client.SecretHash)
+		assert.True(t, client.SecretRotatedAt.Equal(later))
+
+		rotated, err = repo.RotateSecret(ctx, "missing", "hash", later)
+		require.NoError(t, err)
+		assert.False(t, rotated)
+	})
+
+	t.Run("DeletePermissionRemovesScope", func(t *testing.T) {
+		roles := NewRoleRepository(db)
+		require.NoError(t, roles.CreatePermission(ctx, &models.Permission{Name: "reports:export"}))
+		client, err := repo.GetClient(ctx, "reports")
+		require.NoError(t, err)
+		client.Scopes = append(client.Scopes, "reports:export")
+		require.NoError(t, repo.UpdateClient(ctx, client))
+
+		_, err = roles.DeletePermission(ctx, "reports:export")
+		require.NoError(t, err)
+		client, err = repo.GetClient(ctx, "reports")
+		require.NoError(t, err)
+		assert.NotContains(t, client.Scopes, "reports:export")
+	})
+
+	t.Run("DeleteClient", func(t *testing.T) {
+		deleted, err := repo.DeleteClient(ctx, "reports")
+		require.NoError(t, err)
+		assert.True(t, deleted)
+
+		_, err = repo.GetClient(ctx, "reports")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		deleted, err = repo.DeleteClient(ctx, "reports")
+		require.NoError(t, err)
+		assert.False(t, deleted)
+	})
+}
@@ -9,22 +9,245 @@ import (
 
 var DB *sqlx.DB
 
+// InitDB відкриває БД з увімкненими зовнішніми ключами: SQLite за
+// замовчуванням їх не перевіряє, і ON DELETE CASCADE не спрацьовує.
+// Параметр у DSN застосовується до кожного з'єднання пулу.
 func InitDB(filepath string) {
 	var err error
-	DB, err = sqlx.Connect("sqlite3", filepath)
+	DB, err = sqlx.Connect("sqlite3", filepath+"?_foreign_keys=on")
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	schema := `
+	DB.MustExec(schema)
+	if err := migrate(DB); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+}
+
+const schema = `
     CREATE TABLE IF NOT EXISTS users (
         id TEXT PRIMARY KEY,
         login TEXT NOT NULL UNIQUE,
+        email TEXT NOT NULL DEFAULT '',
+        email_verified_at TIMESTAMP,
+        status TEXT NOT NULL DEFAULT 'active',
         password TEXT NOT NULL,
-        is_admin BOOLEAN NOT NULL,
         created_at TIMESTAMP NOT NULL,
         updated_at TIMESTAMP
     );
+
+    CREATE TABLE IF NOT EXISTS refresh_tokens (
+        id TEXT PRIMARY KEY,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        family_id TEXT NOT NULL,
+        token_hash TEXT NOT NULL UNIQUE,
+        expires_at TIMESTAMP NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        used_at TIMESTAMP,
+        revoked_at TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+
+    CREATE TABLE IF NOT EXISTS sessions (
+        id TEXT PRIMARY KEY,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        token_id TEXT NOT NULL,
+        client_ip TEXT NOT NULL DEFAULT '',
+        user_agent TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP NOT NULL,
+        issued_at TIMESTAMP NOT NULL,
+        expires_at TIMESTAMP NOT NULL,
+        last_seen_at TIMESTAMP,
+        revoked_at TIMESTAMP,
+        tenant_id TEXT NOT NULL DEFAULT ''
+    );
+    CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+
+    CREATE TABLE IF NOT EXISTS user_tokens (
+        token_hash TEXT PRIMARY KEY,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        purpose TEXT NOT NULL,
+        expires_at TIMESTAMP NOT NULL,
+        created_at TIMESTAMP NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens(user_id, purpose);
+
+    CREATE TABLE IF NOT EXISTS revoked_tokens (
+        jti TEXT PRIMARY KEY,
+        user_id TEXT NOT NULL,
+        expires_at TIMESTAMP NOT NULL,
+        revoked_at TIMESTAMP NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS token_watermarks (
+        user_id TEXT PRIMARY KEY,
+        revoked_before TIMESTAMP NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS signing_keys (
+        id TEXT PRIMARY KEY,
+        algorithm TEXT NOT NULL,
+        private_key TEXT NOT NULL,
+        status TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        activated_at TIMESTAMP,
+        retires_at TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS roles (
+        name TEXT PRIMARY KEY,
+        description TEXT NOT NULL DEFAULT '',
+        require_mfa BOOLEAN NOT NULL DEFAULT 0,
+        created_at TIMESTAMP NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS permissions (
+        name TEXT PRIMARY KEY,
+        description TEXT NOT NULL DEFAULT ''
+    );
+
+    CREATE TABLE IF NOT EXISTS user_roles (
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+        PRIMARY KEY (user_id, role)
+    );
+
+    CREATE TABLE IF NOT EXISTS role_permissions (
+        role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+        permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+        PRIMARY KEY (role, permission)
+    );
+
+    CREATE TABLE IF NOT EXISTS login_attempts (
+        key TEXT PRIMARY KEY,
+        failures INTEGER NOT NULL,
+        last_failure_at TIMESTAMP NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS password_history (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        password TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_password_history_user ON password_history(user_id);
+
+    CREATE TABLE IF NOT EXISTS user_mfa (
+        user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+        secret TEXT NOT NULL,
+        enabled BOOLEAN NOT NULL DEFAULT 0,
+        last_used_step INTEGER NOT NULL DEFAULT 0,
+        created_at TIMESTAMP NOT NULL,
+        enabled_at TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        code_hash TEXT NOT NULL,
+        used_at TIMESTAMP,
+        PRIMARY KEY (user_id, code_hash)
+    );
+
+    CREATE TABLE IF NOT EXISTS mfa_challenges (
+        id TEXT PRIMARY KEY,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        expires_at TIMESTAMP NOT NULL,
+        created_at TIMESTAMP NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS clients (
+        id TEXT PRIMARY KEY,
+        name TEXT NOT NULL DEFAULT '',
+        secret_hash TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        secret_rotated_at TIMESTAMP NOT NULL,
+        public BOOLEAN NOT NULL DEFAULT 0
+    );
+
+    CREATE TABLE IF NOT EXISTS client_scopes (
+        client_id TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+        permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+        PRIMARY KEY (client_id, permission)
+    );
+
+    CREATE TABLE IF NOT EXISTS client_redirect_uris (
+        client_id TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+        redirect_uri TEXT NOT NULL,
+        PRIMARY KEY (client_id, redirect_uri)
+    );
+
+    CREATE TABLE IF NOT EXISTS authorization_codes (
+        code_hash TEXT PRIMARY KEY,
+        client_id TEXT NOT NULL,
+        user_id TEXT NOT NULL,
+        redirect_uri TEXT NOT NULL,
+        scope TEXT NOT NULL,
+        nonce TEXT NOT NULL,
+        code_challenge TEXT NOT NULL,
+        auth_time TIMESTAMP NOT NULL,
+        expires_at TIMESTAMP NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS api_keys (
+        id TEXT PRIMARY KEY,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        name TEXT NOT NULL,
+        prefix TEXT NOT NULL UNIQUE,
+        key_hash TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        expires_at TIMESTAMP,
+        last_used_at TIMESTAMP,
+        tenant_id TEXT NOT NULL DEFAULT ''
+    );
+    CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+    CREATE TABLE IF NOT EXISTS api_key_scopes (
+        api_key_id TEXT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+        permission TEXT NOT NULL,
+        PRIMARY KEY (api_key_id, permission)
+    );
+
+    CREATE TABLE IF NOT EXISTS tenants (
+        id TEXT PRIMARY KEY,
+        name TEXT NOT NULL UNIQUE,
+        created_at TIMESTAMP NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS tenant_members (
+        tenant_id TEXT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        role TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        PRIMARY KEY (tenant_id, user_id)
+    );
+    CREATE INDEX IF NOT EXISTS idx_tenant_members_user ON tenant_members(user_id);
+
+    CREATE TABLE IF NOT EXISTS audit_events (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        type TEXT NOT NULL,
+        actor_id TEXT NOT NULL DEFAULT '',
+        actor_login TEXT NOT NULL DEFAULT '',
+        target_id TEXT NOT NULL DEFAULT '',
+        target_login TEXT NOT NULL DEFAULT '',
+        client_ip TEXT NOT NULL DEFAULT '',
+        user_agent TEXT NOT NULL DEFAULT '',
+        details TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_audit_events_created ON audit_events(created_at);
+    CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+    BEGIN
+        SELECT RAISE(ABORT, 'audit log is append-only');
+    END;
+    CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+    BEGIN
+        SELECT RAISE(ABORT, 'audit log is append-only');
+    END;
+
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        id TEXT PRIMARY KEY,
+        applied_at TIMESTAMP NOT NULL
+    );
     `
-	DB.MustExec(schema)
-}
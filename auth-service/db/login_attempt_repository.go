@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+type LoginAttemptRepository struct {
+	db *DBWrapper
+}
+
+func NewLoginAttemptRepository(db *sqlx.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *LoginAttemptRepository) GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.GetContext(ctx, &attempt, `SELECT * FROM login_attempts WHERE key = ?`, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login attempt: %w", err)
+	}
+	return &attempt, nil
+}
+
+// ReserveAttempt атомарно враховує спробу входу для key як невдалу, якщо
+// лічильник (без невдач, старших за resetBefore) менший за limit. false —
+// ліміт уже досягнуто, можливо, паралельною спробою.
+func (r *LoginAttemptRepository) ReserveAttempt(ctx context.Context, key string, limit int, now time.Time, resetBefore time.Time) (bool, error) {
+	query := `
+        INSERT INTO login_attempts (key, failures, last_failure_at)
+        SELECT ?, 1, ? WHERE ? > 0
+        ON CONFLICT(key) DO UPDATE SET
+            failures = CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END,
+            last_failure_at = excluded.last_failure_at
+        WHERE CASE WHEN last_failure_at < ? THEN 0 ELSE failures END < ?
+    `
+	res, err := r.db.ExecContext(ctx, query, key, now, limit, resetBefore, resetBefore, limit)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve login attempt: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve login attempt: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RefundAttempt повертає спробу, зараховану ReserveAttempt.
+func (r *LoginAttemptRepository) RefundAttempt(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE login_attempts SET failures = failures - 1 WHERE key = ? AND failures > 0`, key)
+	if err != nil {
+		return fmt.Errorf("failed to refund login attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *LoginAttemptRepository) DeleteLoginAttempt(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = ?`, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete login attempt: %w", err)
+	}
+	return nil
+}
+
+// PruneLoginAttempts видаляє лічильники, остання невдача яких була раніше before.
+func (r *LoginAttemptRepository) PruneLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE last_failure_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune login attempts: %w", err)
+	}
+	return res.RowsAffected()
+}
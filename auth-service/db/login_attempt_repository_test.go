@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLoginAttemptTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE login_attempts (
+		key TEXT PRIMARY KEY,
+		failures INTEGER NOT NULL,
+		last_failure_at DATETIME NOT NULL
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestLoginAttemptRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupLoginAttemptTestDB(t)
+	repo := NewLoginAttemptRepository(db)
+	key := "login:testuser"
+	start := time.Now()
+
+	t.Run("ReserveAttempt", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			now := start.Add(time.Duration(i) * time.Second)
+			ok, err := repo.ReserveAttempt(ctx, key, 3, now, now.Add(-time.Hour))
+			require.NoError(t, err)
+			assert.True(t, ok)
+		}
+
+		ok, err := repo.ReserveAttempt(ctx, key, 3, start.Add(4*time.Second), start.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.False(t, ok, "limit is reached")
+
+		attempt, err := repo.GetLoginAttempt(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempt.Failures)
+		assert.WithinDuration(t, start.Add(3*time.Second), attempt.LastFailureAt, time.Millisecond)
+	})
+
+	t.Run("RefundAttempt", func(t *testing.T) {
+		require.NoError(t, repo.RefundAttempt(ctx, key))
+
+		attempt, err := repo.GetLoginAttempt(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempt.Failures)
+	})
+
+	t.Run("ReserveAttemptAfterReset", func(t *testing.T) {
+		now := start.Add(2 * time.Hour)
+		ok, err := repo.ReserveAttempt(ctx, key, 1, now, now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.True(t, ok, "expired failures do not count towards the limit")
+
+		attempt, err := repo.GetLoginAttempt(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempt.Failures)
+	})
+
+	t.Run("DeleteLoginAttempt", func(t *testing.T) {
+		require.NoError(t, repo.DeleteLoginAttempt(ctx, key))
+
+		_, err := repo.GetLoginAttempt(ctx, key)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("PruneLoginAttempts", func(t *testing.T) {
+		_, err := repo.ReserveAttempt(ctx, "ip:old", 1, start.Add(-2*time.Hour), start.Add(-3*time.Hour))
+		require.NoError(t, err)
+		_, err = repo.ReserveAttempt(ctx, "ip:new", 1, start, start.Add(-time.Hour))
+		require.NoError(t, err)
+
+		n, err := repo.PruneLoginAttempts(ctx, start.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		_, err = repo.GetLoginAttempt(ctx, "ip:new")
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+var ErrMFAEnabled = errors.New("mfa is already enabled")
+
+type MFARepository struct {
+	db *DBWrapper
+}
+
+func NewMFARepository(db *sqlx.DB) *MFARepository {
+	return &MFARepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *MFARepository) GetUserMFA(ctx context.Context, userID string) (*models.UserMFA, error) {
+	var m models.UserMFA
+	err := r.db.GetContext(ctx, &m, `SELECT * FROM user_mfa WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user mfa: %w", err)
+	}
+	return &m, nil
+}
+
+// SavePendingMFA зберігає новий секрет, що ще не підтверджений кодом.
+// Якщо MFA вже ввімкнено, повертає ErrMFAEnabled.
+func (r *MFARepository) SavePendingMFA(ctx context.Context, userID string, secret string, now time.Time) error {
+	query := `
+        INSERT INTO user_mfa (user_id, secret, enabled, last_used_step, created_at)
+        VALUES (?, ?, 0, 0, ?)
+        ON CONFLICT(user_id) DO UPDATE SET
+            secret = excluded.secret,
+            last_used_step = 0,
+            created_at = excluded.created_at
+        WHERE NOT user_mfa.enabled
+    `
+	res, err := r.db.ExecContext(ctx, query, userID, secret, now)
+	if err != nil {
+		return fmt.Errorf("failed to save user mfa: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("failed to save user mfa: %w", ErrMFAEnabled)
+	}
+	return nil
+}
+
+// EnableMFA підтверджує секрет користувача: step — крок уже використаного
+// коду, codeHashes замінюють попередні коди відновлення.
+func (r *MFARepository) EnableMFA(ctx context.Context, userID string, step int64, codeHashes []string, now time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE user_mfa SET enabled = 1, enabled_at = ?, last_used_step = ? WHERE user_id = ? AND NOT enabled`,
+		now, step, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("failed to enable mfa: %w", ErrMFAEnabled)
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+	return nil
+}
+
+// UseStep атомарно позначає крок TOTP використаним. Повертає false, якщо
+// цей або новіший крок уже використовувався, тобто код повторюється.
+func (r *MFARepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE user_mfa SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?`,
+		step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %w", err)
+	}
+	return n > 0, nil
+}
+
+// UseRecoveryCode погашає код відновлення. Повертає false, якщо коду немає
+// або його вже використано.
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID string, codeHash string, now time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE mfa_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		now, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountRecoveryCodes повертає кількість ще не використаних кодів відновлення.
+func (r *MFARepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteUserMFA вимикає MFA користувача разом з кодами відновлення.
+func (r *MFARepository) DeleteUserMFA(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete user mfa: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM mfa_recovery_codes WHERE user_id = ?`,
+		`DELETE FROM mfa_challenges WHERE user_id = ?`,
+		`DELETE FROM user_mfa WHERE user_id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("failed to delete user mfa: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete user mfa: %w", err)
+	}
+	return nil
+}
+
+func (r *MFARepository) CreateChallenge(ctx context.Context, c *models.MFAChallenge) error {
+	query := `
+        INSERT INTO mfa_challenges (id, user_id, attempts, expires_at, created_at)
+        VALUES (:id, :user_id, :attempts, :expires_at, :created_at)
+    `
+	_, err := r.db.NamedExecContext(ctx, query, c)
+	if err != nil {
+		return fmt.Errorf("failed to create mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// AttemptChallenge враховує спробу ввести код і повертає challenge з
+// оновленим лічильником. Прострочений challenge не знаходиться.
+func (r *MFARepository) AttemptChallenge(ctx context.Context, id string, now time.Time) (*models.MFAChallenge, error) {
+	var c models.MFAChallenge
+	err := r.db.GetContext(ctx, &c,
+		`UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = ? AND expires_at > ? RETURNING *`,
+		id, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attempt mfa challenge: %w", err)
+	}
+	return &c, nil
+}
+
+func (r *MFARepository) DeleteChallenge(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// PruneChallenges видаляє прострочені challenge.
+func (r *MFARepository) PruneChallenges(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune mfa challenges: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupMFATestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE user_mfa (
+		user_id TEXT PRIMARY KEY,
+		secret TEXT NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT 0,
+		last_used_step INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		enabled_at DATETIME
+	);
+	CREATE TABLE mfa_recovery_codes (
+		user_id TEXT NOT NULL,
+		code_hash TEXT NOT NULL,
+		used_at DATETIME,
+		PRIMARY KEY (user_id, code_hash)
+	);
+	CREATE TABLE mfa_challenges (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestMFARepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupMFATestDB(t)
+	repo := NewMFARepository(db)
+	userID := "user-1"
+	now := time.Now()
+
+	t.Run("SavePendingMFA", func(t *testing.T) {
+		require.NoError(t, repo.SavePendingMFA(ctx, userID, "SECRET1", now))
+		require.NoError(t, repo.SavePendingMFA(ctx, userID, "SECRET2", now))
+
+		m, err := repo.GetUserMFA(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "SECRET2", m.Secret)
+		assert.False(t, m.Enabled)
+		assert.Nil(t, m.EnabledAt)
+	})
+
+	t.Run("EnableMFA", func(t *testing.T) {
+		require.NoError(t, repo.EnableMFA(ctx, userID, 100, []string{"h1", "h2"}, now))
+
+		m, err := repo.GetUserMFA(ctx, userID)
+		require.NoError(t, err)
+		assert.True(t, m.Enabled)
+		assert.Equal(t, int64(100), m.LastUsedStep)
+		require.NotNil(t, m.EnabledAt)
+
+		err = repo.SavePendingMFA(ctx, userID, "SECRET3", now)
+		require.ErrorIs(t, err, ErrMFAEnabled)
+		err = repo.EnableMFA(ctx, userID, 101, nil, now)
+		require.ErrorIs(t, err, ErrMFAEnabled)
+
+		count, err := repo.CountRecoveryCodes(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("UseStep", func(t *testing.T) {
+		ok, err := repo.UseStep(ctx, userID, 100)
+		require.NoError(t, err)
+		assert.False(t, ok, "step already used")
+
+		ok, err = repo.UseStep(ctx, userID, 101)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = repo.UseStep(ctx, userID, 101)
+		require.NoError(t, err)
+		assert.False(t, ok, "code must not be reusable")
+	})
+
+	t.Run("UseRecoveryCode", func(t *testing.T) {
+		ok, err := repo.UseRecoveryCode(ctx, userID, "h1", now)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = repo.UseRecoveryCode(ctx, userID, "h1", now)
+		require.NoError(t, err)
+		assert.False(t, ok, "recovery code is single use")
+
+		ok, err = repo.UseRecoveryCode(ctx, userID, "missing", now)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		count, err := repo.CountRecoveryCodes(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		require.NoError(t, repo.ReplaceRecoveryCodes(ctx, userID, []string{"h3", "h4", "h5"}))
+		count, err = repo.CountRecoveryCodes(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("Challenges", func(t *testing.T) {
+		c := &models.MFAChallenge{ID: "c1", UserID: userID, ExpiresAt: now.Add(time.Minute), CreatedAt: now}
+		require.NoError(t, repo.CreateChallenge(ctx, c))
+		expired := &models.MFAChallenge{ID: "c2", UserID: userID, ExpiresAt: now.Add(-time.Minute), CreatedAt: now}
+		require.NoError(t, repo.CreateChallenge(ctx, expired))
+
+		got, err := repo.AttemptChallenge(ctx, "c1", now)
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.Attempts)
+		got, err = repo.AttemptChallenge(ctx, "c1", now)
+		require.NoError(t, err)
+		assert.Equal(t, 2, got.Attempts)
+
+		_, err = repo.AttemptChallenge(ctx, "c2", now)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		n, err := repo.PruneChallenges(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		require.NoError(t, repo.DeleteChallenge(ctx, "c1"))
+		_, err = repo.AttemptChallenge(ctx, "c1", now)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("DeleteUserMFA", func(t *testing.T) {
+		require.NoError(t, repo.DeleteUserMFA(ctx, userID))
+
+		_, err := repo.GetUserMFA(ctx, userID)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		count, err := repo.CountRecoveryCodes(ctx, userID)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
@@ -0,0 +1,299 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+// migration — зміна даних або схеми, яку не можна виразити через
+// CREATE TABLE IF NOT EXISTS. Кожна виконується один раз, в транзакції,
+// і записується в schema_migrations.
+type migration struct {
+	id string
+	up func(tx *sqlx.Tx) error
+}
+
+var migrations = []migration{
+	{id: "001_seed_roles", up: seedRoles},
+	{id: "002_roles_from_is_admin", up: migrateIsAdmin},
+	{id: "003_roles_require_mfa", up: addRoleRequireMFA},
+	{id: "004_seed_clients_permission", up: seedClientsPermission},
+	{id: "005_clients_public", up: addClientPublic},
+	{id: "006_seed_audit_permission", up: seedAuditPermission},
+	{id: "007_users_email", up: addUserEmail},
+	{id: "008_users_status", up: addUserStatus},
+	{id: "009_seed_impersonate_permission", up: seedImpersonatePermission},
+	{id: "010_seed_tenants_permission", up: seedTenantsPermission},
+	{id: "011_tenant_columns", up: addTenantColumns},
+	{id: "012_delete_orphaned_rows", up: deleteOrphanedRows},
+	{id: "013_seed_introspect_permission", up: seedIntrospectPermission},
+}
+
+func migrate(db *sqlx.DB) error {
+	for _, m := range migrations {
+		var applied int
+		if err := db.Get(&applied, `SELECT COUNT(*) FROM schema_migrations WHERE id = ?`, m.id); err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", m.id, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %w", m.id, err)
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %s: %w", m.id, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (id, applied_at) VALUES (?, ?)`, m.id, time.Now()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %s: %w", m.id, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", m.id, err)
+		}
+		log.Printf("Applied migration %s", m.id)
+	}
+	return nil
+}
+
+// seedRoles створює вбудовані дозволи і ролі: admin має всі дозволи,
+// user — лише перегляд продуктів. Дозволи, що з'явилися пізніше, додають
+// власні міграції.
+func seedRoles(tx *sqlx.Tx) error {
+	permissions := []models.Permission{
+		{Name: models.PermissionProductRead, Description: "Перегляд продуктів"},
+		{Name: models.PermissionProductWrite, Description: "Створення, зміна і видалення продуктів"},
+		{Name: models.PermissionUsersManage, Description: "Керування користувачами"},
+		{Name: models.PermissionRolesManage, Description: "Керування ролями і дозволами"},
+		{Name: models.PermissionKeysManage, Description: "Керування ключами підпису JWT"},
+	}
+	admin := make([]string, len(permissions))
+	for i, p := range permissions {
+		admin[i] = p.Name
+	}
+	roles := map[string][]string{
+		models.RoleAdmin: admin,
+		models.RoleUser:  {models.PermissionProductRead},
+	}
+	descriptions := map[string]string{
+		models.RoleAdmin: "Адміністратор",
+		models.RoleUser:  "Користувач",
+	}
+
+	for _, p := range permissions {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO permissions (name, description) VALUES (?, ?)`, p.Name, p.Description); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	for role, perms := range roles {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO roles (name, description, created_at) VALUES (?, ?, ?)`, role, descriptions[role], now); err != nil {
+			return err
+		}
+		for _, p := range perms {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`, role, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateIsAdmin переносить старий прапорець users.is_admin у ролі і видаляє
+// колонку. У новій БД колонки немає, і міграція нічого не робить.
+func migrateIsAdmin(tx *sqlx.Tx) error {
+	var hasColumn int
+	if err := tx.Get(&hasColumn, `SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = 'is_admin'`); err != nil {
+		return err
+	}
+	if hasColumn == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(`
+        INSERT OR IGNORE INTO user_roles (user_id, role)
+        SELECT id, CASE WHEN is_admin THEN ? ELSE ? END FROM users
+    `, models.RoleAdmin, models.RoleUser); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`ALTER TABLE users DROP COLUMN is_admin`)
+	return err
+}
+
+// addRoleRequireMFA додає колонку roles.require_mfa у БД, створену до появи
+// двофакторної автентифікації.
+func addRoleRequireMFA(tx *sqlx.Tx) error {
+	var hasColumn int
+	if err := tx.Get(&hasColumn, `SELECT COUNT(*) FROM pragma_table_info('roles') WHERE name = 'require_mfa'`); err != nil {
+		return err
+	}
+	if hasColumn > 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`ALTER TABLE roles ADD COLUMN require_mfa BOOLEAN NOT NULL DEFAULT 0`)
+	return err
+}
+
+// seedClientsPermission додає дозвіл clients:manage і видає його ролі admin
+// у БД, створеній до появи OAuth клієнтів.
+func seedClientsPermission(tx *sqlx.Tx) error {
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO permissions (name, description) VALUES (?, ?)`,
+		models.PermissionClientsManage, "Керування OAuth клієнтами"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`,
+		models.RoleAdmin, models.PermissionClientsManage)
+	return err
+}
+
+// addClientPublic додає колонку clients.public у БД, створену до появи
+// grant authorization_code.
+func addClientPublic(tx *sqlx.Tx) error {
+	var hasColumn int
+	if err := tx.Get(&hasColumn, `SELECT COUNT(*) FROM pragma_table_info('clients') WHERE name = 'public'`); err != nil {
+		return err
+	}
+	if hasColumn > 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN public BOOLEAN NOT NULL DEFAULT 0`)
+	return err
+}
+
+// seedAuditPermission додає дозвіл audit:read і видає його ролі admin у БД,
+// створеній до появи журналу аудиту.
+func seedAuditPermission(tx *sqlx.Tx) error {
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO permissions (name, description) VALUES (?, ?)`,
+		models.PermissionAuditRead, "Перегляд журналу аудиту"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`,
+		models.RoleAdmin, models.PermissionAuditRead)
+	return err
+}
+
+// addUserEmail додає колонку users.email у БД, створену до появи скидання
+// пароля, і унікальний індекс на неї. Email необов'язковий, тож порожні
+// значення з індексу виключено.
+func addUserEmail(tx *sqlx.Tx) error {
+	var hasColumn int
+	if err := tx.Get(&hasColumn, `SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = 'email'`); err != nil {
+		return err
+	}
+	if hasColumn == 0 {
+		if _, err := tx.Exec(`ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email != ''`)
+	return err
+}
+
+// addUserStatus додає колонки users.status і users.email_verified_at у БД,
+// створену до появи підтвердження email. Існуючі користувачі лишаються
+// активними.
+func addUserStatus(tx *sqlx.Tx) error {
+	columns := map[string]string{
+		"status":            `ALTER TABLE users ADD COLUMN status TEXT NOT NULL DEFAULT 'active'`,
+		"email_verified_at": `ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP`,
+	}
+	for name, stmt := range columns {
+		var hasColumn int
+		if err := tx.Get(&hasColumn, `SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = ?`, name); err != nil {
+			return err
+		}
+		if hasColumn > 0 {
+			continue
+		}
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedImpersonatePermission додає дозвіл users:impersonate і видає його ролі
+// admin у БД, створеній до появи імперсонації.
+func seedImpersonatePermission(tx *sqlx.Tx) error {
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO permissions (name, description) VALUES (?, ?)`,
+		models.PermissionUsersImpersonate, "Вхід від імені користувача"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`,
+		models.RoleAdmin, models.PermissionUsersImpersonate)
+	return err
+}
+
+// seedTenantsPermission додає дозвіл tenants:manage і видає його ролі admin
+// у БД, створеній до появи організацій: адміністратори стають
+// адміністраторами платформи.
+func seedTenantsPermission(tx *sqlx.Tx) error {
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO permissions (name, description) VALUES (?, ?)`,
+		models.PermissionTenantsManage, "Керування організаціями"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`,
+		models.RoleAdmin, models.PermissionTenantsManage)
+	return err
+}
+
+// addTenantColumns додає колонки sessions.tenant_id і api_keys.tenant_id у
+// БД, створену до появи організацій. Існуючі сесії і ключі лишаються поза
+// організаціями.
+func addTenantColumns(tx *sqlx.Tx) error {
+	for _, table := range []string{"sessions", "api_keys"} {
+		var hasColumn int
+		if err := tx.Get(&hasColumn, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = 'tenant_id'`, table); err != nil {
+			return err
+		}
+		if hasColumn > 0 {
+			continue
+		}
+		if _, err := tx.Exec(`ALTER TABLE ` + table + ` ADD COLUMN tenant_id TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userTables — таблиці з даними користувача, що видаляються каскадно разом
+// з ним.
+var userTables = []string{
+	"refresh_tokens", "sessions", "user_tokens", "user_roles", "password_history",
+	"user_mfa", "mfa_recovery_codes", "mfa_challenges", "api_keys", "tenant_members",
+}
+
+// deleteOrphanedRows видаляє дані користувачів, видалених до того, як у БД
+// увімкнули зовнішні ключі: тоді ON DELETE CASCADE не спрацьовував.
+func deleteOrphanedRows(tx *sqlx.Tx) error {
+	for _, table := range userTables {
+		if _, err := tx.Exec(`DELETE FROM ` + table + ` WHERE user_id NOT IN (SELECT id FROM users)`); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(`DELETE FROM api_key_scopes WHERE api_key_id NOT IN (SELECT id FROM api_keys)`)
+	return err
+}
+
+// seedIntrospectPermission додає scope tokens:introspect і видає його ролі
+// admin, щоб адміністратори могли призначати його клієнтам.
+func seedIntrospectPermission(tx *sqlx.Tx) error {
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO permissions (name, description) VALUES (?, ?)`,
+		models.PermissionTokensIntrospect, "Інтроспекція токенів (scope OAuth клієнтів)"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`,
+		models.RoleAdmin, models.PermissionTokensIntrospect)
+	return err
+}
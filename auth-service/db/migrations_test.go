@@ -0,0 +1,30 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func TestMigrateSeedsBuiltinPermissions(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:?_foreign_keys=on")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	db.MustExec(schema)
+	require.NoError(t, migrate(db))
+
+	var permissions []string
+	require.NoError(t, db.Select(&permissions, `SELECT name FROM permissions`))
+	assert.ElementsMatch(t, models.BuiltinPermissions, permissions)
+
+	var admin []string
+	require.NoError(t, db.Select(&admin, `SELECT permission FROM role_permissions WHERE role = ?`, models.RoleAdmin))
+	assert.ElementsMatch(t, models.BuiltinPermissions, admin)
+
+	// Повторний запуск нічого не змінює.
+	require.NoError(t, migrate(db))
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PasswordHistoryRepository зберігає хеші попередніх паролів користувачів,
+// щоб не дозволяти повторно використовувати їх.
+type PasswordHistoryRepository struct {
+	db *DBWrapper
+}
+
+func NewPasswordHistoryRepository(db *sqlx.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+// AddPassword записує хеш нового пароля і лишає в історії користувача лише
+// keep останніх записів.
+func (r *PasswordHistoryRepository) AddPassword(ctx context.Context, userID string, hash string, createdAt time.Time, keep int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to add password history: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO password_history (user_id, password, created_at) VALUES (?, ?, ?)`,
+		userID, hash, createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to add password history: %w", err)
+	}
+
+	query := `
+        DELETE FROM password_history
+        WHERE user_id = ? AND id NOT IN (
+            SELECT id FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?
+        )
+    `
+	if _, err := tx.ExecContext(ctx, query, userID, userID, keep); err != nil {
+		return fmt.Errorf("failed to add password history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to add password history: %w", err)
+	}
+	return nil
+}
+
+// RecentPasswords повертає хеші limit останніх паролів, новіші першими.
+func (r *PasswordHistoryRepository) RecentPasswords(ctx context.Context, userID string, limit int) ([]string, error) {
+	hashes := []string{}
+	err := r.db.SelectContext(ctx, &hashes,
+		`SELECT password FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?`,
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list password history: %w", err)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPasswordHistoryTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE password_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		password TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestPasswordHistoryRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupPasswordHistoryTestDB(t)
+	repo := NewPasswordHistoryRepository(db)
+	now := time.Now()
+
+	t.Run("RecentPasswordsEmpty", func(t *testing.T) {
+		hashes, err := repo.RecentPasswords(ctx, "u1", 5)
+		require.NoError(t, err)
+		assert.Empty(t, hashes)
+	})
+
+	t.Run("AddPasswordKeepsLatest", func(t *testing.T) {
+		for _, hash := range []string{"h1", "h2", "h3", "h4"} {
+			require.NoError(t, repo.AddPassword(ctx, "u1", hash, now, 3))
+		}
+		require.NoError(t, repo.AddPassword(ctx, "u2", "other", now, 3))
+
+		hashes, err := repo.RecentPasswords(ctx, "u1", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"h4", "h3", "h2"}, hashes)
+
+		hashes, err = repo.RecentPasswords(ctx, "u2", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"other"}, hashes)
+	})
+
+	t.Run("RecentPasswordsLimit", func(t *testing.T) {
+		hashes, err := repo.RecentPasswords(ctx, "u1", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"h4", "h3"}, hashes)
+	})
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupRefreshTokenTestDB(t *testing.T) *sqlx.DB {
+	db := setupTestDB(t)
+
+	schema := `
+	CREATE TABLE refresh_tokens (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		family_id TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		used_at DATETIME,
+		revoked_at DATETIME
+	);`
+	_, err := db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestRefreshTokenRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupRefreshTokenTestDB(t)
+	repo := NewRefreshTokenRepository(db)
+
+	familyID := uuid.NewString()
+	newToken := func(hash string) *models.RefreshToken {
+		return &models.RefreshToken{
+			ID:        uuid.NewString(),
+			UserID:    uuid.NewString(),
+			FamilyID:  familyID,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(time.Hour),
+			CreatedAt: time.Now(),
+		}
+	}
+
+	first := newToken("hash-1")
+	second := newToken("hash-2")
+
+	t.Run("CreateRefreshToken", func(t *testing.T) {
+		require.NoError(t, repo.CreateRefreshToken(ctx, first))
+		require.NoError(t, repo.CreateRefreshToken(ctx, second))
+	})
+
+	t.Run("GetRefreshTokenByHash", func(t *testing.T) {
+		fetched, err := repo.GetRefreshTokenByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, fetched.ID)
+		assert.Nil(t, fetched.UsedAt)
+	})
+
+	t.Run("MarkRefreshTokenUsed", func(t *testing.T) {
+		marked, err := repo.MarkRefreshTokenUsed(ctx, first.ID)
+		require.NoError(t, err)
+		assert.True(t, marked)
+
+		// Повторне використання не проходить
+		marked, err = repo.MarkRefreshTokenUsed(ctx, first.ID)
+		require.NoError(t, err)
+		assert.False(t, marked)
+	})
+
+	t.Run("RevokeFamily", func(t *testing.T) {
+		require.NoError(t, repo.RevokeFamily(ctx, familyID))
+
+		fetched, err := repo.GetRefreshTokenByHash(ctx, "hash-2")
+		require.NoError(t, err)
+		assert.NotNil(t, fetched.RevokedAt)
+
+		marked, err := repo.MarkRefreshTokenUsed(ctx, second.ID)
+		require.NoError(t, err)
+		assert.False(t, marked)
+	})
+	t.Run("RevokeAllForUser", func(t *testing.T) {
+		third := newToken("hash-3")
+		third.FamilyID = uuid.NewString()
+		require.NoError(t, repo.CreateRefreshToken(ctx, third))
+
+		require.NoError(t, repo.RevokeAllForUser(ctx, third.UserID))
+
+		fetched, err := repo.GetRefreshTokenByHash(ctx, "hash-3")
+		require.NoError(t, err)
+		assert.NotNil(t, fetched.RevokedAt)
+	})
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RevocationRepository зберігає відкликані jti та "водяні знаки" користувачів:
+// усі токени користувача, видані до revoked_before, вважаються відкликаними.
+type RevocationRepository struct {
+	db *DBWrapper
+}
+
+func NewRevocationRepository(db *sqlx.DB) *RevocationRepository {
+	return &RevocationRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *RevocationRepository) RevokeToken(ctx context.Context, jti string, userID string, expiresAt time.Time) error {
+	query := `
+        INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT(jti) DO NOTHING
+    `
+	_, err := r.db.ExecContext(ctx, query, jti, userID, expiresAt, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser відкликає всі токени користувача, видані до цієї миті.
+// Зберігається точний час відкликання; з iat його порівнює IsRevoked.
+func (r *RevocationRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	query := `
+        INSERT INTO token_watermarks (user_id, revoked_before)
+        VALUES (?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET revoked_before = excluded.revoked_before
+    `
+	_, err := r.db.ExecContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *RevocationRepository) IsRevoked(ctx context.Context, jti string, userID string, issuedAt time.Time) (bool, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?`, jti)
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	var revokedBefore time.Time
+	err = r.db.GetContext(ctx, &revokedBefore, `SELECT revoked_before FROM token_watermarks WHERE user_id = ?`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check token watermark: %w", err)
+	}
+	// iat у JWT має точність до секунди, тож токени, видані в ту саму секунду,
+	// що й відкликання, лишаються дійсними: інакше новий токен після зміни
+	// пароля чи повторного входу відкликався б одразу ж.
+	return issuedAt.Before(revokedBefore.Truncate(time.Second)), nil
+}
+
+// PruneExpired видаляє записи про відкликані токени, строк дії яких уже минув.
+func (r *RevocationRepository) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune revoked tokens: %w", err)
+	}
+	return res.RowsAffected()
+}
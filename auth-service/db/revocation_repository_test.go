@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRevocationTestDB(t *testing.T) *sqlx.DB {
+	db := setupTestDB(t)
+
+	schema := `
+	CREATE TABLE revoked_tokens (
+		jti TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		expires_at DATETIME NOT NULL,
+		revoked_at DATETIME NOT NULL
+	);
+	CREATE TABLE token_watermarks (
+		user_id TEXT PRIMARY KEY,
+		revoked_before DATETIME NOT NULL
+	);`
+	_, err := db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestRevocationRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupRevocationTestDB(t)
+	repo := NewRevocationRepository(db)
+
+	userID := uuid.NewString()
+	jti := uuid.NewString()
+	issuedAt := time.Now().Truncate(time.Second)
+
+	t.Run("RevokeToken", func(t *testing.T) {
+		revoked, err := repo.IsRevoked(ctx, jti, userID, issuedAt)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		require.NoError(t, repo.RevokeToken(ctx, jti, userID, time.Now().Add(time.Hour)))
+		// Повторне відкликання не є помилкою
+		require.NoError(t, repo.RevokeToken(ctx, jti, userID, time.Now().Add(time.Hour)))
+
+		revoked, err = repo.IsRevoked(ctx, jti, userID, issuedAt)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("RevokeAllForUser", func(t *testing.T) {
+		otherJTI := uuid.NewString()
+		require.NoError(t, repo.RevokeAllForUser(ctx, userID))
+
+		revoked, err := repo.IsRevoked(ctx, otherJTI, userID, issuedAt.Add(-time.Second))
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		// Токени, видані після відкликання, лишаються дійсними
+		revoked, err = repo.IsRevoked(ctx, otherJTI, userID, issuedAt.Add(2*time.Second))
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		revoked, err = repo.IsRevoked(ctx, otherJTI, uuid.NewString(), issuedAt)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("TokenIssuedInSameSecond", func(t *testing.T) {
+		userID := uuid.NewString()
+		require.NoError(t, repo.RevokeAllForUser(ctx, userID))
+		// Токен, виданий одразу після відкликання, має iat тієї ж секунди
+		reissuedAt := time.Now().Truncate(time.Second)
+
+		revoked, err := repo.IsRevoked(ctx, uuid.NewString(), userID, reissuedAt)
+		require.NoError(t, err)
+		assert.False(t, revoked, "token issued right after the revoke must stay valid")
+
+		revoked, err = repo.IsRevoked(ctx, uuid.NewString(), userID, reissuedAt.Add(-time.Second))
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("PruneExpired", func(t *testing.T) {
+		expiredJTI := uuid.NewString()
+		require.NoError(t, repo.RevokeToken(ctx, expiredJTI, userID, time.Now().Add(-time.Minute)))
+
+		n, err := repo.PruneExpired(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		revoked, err := repo.IsRevoked(ctx, jti, uuid.NewString(), issuedAt)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+}
@@ -0,0 +1,310 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+var (
+	ErrRoleExists        = errors.New("role already exists")
+	ErrPermissionExists  = errors.New("permission already exists")
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+type RoleRepository struct {
+	db *DBWrapper
+}
+
+func NewRoleRepository(db *sqlx.DB) *RoleRepository {
+	return &RoleRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *RoleRepository) ListRoles(ctx context.Context) ([]models.Role, error) {
+	roles := []models.Role{}
+	err := r.db.SelectContext(ctx, &roles, `SELECT * FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	var rows []struct {
+		Role       string `db:"role"`
+		Permission string `db:"permission"`
+	}
+	err = r.db.SelectContext(ctx, &rows, `SELECT role, permission FROM role_permissions ORDER BY permission`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role permissions: %w", err)
+	}
+	byRole := map[string][]string{}
+	for _, row := range rows {
+		byRole[row.Role] = append(byRole[row.Role], row.Permission)
+	}
+	for i := range roles {
+		roles[i].Permissions = byRole[roles[i].Name]
+		if roles[i].Permissions == nil {
+			roles[i].Permissions = []string{}
+		}
+	}
+	return roles, nil
+}
+
+func (r *RoleRepository) GetRole(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	err := r.db.GetContext(ctx, &role, `SELECT * FROM roles WHERE name = ?`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	role.Permissions = []string{}
+	err = r.db.SelectContext(ctx, &role.Permissions, `SELECT permission FROM role_permissions WHERE role = ? ORDER BY permission`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) CreateRole(ctx context.Context, role *models.Role) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.NamedExecContext(ctx, `INSERT INTO roles (name, description, require_mfa, created_at) VALUES (:name, :description, :require_mfa, :created_at)`, role)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("failed to create role: %w", ErrRoleExists)
+		}
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	if err := setRolePermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	return nil
+}
+
+// UpdateRole змінює опис ролі і вимогу MFA та замінює її дозволи.
+func (r *RoleRepository) UpdateRole(ctx context.Context, role *models.Role) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE roles SET description = ?, require_mfa = ? WHERE name = ?`, role.Description, role.RequireMFA, role.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if err := setRolePermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	return nil
+}
+
+func (r *RoleRepository) DeleteRole(ctx context.Context, name string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM user_roles WHERE role = ?`,
+		`DELETE FROM role_permissions WHERE role = ?`,
+		`DELETE FROM roles WHERE name = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, name); err != nil {
+			return fmt.Errorf("failed to delete role: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	return nil
+}
+
+func setRolePermissions(ctx context.Context, tx *sqlx.Tx, role string, permissions []string) error {
+	if err := requireExisting(ctx, tx, "permissions", permissions, ErrUnknownPermission); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role = ?`, role); err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`, role, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireExisting повертає notFound, якщо хоча б одного з names немає в table.
+func requireExisting(ctx context.Context, tx *sqlx.Tx, table string, names []string, notFound error) error {
+	for _, name := range names {
+		var count int
+		if err := tx.GetContext(ctx, &count, `SELECT COUNT(*) FROM `+table+` WHERE name = ?`, name); err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: %s", notFound, name)
+		}
+	}
+	return nil
+}
+
+func (r *RoleRepository) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	err := r.db.SelectContext(ctx, &permissions, `SELECT * FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+func (r *RoleRepository) CreatePermission(ctx context.Context, permission *models.Permission) error {
+	_, err := r.db.NamedExecContext(ctx, `INSERT INTO permissions (name, description) VALUES (:name, :description)`, permission)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("failed to create permission: %w", ErrPermissionExists)
+		}
+		return fmt.Errorf("failed to create permission: %w", err)
+	}
+	return nil
+}
+
+// DeletePermission видаляє дозвіл і забирає його в усіх ролей. Повертає false,
+// якщо такого дозволу не було.
+func (r *RoleRepository) DeletePermission(ctx context.Context, name string) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete permission: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE permission = ?`, name); err != nil {
+		return false, fmt.Errorf("failed to delete permission: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM client_scopes WHERE permission = ?`, name); err != nil {
+		return false, fmt.Errorf("failed to delete permission: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM permissions WHERE name = ?`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete permission: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete permission: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to delete permission: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *RoleRepository) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	roles := []string{}
+	err := r.db.SelectContext(ctx, &roles, `SELECT role FROM user_roles WHERE user_id = ? ORDER BY role`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	return roles, nil
+}
+
+// GetRolesForUsers повертає ролі кількох користувачів одним запитом.
+func (r *RoleRepository) GetRolesForUsers(ctx context.Context, userIDs []string) (map[string][]string, error) {
+	result := map[string][]string{}
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT user_id, role FROM user_roles WHERE user_id IN (?) ORDER BY role`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users roles: %w", err)
+	}
+	var rows []struct {
+		UserID string `db:"user_id"`
+		Role   string `db:"role"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, r.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to get users roles: %w", err)
+	}
+	for _, row := range rows {
+		result[row.UserID] = append(result[row.UserID], row.Role)
+	}
+	return result, nil
+}
+
+// SetUserRoles замінює ролі користувача.
+func (r *RoleRepository) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set user roles: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireExisting(ctx, tx, "roles", roles, ErrUnknownRole); err != nil {
+		return fmt.Errorf("failed to set user roles: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to set user roles: %w", err)
+	}
+	for _, role := range roles {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO user_roles (user_id, role) VALUES (?, ?)`, userID, role); err != nil {
+			return fmt.Errorf("failed to set user roles: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to set user roles: %w", err)
+	}
+	return nil
+}
+
+// GetUserPermissions повертає об'єднання дозволів усіх ролей користувача.
+func (r *RoleRepository) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	permissions := []string{}
+	err := r.db.SelectContext(ctx, &permissions, `
+        SELECT DISTINCT rp.permission
+        FROM user_roles ur
+        JOIN role_permissions rp ON rp.role = ur.role
+        WHERE ur.user_id = ?
+        ORDER BY rp.permission
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// UserRequiresMFA повідомляє, чи має користувач хоча б одну роль з вимогою MFA.
+func (r *RoleRepository) UserRequiresMFA(ctx context.Context, userID string) (bool, error) {
+	var required bool
+	err := r.db.GetContext(ctx, &required, `
+        SELECT EXISTS (
+            SELECT 1 FROM user_roles ur
+            JOIN roles ro ON ro.name = ur.role
+            WHERE ur.user_id = ? AND ro.require_mfa
+        )
+    `, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check mfa requirement: %w", err)
+	}
+	return required, nil
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupRoleTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+	require.NoError(t, migrate(db))
+
+	return db
+}
+
+func TestRoleRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupRoleTestDB(t)
+	repo := NewRoleRepository(db)
+	userID := "user-1"
+
+	t.Run("SeededRoles", func(t *testing.T) {
+		roles, err := repo.ListRoles(ctx)
+		require.NoError(t, err)
+		require.Len(t, roles, 2)
+		assert.Equal(t, models.RoleAdmin, roles[0].Name)
+		assert.ElementsMatch(t, models.BuiltinPermissions, roles[0].Permissions)
+		assert.Equal(t, models.RoleUser, roles[1].Name)
+		assert.Equal(t, []string{models.PermissionProductRead}, roles[1].Permissions)
+	})
+
+	t.Run("CreateRole", func(t *testing.T) {
+		role := &models.Role{
+			Name:        "catalog_editor",
+			CreatedAt:   time.Now(),
+			Permissions: []string{models.PermissionProductRead, models.PermissionProductWrite},
+		}
+		require.NoError(t, repo.CreateRole(ctx, role))
+
+		err := repo.CreateRole(ctx, role)
+		require.ErrorIs(t, err, ErrRoleExists)
+
+		err = repo.CreateRole(ctx, &models.Role{Name: "bad", CreatedAt: time.Now(), Permissions: []string{"missing:perm"}})
+		require.ErrorIs(t, err, ErrUnknownPermission)
+		_, err = repo.GetRole(ctx, "bad")
+		require.Error(t, err, "role must not be created when a permission is unknown")
+	})
+
+	t.Run("SetUserRoles", func(t *testing.T) {
+		require.NoError(t, repo.SetUserRoles(ctx, userID, []string{models.RoleUser, "catalog_editor"}))
+
+		roles, err := repo.GetUserRoles(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"catalog_editor", models.RoleUser}, roles)
+
+		permissions, err := repo.GetUserPermissions(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{models.PermissionProductRead, models.PermissionProductWrite}, permissions)
+
+		err = repo.SetUserRoles(ctx, userID, []string{"missing"})
+		require.ErrorIs(t, err, ErrUnknownRole)
+		roles, err = repo.GetUserRoles(ctx, userID)
+		require.NoError(t, err)
+		assert.Len(t, roles, 2, "roles must stay unchanged after a failed update")
+	})
+
+	t.Run("GetRolesForUsers", func(t *testing.T) {
+		require.NoError(t, repo.SetUserRoles(ctx, "user-2", []string{models.RoleAdmin}))
+
+		byUser, err := repo.GetRolesForUsers(ctx, []string{userID, "user-2", "user-3"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"catalog_editor", models.RoleUser}, byUser[userID])
+		assert.Equal(t, []string{models.RoleAdmin}, byUser["user-2"])
+		assert.Empty(t, byUser["user-3"])
+	})
+
+	t.Run("UpdateRole", func(t *testing.T) {
+		role, err := repo.GetRole(ctx, "catalog_editor")
+		require.NoError(t, err)
+		role.Description = "Редактор каталогу"
+		role.Permissions = []string{models.PermissionProductRead}
+		require.NoError(t, repo.UpdateRole(ctx, role))
+
+		updated, err := repo.GetRole(ctx, "catalog_editor")
+		require.NoError(t, err)
+		assert.Equal(t, "Редактор каталогу", updated.Description)
+		assert.Equal(t, []string{models.PermissionProductRead}, updated.Permissions)
+	})
+
+	t.Run("Permissions", func(t *testing.T) {
+		require.NoError(t, repo.CreatePermission(ctx, &models.Permission{Name: "inventory:write"}))
+		err := repo.CreatePermission(ctx, &models.Permission{Name: "inventory:write"})
+		require.ErrorIs(t, err, ErrPermissionExists)
+
+		role, err := repo.GetRole(ctx, "catalog_editor")
+		require.NoError(t, err)
+		role.Permissions = append(role.Permissions, "inventory:write")
+		require.NoError(t, repo.UpdateRole(ctx, role))
+
+		deleted, err := repo.DeletePermission(ctx, "inventory:write")
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		role, err = repo.GetRole(ctx, "catalog_editor")
+		require.NoError(t, err)
+		assert.NotContains(t, role.Permissions, "inventory:write")
+
+		deleted, err = repo.DeletePermission(ctx, "inventory:write")
+		require.NoError(t, err)
+		assert.False(t, deleted)
+	})
+
+	t.Run("UserRequiresMFA", func(t *testing.T) {
+		required, err := repo.UserRequiresMFA(ctx, userID)
+		require.NoError(t, err)
+		assert.False(t, required)
+
+		role, err := repo.GetRole(ctx, "catalog_editor")
+		require.NoError(t, err)
+		role.RequireMFA = true
+		require.NoError(t, repo.UpdateRole(ctx, role))
+
+		required, err = repo.UserRequiresMFA(ctx, userID)
+		require.NoError(t, err)
+		assert.True(t, required)
+		required, err = repo.UserRequiresMFA(ctx, "user-2")
+		require.NoError(t, err)
+		assert.False(t, required)
+	})
+
+	t.Run("DeleteRole", func(t *testing.T) {
+		require.NoError(t, repo.DeleteRole(ctx, "catalog_editor"))
+
+		roles, err := repo.GetUserRoles(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{models.RoleUser}, roles)
+	})
+}
+
+func TestMigrateIsAdmin(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+
+	// Схема до появи ролей
+	_, err = db.Exec(`
+	CREATE TABLE users (
+		id TEXT PRIMARY KEY,
+		login TEXT NOT NULL UNIQUE,
+		password TEXT NOT NULL,
+		is_admin BOOLEAN NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME
+	);
+	INSERT INTO users (id, login, password, is_admin, created_at) VALUES
+		('admin-id', 'admin', 'x', 1, CURRENT_TIMESTAMP),
+		('user-id', 'user', 'x', 0, CURRENT_TIMESTAMP);`)
+	require.NoError(t, err)
+
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+	require.NoError(t, migrate(db))
+
+	repo := NewRoleRepository(db)
+	roles, err := repo.GetUserRoles(context.Background(), "admin-id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.RoleAdmin}, roles)
+	roles, err = repo.GetUserRoles(context.Background(), "user-id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.RoleUser}, roles)
+
+	user, err := NewUserRepository(db).GetUserByID(context.Background(), "admin-id")
+	require.NoError(t, err, "is_admin column must be dropped")
+	assert.Equal(t, "admin", user.Login)
+
+	// Повторний запуск нічого не змінює
+	require.NoError(t, repo.SetUserRoles(context.Background(), "user-id", nil))
+	require.NoError(t, migrate(db))
+	roles, err = repo.GetUserRoles(context.Background(), "user-id")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+}
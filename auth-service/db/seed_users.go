@@ -20,6 +20,7 @@ func CreateTestUser() {
 	defer DB.Close()
 
 	repo := NewUserRepository(DB)
+	roles := NewRoleRepository(DB)
 
 	ctx := context.Background()
 
@@ -43,30 +44,37 @@ func CreateTestUser() {
 		ID:        uuid.NewString(),
 		Login:     "admin",
 		Password:  adminPassword,
-		IsAdmin:   true,
 		CreatedAt: now,
 	}
 
 	if err := repo.CreateUser(ctx, admin); err != nil {
 		log.Fatalf("failed to create admin: %v", err)
 	}
+	if err := roles.SetUserRoles(ctx, admin.ID, []string{models.RoleAdmin}); err != nil {
+		log.Fatalf("failed to assign admin role: %v", err)
+	}
 
 	user := &models.User{
 		ID:        uuid.NewString(),
 		Login:     "user",
 		Password:  userPassword,
-		IsAdmin:   false,
 		CreatedAt: now,
 	}
 
 	if err := repo.CreateUser(ctx, user); err != nil {
 		log.Fatalf("failed to create user: %v", err)
 	}
+	if err := roles.SetUserRoles(ctx, user.ID, []string{models.RoleUser}); err != nil {
+		log.Fatalf("failed to assign user role: %v", err)
+	}
 
 	fmt.Println("Successfully cleaned table and inserted admin and user!")
 }
 
 func clearUsers(ctx context.Context) error {
+	if _, err := DB.ExecContext(ctx, "DELETE FROM user_roles"); err != nil {
+		return err
+	}
 	_, err := DB.ExecContext(ctx, "DELETE FROM users")
 	return err
 }
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+// SessionRepository зберігає сесії входу. Завершення сесії відкликає і її
+// сім'ю refresh токенів, а access токени з її sid перестають проходити
+// перевірку.
+type SessionRepository struct {
+	db *DBWrapper
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *SessionRepository) CreateSession(ctx context.Context, s *models.Session) error {
+	query := `
+        INSERT INTO sessions (id, user_id, token_id, client_ip, user_agent, created_at, issued_at, expires_at, last_seen_at, revoked_at, tenant_id)
+        VALUES (:id, :user_id, :token_id, :client_ip, :user_agent, :created_at, :issued_at, :expires_at, :last_seen_at, :revoked_at, :tenant_id)
+    `
+	_, err := r.db.NamedExecContext(ctx, query, s)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+func (r *SessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var s models.Session
+	err := r.db.GetContext(ctx, &s, `SELECT * FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &s, nil
+}
+
+// SetSessionTenant перемикає сесію в організацію tenantID: токени, видані
+// сесії далі, діють у ній.
+func (r *SessionRepository) SetSessionTenant(ctx context.Context, id string, tenantID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE sessions SET tenant_id = ? WHERE id = ?`, tenantID, id)
+	if err != nil {
+		return fmt.Errorf("failed to set session tenant: %w", err)
+	}
+	return nil
+}
+
+// RenewSession записує токени, видані сесії при оновленні. Повертає false,
+// якщо сесії немає.
+func (r *SessionRepository) RenewSession(ctx context.Context, id string, tokenID string, issuedAt time.Time, expiresAt time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE sessions SET token_id = ?, issued_at = ?, expires_at = ? WHERE id = ?`,
+		tokenID, issuedAt, expiresAt, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to renew session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew session: %w", err)
+	}
+	return n > 0, nil
+}
+
+// TouchSession оновлює час останньої активності, якщо попередній запис
+// старший за staleBefore: так перевірка кожного запиту не пише в БД щоразу.
+func (r *SessionRepository) TouchSession(ctx context.Context, id string, seenAt time.Time, staleBefore time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE sessions SET last_seen_at = ?
+        WHERE id = ? AND revoked_at IS NULL AND (last_seen_at IS NULL OR last_seen_at < ?)
+    `, seenAt, id, staleBefore)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+// IsSessionActive повідомляє, чи сесія існує і не завершена.
+func (r *SessionRepository) IsSessionActive(ctx context.Context, id string) (bool, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM sessions WHERE id = ? AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ListUserSessions повертає незавершені і не прострочені сесії користувача,
+// починаючи з останньої.
+func (r *SessionRepository) ListUserSessions(ctx context.Context, userID string, now time.Time) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := r.db.SelectContext(ctx, &sessions, `
+        SELECT * FROM sessions
+        WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+        ORDER BY created_at DESC, id
+    `, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершує сесію id користувача userID. Повертає false, якщо
+// в користувача немає такої активної сесії.
+func (r *SessionRepository) RevokeSession(ctx context.Context, userID string, id string) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		now, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND user_id = ? AND revoked_at IS NULL`,
+		now, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RevokeUserSessions завершує всі сесії користувача, крім exceptID (порожній
+// — завершити всі). Повертає кількість завершених сесій.
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userID string, exceptID string) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id != ? AND revoked_at IS NULL`,
+		now, userID, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND family_id != ? AND revoked_at IS NULL`,
+		now, userID, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return n, nil
+}
+
+// RevokeTenantSessions завершує сесії в організації tenantID: усі або лише
+// користувача userID, якщо він не порожній. Повертає кількість завершених
+// сесій.
+func (r *SessionRepository) RevokeTenantSessions(ctx context.Context, tenantID string, userID string) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke tenant sessions: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ids []string
+	err = tx.SelectContext(ctx, &ids,
+		`SELECT id FROM sessions WHERE tenant_id = ? AND (? = '' OR user_id = ?) AND revoked_at IS NULL`,
+		tenantID, userID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke tenant sessions: %w", err)
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = ? WHERE id = ?`, now, id); err != nil {
+			return 0, fmt.Errorf("failed to revoke tenant sessions: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`, now, id); err != nil {
+			return 0, fmt.Errorf("failed to revoke tenant sessions: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to revoke tenant sessions: %w", err)
+	}
+	return int64(len(ids)), nil
+}
+
+func (r *SessionRepository) DeleteUserSessions(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+	return nil
+}
+
+// PruneExpired видаляє прострочені і завершені сесії. Токени видаленої сесії
+// не проходять перевірку так само, як токени завершеної.
+func (r *SessionRepository) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= ? OR revoked_at IS NOT NULL`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune sessions: %w", err)
+	}
+	return res.RowsAffected()
+}
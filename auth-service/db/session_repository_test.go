@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupSessionTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE refresh_tokens (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		family_id TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		used_at DATETIME,
+		revoked_at DATETIME
+	);
+	CREATE TABLE sessions (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		token_id TEXT NOT NULL,
+		client_ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		issued_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		last_seen_at DATETIME,
+		revoked_at DATETIME,
+		tenant_id TEXT NOT NULL DEFAULT ''
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestSessionRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupSessionTestDB(t)
+	repo := NewSessionRepository(db)
+	refreshRepo := NewRefreshTokenRepository(db)
+	now := time.Now().Truncate(time.Second)
+	expires := now.Add(time.Hour)
+
+	for i, s := range []struct{ id, userID string }{
+		{"session-1", "user-1"},
+		{"session-2", "user-1"},
+		{"session-3", "user-1"},
+		{"session-4", "user-2"},
+	} {
+		created := now.Add(time.Duration(i) * time.Second)
+		require.NoError(t, repo.CreateSession(ctx, &models.Session{
+			ID: s.id, UserID: s.userID, TokenID: "jti-" + s.id, ClientIP: "203.0.113.7", UserAgent: "curl/8.0",
+			CreatedAt: created, IssuedAt: created, ExpiresAt: expires,
+		}))
+		require.NoError(t, refreshRepo.CreateRefreshToken(ctx, &models.RefreshToken{
+			ID: "rt-" + s.id, UserID: s.userID, FamilyID: s.id, TokenHash: "hash-" + s.id, ExpiresAt: expires, CreatedAt: created,
+		}))
+	}
+
+	t.Run("ListUserSessions", func(t *testing.T) {
+		sessions, err := repo.ListUserSessions(ctx, "user-1", now)
+		require.NoError(t, err)
+		require.Len(t, sessions, 3)
+		assert.Equal(t, "session-3", sessions[0].ID)
+		assert.Equal(t, "203.0.113.7", sessions[0].ClientIP)
+		assert.Equal(t, "curl/8.0", sessions[0].UserAgent)
+		assert.Nil(t, sessions[0].LastSeenAt)
+
+		sessions, err = repo.ListUserSessions(ctx, "user-1", expires)
+		require.NoError(t, err)
+		assert.Empty(t, sessions, "expired sessions must not be listed")
+	})
+
+	t.Run("RenewSession", func(t *testing.T) {
+		renewed, err := repo.RenewSession(ctx, "session-1", "jti-renewed", now.Add(time.Minute), expires.Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, renewed)
+
+		sessions, err := repo.ListUserSessions(ctx, "user-1", now)
+		require.NoError(t, err)
+		assert.Equal(t, "jti-renewed", sessions[2].TokenID)
+		assert.True(t, sessions[2].ExpiresAt.Equal(expires.Add(time.Minute)))
+
+		renewed, err = repo.RenewSession(ctx, "missing", "jti", now, expires)
+		require.NoError(t, err)
+		assert.False(t, renewed)
+	})
+
+	t.Run("TouchSession", func(t *testing.T) {
+		seen := now.Add(time.Minute)
+		require.NoError(t, repo.TouchSession(ctx, "session-2", seen, seen.Add(-time.Minute)))
+		// Повторна перевірка в межах інтервалу не переписує час.
+		require.NoError(t, repo.TouchSession(ctx, "session-2", seen.Add(time.Second), seen.Add(-time.Minute)))
+
+		sessions, err := repo.ListUserSessions(ctx, "user-1", now)
+		require.NoError(t, err)
+		require.NotNil(t, sessions[1].LastSeenAt)
+		assert.True(t, sessions[1].LastSeenAt.Equal(seen))
+	})
+
+	t.Run("RevokeSession", func(t *testing.T) {
+		revoked, err := repo.RevokeSession(ctx, "user-2", "session-1")
+		require.NoError(t, err)
+		assert.False(t, revoked, "a user must not revoke another user's session")
+
+		revoked, err = repo.RevokeSession(ctx, "user-1", "session-1")
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		active, err := repo.IsSessionActive(ctx, "session-1")
+		require.NoError(t, err)
+		assert.False(t, active)
+		rt, err := refreshRepo.GetRefreshTokenByHash(ctx, "hash-session-1")
+		require.NoError(t, err)
+		assert.NotNil(t, rt.RevokedAt, "revoking a session must revoke its refresh tokens")
+
+		revoked, err = repo.RevokeSession(ctx, "user-1", "session-1")
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("RevokeUserSessions", func(t *testing.T) {
+		revoked, err := repo.RevokeUserSessions(ctx, "user-1", "session-3")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), revoked)
+
+		sessions, err := repo.ListUserSessions(ctx, "user-1", now)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "session-3", sessions[0].ID)
+
+		rt, err := refreshRepo.GetRefreshTokenByHash(ctx, "hash-session-2")
+		require.NoError(t, err)
+		assert.NotNil(t, rt.RevokedAt)
+		rt, err = refreshRepo.GetRefreshTokenByHash(ctx, "hash-session-3")
+		require.NoError(t, err)
+		assert.Nil(t, rt.RevokedAt)
+
+		active, err := repo.IsSessionActive(ctx, "session-4")
+		require.NoError(t, err)
+		assert.True(t, active, "other users' sessions must stay active")
+	})
+
+	t.Run("PruneExpired", func(t *testing.T) {
+		n, err := repo.PruneExpired(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		active, err := repo.IsSessionActive(ctx, "session-3")
+		require.NoError(t, err)
+		assert.True(t, active)
+	})
+
+	t.Run("DeleteUserSessions", func(t *testing.T) {
+		require.NoError(t, repo.DeleteUserSessions(ctx, "user-2"))
+
+		active, err := repo.IsSessionActive(ctx, "session-4")
+		require.NoError(t, err)
+		assert.False(t, active)
+	})
+	t.Run("TenantSessions", func(t *testing.T) {
+		require.NoError(t, repo.SetSessionTenant(ctx, "session-3", "tenant-1"))
+		session, err := repo.GetSession(ctx, "session-3")
+		require.NoError(t, err)
+		assert.Equal(t, "tenant-1", session.TenantID)
+
+		revoked, err := repo.RevokeTenantSessions(ctx, "tenant-1", "user-2")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), revoked, "only the given user's sessions must be revoked")
+
+		revoked, err = repo.RevokeTenantSessions(ctx, "tenant-1", "")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), revoked)
+
+		active, err := repo.IsSessionActive(ctx, "session-3")
+		require.NoError(t, err)
+		assert.False(t, active)
+		rt, err := refreshRepo.GetRefreshTokenByHash(ctx, "hash-session-3")
+		require.NoError(t, err)
+		assert.NotNil(t, rt.RevokedAt)
+	})
+}
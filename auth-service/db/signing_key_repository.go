@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+type SigningKeyRepository struct {
+	db *DBWrapper
+}
+
+func NewSigningKeyRepository(db *sqlx.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+func (r *SigningKeyRepository) CreateSigningKey(ctx context.Context, key *models.SigningKey) error {
+	query := `
+        INSERT INTO signing_keys (id, algorithm, private_key, status, created_at, activated_at, retires_at)
+        VALUES (:id, :algorithm, :private_key, :status, :created_at, :activated_at, :retires_at)
+    `
+	_, err := r.db.NamedExecContext(ctx, query, key)
+	if err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+	return nil
+}
+
+func (r *SigningKeyRepository) GetSigningKey(ctx context.Context, id string) (*models.SigningKey, error) {
+	var key models.SigningKey
+	err := r.db.GetContext(ctx, &key, `SELECT * FROM signing_keys WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing key: %w", err)
+	}
+	return &key, nil
+}
+
+func (r *SigningKeyRepository) ListSigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	keys := []models.SigningKey{}
+	err := r.db.SelectContext(ctx, &keys, `SELECT * FROM signing_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	return keys, nil
+}
+
+// ActivateSigningKey робить ключ id активним. Попередній активний ключ стає
+// неактивним і лишається дійсним для перевірки до retirePreviousAt.
+func (r *SigningKeyRepository) ActivateSigningKey(ctx context.Context, id string, retirePreviousAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to activate signing key: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx,
+		`UPDATE signing_keys SET status = ?, retires_at = ? WHERE status = ? AND id != ?`,
+		models.KeyStatusInactive, retirePreviousAt, models.KeyStatusActive, id)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate previous signing key: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE signing_keys SET status = ?, activated_at = ?, retires_at = NULL WHERE id = ?`,
+		models.KeyStatusActive, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to activate signing key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to activate signing key: %w", err)
+	}
+	return nil
+}
+
+// UpdatePrivateKey замінює збережений приватний ключ, наприклад його
+// зашифровану версію.
+func (r *SigningKeyRepository) UpdatePrivateKey(ctx context.Context, id string, privateKey string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE signing_keys SET private_key = ? WHERE id = ?`, privateKey, id)
+	if err != nil {
+		return fmt.Errorf("failed to update signing key: %w", err)
+	}
+	return nil
+}
+
+// RetireSigningKey призначає дату виведення ключа з обігу. Якщо дата вже
+// настала, ключ одразу отримує статус retired.
+func (r *SigningKeyRepository) RetireSigningKey(ctx context.Context, id string, at time.Time) error {
+	status := models.KeyStatusInactive
+	if !at.After(time.Now()) {
+		status = models.KeyStatusRetired
+	}
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE signing_keys SET status = ?, retires_at = ? WHERE id = ?`,
+		status, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to retire signing key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupSigningKeyTestDB(t *testing.T) *sqlx.DB {
+	db := setupTestDB(t)
+
+	schema := `
+	CREATE TABLE signing_keys (
+		id TEXT PRIMARY KEY,
+		algorithm TEXT NOT NULL,
+		private_key TEXT NOT NULL,
+		status TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		activated_at DATETIME,
+		retires_at DATETIME
+	);`
+	_, err := db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestSigningKeyRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupSigningKeyTestDB(t)
+	repo := NewSigningKeyRepository(db)
+
+	newKey := func(id string) *models.SigningKey {
+		return &models.SigningKey{
+			ID:         id,
+			Algorithm:  "ES256",
+			PrivateKey: "pem-" + id,
+			Status:     models.KeyStatusPending,
+			CreatedAt:  time.Now(),
+		}
+	}
+
+	t.Run("CreateSigningKey", func(t *testing.T) {
+		require.NoError(t, repo.CreateSigningKey(ctx, newKey("k1")))
+		require.NoError(t, repo.CreateSigningKey(ctx, newKey("k2")))
+
+		keys, err := repo.ListSigningKeys(ctx)
+		require.NoError(t, err)
+		assert.Len(t, keys, 2)
+	})
+
+	t.Run("ActivateSigningKey", func(t *testing.T) {
+		require.NoError(t, repo.ActivateSigningKey(ctx, "k1", time.Now().Add(time.Hour)))
+
+		k1, err := repo.GetSigningKey(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, models.KeyStatusActive, k1.Status)
+		assert.NotNil(t, k1.ActivatedAt)
+
+		retireAt := time.Now().Add(time.Hour)
+		require.NoError(t, repo.ActivateSigningKey(ctx, "k2", retireAt))
+
+		k1, err = repo.GetSigningKey(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, models.KeyStatusInactive, k1.Status)
+		require.NotNil(t, k1.RetiresAt)
+		assert.WithinDuration(t, retireAt, *k1.RetiresAt, time.Second)
+
+		k2, err := repo.GetSigningKey(ctx, "k2")
+		require.NoError(t, err)
+		assert.Equal(t, models.KeyStatusActive, k2.Status)
+		assert.Nil(t, k2.RetiresAt)
+	})
+
+	t.Run("UpdatePrivateKey", func(t *testing.T) {
+		require.NoError(t, repo.UpdatePrivateKey(ctx, "k2", "enc:v1:k2"))
+
+		k2, err := repo.GetSigningKey(ctx, "k2")
+		require.NoError(t, err)
+		assert.Equal(t, "enc:v1:k2", k2.PrivateKey)
+		assert.Equal(t, models.KeyStatusActive, k2.Status)
+	})
+
+	t.Run("RetireSigningKey", func(t *testing.T) {
+		require.NoError(t, repo.RetireSigningKey(ctx, "k1", time.Now().Add(-time.Second)))
+
+		k1, err := repo.GetSigningKey(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, models.KeyStatusRetired, k1.Status)
+	})
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+var ErrTenantExists = errors.New("tenant already exists")
+
+// TenantRepository зберігає організації та членство в них.
+type TenantRepository struct {
+	db *DBWrapper
+}
+
+func NewTenantRepository(db *sqlx.DB) *TenantRepository {
+	return &TenantRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+const tenantMemberColumns = `
+        m.tenant_id, t.name AS tenant_name, m.user_id, u.login, m.role, m.created_at
+        FROM tenant_members m
+        JOIN tenants t ON t.id = m.tenant_id
+        JOIN users u ON u.id = m.user_id
+`
+
+func (r *TenantRepository) CreateTenant(ctx context.Context, t *models.Tenant) error {
+	_, err := r.db.NamedExecContext(ctx, `INSERT INTO tenants (id, name, created_at) VALUES (:id, :name, :created_at)`, t)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("failed to create tenant: %w", ErrTenantExists)
+		}
+		return fmt.Errorf("failed to create tenant: %w", err)
+	}
+	return nil
+}
+
+func (r *TenantRepository) GetTenant(ctx context.Context, id string) (*models.Tenant, error) {
+	var t models.Tenant
+	err := r.db.GetContext(ctx, &t, `SELECT * FROM tenants WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *TenantRepository) ListTenants(ctx context.Context) ([]models.Tenant, error) {
+	tenants := []models.Tenant{}
+	err := r.db.SelectContext(ctx, &tenants, `SELECT * FROM tenants ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	return tenants, nil
+}
+
+// DeleteTenant видаляє організацію разом із членством у ній.
+func (r *TenantRepository) DeleteTenant(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tenant_members WHERE tenant_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tenants WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	return nil
+}
+
+// SetMember додає користувача в організацію або змінює його роль у ній.
+func (r *TenantRepository) SetMember(ctx context.Context, m *models.TenantMember) error {
+	_, err := r.db.NamedExecContext(ctx, `
+        INSERT INTO tenant_members (tenant_id, user_id, role, created_at)
+        VALUES (:tenant_id, :user_id, :role, :created_at)
+        ON CONFLICT (tenant_id, user_id) DO UPDATE SET role = excluded.role
+    `, m)
+	if err != nil {
+		return fmt.Errorf("failed to set tenant member: %w", err)
+	}
+	return nil
+}
+
+func (r *TenantRepository) GetMember(ctx context.Context, tenantID string, userID string) (*models.TenantMember, error) {
+	var m models.TenantMember
+	err := r.db.GetContext(ctx, &m, `SELECT`+tenantMemberColumns+`WHERE m.tenant_id = ? AND m.user_id = ?`, tenantID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant member: %w", err)
+	}
+	return &m, nil
+}
+
+func (r *TenantRepository) ListMembers(ctx context.Context, tenantID string) ([]models.TenantMember, error) {
+	members := []models.TenantMember{}
+	err := r.db.SelectContext(ctx, &members, `SELECT`+tenantMemberColumns+`WHERE m.tenant_id = ? ORDER BY u.login`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant members: %w", err)
+	}
+	return members, nil
+}
+
+// ListUserTenants повертає членство користувача в організаціях у порядку
+// вступу.
+func (r *TenantRepository) ListUserTenants(ctx context.Context, userID string) ([]models.TenantMember, error) {
+	members := []models.TenantMember{}
+	err := r.db.SelectContext(ctx, &members, `SELECT`+tenantMemberColumns+`WHERE m.user_id = ? ORDER BY m.created_at, t.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user tenants: %w", err)
+	}
+	return members, nil
+}
+
+// DefaultTenantID повертає організацію, в якій починається новий вхід:
+// першу, до якої вступив користувач, або порожній рядок, якщо він не
+// входить у жодну.
+func (r *TenantRepository) DefaultTenantID(ctx context.Context, userID string) (string, error) {
+	var id string
+	err := r.db.GetContext(ctx, &id, `SELECT tenant_id FROM tenant_members WHERE user_id = ? ORDER BY created_at, tenant_id LIMIT 1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get default tenant: %w", err)
+	}
+	return id, nil
+}
+
+// CountAdmins повертає кількість адміністраторів організації.
+func (r *TenantRepository) CountAdmins(ctx context.Context, tenantID string) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM tenant_members WHERE tenant_id = ? AND role = ?`, tenantID, models.TenantRoleAdmin)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tenant admins: %w", err)
+	}
+	return count, nil
+}
+
+// RemoveMember видаляє користувача з організації. Повертає false, якщо він
+// не був її учасником.
+func (r *TenantRepository) RemoveMember(ctx context.Context, tenantID string, userID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tenant_members WHERE tenant_id = ? AND user_id = ?`, tenantID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove tenant member: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove tenant member: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *TenantRepository) DeleteUserMemberships(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM tenant_members WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tenant memberships: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupTenantTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE users (
+		id TEXT PRIMARY KEY,
+		login TEXT NOT NULL UNIQUE
+	);
+	CREATE TABLE tenants (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		created_at DATETIME NOT NULL
+	);
+	CREATE TABLE tenant_members (
+		tenant_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		role TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (tenant_id, user_id)
+	);
+	INSERT INTO users (id, login) VALUES ('user-1', 'alice'), ('user-2', 'bob');`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestTenantRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupTenantTestDB(t)
+	repo := NewTenantRepository(db)
+	now := time.Now().Truncate(time.Second)
+
+	t.Run("CreateTenant", func(t *testing.T) {
+		require.NoError(t, repo.CreateTenant(ctx, &models.Tenant{ID: "tenant-1", Name: "Acme", CreatedAt: now}))
+		require.NoError(t, repo.CreateTenant(ctx, &models.Tenant{ID: "tenant-2", Name: "Globex", CreatedAt: now}))
+
+		err := repo.CreateTenant(ctx, &models.Tenant{ID: "tenant-3", Name: "Acme", CreatedAt: now})
+		require.ErrorIs(t, err, ErrTenantExists)
+
+		tenants, err := repo.ListTenants(ctx)
+		require.NoError(t, err)
+		require.Len(t, tenants, 2)
+		assert.Equal(t, "Acme", tenants[0].Name)
+	})
+
+	t.Run("SetMember", func(t *testing.T) {
+		require.NoError(t, repo.SetMember(ctx, &models.TenantMember{TenantID: "tenant-2", UserID: "user-1", Role: models.TenantRoleMember, CreatedAt: now}))
+		require.NoError(t, repo.SetMember(ctx, &models.TenantMember{TenantID: "tenant-1", UserID: "user-1", Role: models.TenantRoleAdmin, CreatedAt: now.Add(time.Second)}))
+		require.NoError(t, repo.SetMember(ctx, &models.TenantMember{TenantID: "tenant-1", UserID: "user-2", Role: models.TenantRoleMember, CreatedAt: now}))
+		require.NoError(t, repo.SetMember(ctx, &models.TenantMember{TenantID: "tenant-1", UserID: "user-2", Role: models.TenantRoleEditor, CreatedAt: now}))
+
+		member, err := repo.GetMember(ctx, "tenant-1", "user-2")
+		require.NoError(t, err)
+		assert.Equal(t, models.TenantRoleEditor, member.Role, "setting a member again must change the role")
+		assert.Equal(t, "bob", member.Login)
+		assert.Equal(t, "Acme", member.TenantName)
+
+		members, err := repo.ListMembers(ctx, "tenant-1")
+		require.NoError(t, err)
+		require.Len(t, members, 2)
+		assert.Equal(t, "alice", members[0].Login)
+
+		admins, err := repo.CountAdmins(ctx, "tenant-1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, admins)
+	})
+
+	t.Run("UserTenants", func(t *testing.T) {
+		tenants, err := repo.ListUserTenants(ctx, "user-1")
+		require.NoError(t, err)
+		require.Len(t, tenants, 2)
+		assert.Equal(t, "tenant-2", tenants[0].TenantID)
+
+		id, err := repo.DefaultTenantID(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, "tenant-2", id, "the first joined tenant must be the default")
+
+		id, err = repo.DefaultTenantID(ctx, "user-3")
+		require.NoError(t, err)
+		assert.Empty(t, id)
+	})
+
+	t.Run("RemoveMember", func(t *testing.T) {
+		removed, err := repo.RemoveMember(ctx, "tenant-2", "user-1")
+		require.NoError(t, err)
+		assert.True(t, removed)
+
+		removed, err = repo.RemoveMember(ctx, "tenant-2", "user-1")
+		require.NoError(t, err)
+		assert.False(t, removed)
+	})
+
+	t.Run("DeleteTenant", func(t *testing.T) {
+		require.NoError(t, repo.DeleteTenant(ctx, "tenant-1"))
+
+		_, err := repo.GetTenant(ctx, "tenant-1")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		_, err = repo.GetMember(ctx, "tenant-1", "user-2")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("DeleteUserMemberships", func(t *testing.T) {
+		require.NoError(t, repo.SetMember(ctx, &models.TenantMember{TenantID: "tenant-2", UserID: "user-2", Role: models.TenantRoleMember, CreatedAt: now}))
+		require.NoError(t, repo.DeleteUserMemberships(ctx, "user-2"))
+
+		tenants, err := repo.ListUserTenants(ctx, "user-2")
+		require.NoError(t, err)
+		assert.Empty(t, tenants)
+	})
+}
@@ -2,14 +2,22 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
 
 	"ksv/rest-mikroservice/auth-service/models"
 )
 
+var (
+	ErrUserExists  = errors.New("user with this login already exists")
+	ErrEmailExists = errors.New("user with this email already exists")
+)
+
 type UserRepository struct {
 	db *DBWrapper
 }
@@ -24,13 +32,20 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 	}
 }
 
+// CreateUser створює користувача. Без Status користувач створюється активним.
 func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if user.Status == "" {
+		user.Status = models.UserStatusActive
+	}
 	query := `
-        INSERT INTO users (id, login, password, is_admin, created_at, updated_at)
-        VALUES (:id, :login, :password, :is_admin, :created_at, :updated_at)
+        INSERT INTO users (id, login, email, email_verified_at, status, password, created_at, updated_at)
+        VALUES (:id, :login, :email, :email_verified_at, :status, :password, :created_at, :updated_at)
     `
 	_, err := r.db.NamedExecContext(ctx, query, user)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("failed to create user: %w", userConflict(err))
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
@@ -56,6 +71,66 @@ func (r *UserRepository) GetUserByLogin(ctx context.Context, login string) (*mod
 	return &user, nil
 }
 
+// GetUserByEmail шукає користувача за email. Порожній email не належить
+// нікому.
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	query := `SELECT * FROM users WHERE email = ? AND email != ''`
+	err := r.db.GetContext(ctx, &user, query, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	return &user, nil
+}
+
+type UserFilter struct {
+	Login  string
+	Status string
+	Limit  int
+	Offset int
+}
+
+func (f UserFilter) where() (string, []any) {
+	var conds []string
+	var args []any
+	if f.Login != "" {
+		conds = append(conds, `login LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(f.Login)+"%")
+	}
+	if f.Status != "" {
+		conds = append(conds, `status = ?`)
+		args = append(args, f.Status)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conds, ` AND `), args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *UserRepository) ListUsers(ctx context.Context, filter UserFilter) ([]models.User, error) {
+	users := []models.User{}
+	where, args := filter.where()
+	query := `SELECT * FROM users` + where + ` ORDER BY created_at, login LIMIT ? OFFSET ?`
+	args = append(args, filter.Limit, filter.Offset)
+	err := r.db.SelectContext(ctx, &users, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+func (r *UserRepository) CountUsers(ctx context.Context, filter UserFilter) (int, error) {
+	var count int
+	where, args := filter.where()
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM users`+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
 func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
 	now := time.Now()
 	user.UpdatedAt = &now
@@ -63,18 +138,47 @@ func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) erro
 	query := `
         UPDATE users SET
             login = :login,
+            email = :email,
+            email_verified_at = :email_verified_at,
+            status = :status,
             password = :password,
-            is_admin = :is_admin,
             updated_at = :updated_at
         WHERE id = :id
     `
 	_, err := r.db.NamedExecContext(ctx, query, user)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("failed to update user: %w", userConflict(err))
+		}
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 	return nil
 }
 
+// VerifyEmail позначає email користувача підтвердженим і активує обліковий
+// запис, що чекав на підтвердження. Якщо email користувача вже інший,
+// повертає false.
+func (r *UserRepository) VerifyEmail(ctx context.Context, id string, email string, now time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE users SET
+            email_verified_at = ?,
+            status = CASE WHEN status = ? THEN ? ELSE status END,
+            updated_at = ?
+        WHERE id = ? AND email = ?
+    `, now, models.UserStatusPending, models.UserStatusActive, now, id, email)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify email: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to verify email: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteUser видаляє користувача. Його ролі, сесії, токени, MFA, API ключі,
+// історія паролів і членство в організаціях видаляються в тому самому
+// запиті через ON DELETE CASCADE.
 func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
 	query := `DELETE FROM users WHERE id = ?`
 	_, err := r.db.ExecContext(ctx, query, id)
@@ -84,3 +188,20 @@ func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}
+
+// userConflict визначає, яке унікальне поле користувача вже зайняте.
+func userConflict(err error) error {
+	if strings.Contains(err.Error(), "users.email") {
+		return ErrEmailExists
+	}
+	return ErrUserExists
+}
@@ -23,11 +23,14 @@ func setupTestDB(t *testing.T) *sqlx.DB {
 	CREATE TABLE users (
 		id TEXT PRIMARY KEY,
 		login TEXT NOT NULL UNIQUE,
+		email TEXT NOT NULL DEFAULT '',
+		email_verified_at DATETIME,
+		status TEXT NOT NULL DEFAULT 'active',
 		password TEXT NOT NULL,
-		is_admin BOOLEAN NOT NULL,
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME
-	);`
+	);
+	CREATE UNIQUE INDEX idx_users_email ON users(email) WHERE email != '';`
 	_, err = db.Exec(schema)
 	require.NoError(t, err)
 
@@ -44,7 +47,6 @@ func TestUserRepository_CRUD(t *testing.T) {
 		ID:        uuid.NewString(),
 		Login:     "testuser",
 		Password:  "testpassword",
-		IsAdmin:   false,
 		CreatedAt: time.Now(),
 	}
 
@@ -53,6 +55,17 @@ func TestUserRepository_CRUD(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("CreateUserDuplicateLogin", func(t *testing.T) {
+		duplicate := &models.User{
+			ID:        uuid.NewString(),
+			Login:     user.Login,
+			Password:  "otherpassword",
+			CreatedAt: time.Now(),
+		}
+		err := repo.CreateUser(ctx, duplicate)
+		require.ErrorIs(t, err, ErrUserExists)
+	})
+
 	t.Run("GetUserByID", func(t *testing.T) {
 		fetchedUser, err := repo.GetUserByID(ctx, user.ID)
 		require.NoError(t, err)
@@ -65,6 +78,95 @@ func TestUserRepository_CRUD(t *testing.T) {
 		assert.Equal(t, user.ID, fetchedUser.ID)
 	})
 
+	t.Run("Email", func(t *testing.T) {
+		user.Email = "test@example.com"
+		require.NoError(t, repo.UpdateUser(ctx, user))
+
+		fetchedUser, err := repo.GetUserByEmail(ctx, "test@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, fetchedUser.ID)
+
+		// Порожній email не унікальний і нікому не належить
+		_, err = repo.GetUserByEmail(ctx, "")
+		require.Error(t, err)
+
+		duplicate := &models.User{
+			ID:        uuid.NewString(),
+			Login:     "otheruser",
+			Email:     user.Email,
+			Password:  "otherpassword",
+			CreatedAt: time.Now(),
+		}
+		err = repo.CreateUser(ctx, duplicate)
+		require.ErrorIs(t, err, ErrEmailExists)
+	})
+
+	t.Run("VerifyEmail", func(t *testing.T) {
+		pending := &models.User{
+			ID:        uuid.NewString(),
+			Login:     "pendinguser",
+			Email:     "pending@example.com",
+			Status:    models.UserStatusPending,
+			Password:  "testpassword",
+			CreatedAt: time.Now(),
+		}
+		require.NoError(t, repo.CreateUser(ctx, pending))
+		defer repo.DeleteUser(ctx, pending.ID)
+
+		verified, err := repo.VerifyEmail(ctx, pending.ID, "old@example.com", time.Now())
+		require.NoError(t, err)
+		assert.False(t, verified, "a token for a previous email must not verify the new one")
+
+		verified, err = repo.VerifyEmail(ctx, pending.ID, pending.Email, time.Now())
+		require.NoError(t, err)
+		assert.True(t, verified)
+
+		fetchedUser, err := repo.GetUserByID(ctx, pending.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.UserStatusActive, fetchedUser.Status)
+		assert.NotNil(t, fetchedUser.EmailVerifiedAt)
+
+		count, err := repo.CountUsers(ctx, UserFilter{Status: models.UserStatusPending})
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		other := &models.User{
+			ID:        uuid.NewString(),
+			Login:     "another_user",
+			Password:  "testpassword",
+			CreatedAt: time.Now().Add(time.Second),
+		}
+		require.NoError(t, repo.CreateUser(ctx, other))
+		defer repo.DeleteUser(ctx, other.ID)
+
+		users, err := repo.ListUsers(ctx, UserFilter{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.Equal(t, user.ID, users[0].ID)
+
+		users, err = repo.ListUsers(ctx, UserFilter{Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, other.ID, users[0].ID)
+
+		filter := UserFilter{Login: "r_u", Limit: 10}
+		users, err = repo.ListUsers(ctx, filter)
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, other.ID, users[0].ID)
+
+		count, err := repo.CountUsers(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		// "_" шукається буквально, а не як шаблон LIKE
+		count, err = repo.CountUsers(ctx, UserFilter{Login: "t_s"})
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
 	t.Run("UpdateUser", func(t *testing.T) {
 		user.Login = "updateduser"
 		user.Password = "newpassword"
@@ -84,3 +186,39 @@ func TestUserRepository_CRUD(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestUserRepository_DeleteUserCascades(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlx.Connect("sqlite3", ":memory:?_foreign_keys=on")
+	require.NoError(t, err)
+	// У кожного з'єднання своя БД у пам'яті.
+	db.SetMaxOpenConns(1)
+	db.MustExec(schema)
+	require.NoError(t, migrate(db))
+
+	repo := NewUserRepository(db)
+	now := time.Now()
+	for _, id := range []string{"user-1", "user-2"} {
+		require.NoError(t, repo.CreateUser(ctx, &models.User{ID: id, Login: id, Password: "hash", CreatedAt: now}))
+		require.NoError(t, NewRoleRepository(db).SetUserRoles(ctx, id, []string{models.RoleUser}))
+		require.NoError(t, NewPasswordHistoryRepository(db).AddPassword(ctx, id, "hash", now, 5))
+		require.NoError(t, NewAPIKeyRepository(db).CreateAPIKey(ctx, &models.APIKey{
+			ID: "key-" + id, UserID: id, Name: "key", Prefix: "ak_" + id, KeyHash: "hash", CreatedAt: now,
+			Scopes: []string{models.PermissionProductRead},
+		}))
+	}
+
+	require.NoError(t, repo.DeleteUser(ctx, "user-1"))
+
+	for _, table := range userTables {
+		var count int
+		require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM `+table+` WHERE user_id = ?`, "user-1"))
+		assert.Zero(t, count, table)
+	}
+	var scopes int
+	require.NoError(t, db.Get(&scopes, `SELECT COUNT(*) FROM api_key_scopes WHERE api_key_id = ?`, "key-user-1"))
+	assert.Zero(t, scopes, "api_key_scopes")
+	roles, err := NewRoleRepository(db).GetUserRoles(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.RoleUser}, roles, "other users must keep their data")
+}
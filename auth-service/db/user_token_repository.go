@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+// UserTokenRepository зберігає одноразові токени, які користувачі отримують
+// листом.
+type UserTokenRepository struct {
+	db *DBWrapper
+}
+
+func NewUserTokenRepository(db *sqlx.DB) *UserTokenRepository {
+	return &UserTokenRepository{
+		db: &DBWrapper{db},
+	}
+}
+
+// CreateUserToken зберігає токен і видаляє попередні токени користувача з тим
+// самим призначенням: дійсним лишається лише останній надісланий лист.
+func (r *UserTokenRepository) CreateUserToken(ctx context.Context, t *models.UserToken) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_tokens WHERE user_id = ? AND purpose = ?`, t.UserID, t.Purpose); err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+	query := `
+        INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at, created_at)
+        VALUES (:token_hash, :user_id, :purpose, :expires_at, :created_at)
+    `
+	if _, err := tx.NamedExecContext(ctx, query, t); err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+	return nil
+}
+
+// GetUserToken повертає дійсний токен з призначенням purpose, не
+// використовуючи його.
+func (r *UserTokenRepository) GetUserToken(ctx context.Context, tokenHash string, purpose string, now time.Time) (*models.UserToken, error) {
+	var t models.UserToken
+	err := r.db.GetContext(ctx, &t,
+		`SELECT * FROM user_tokens WHERE token_hash = ? AND purpose = ? AND expires_at > ?`,
+		tokenHash, purpose, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user token: %w", err)
+	}
+	return &t, nil
+}
+
+// ConsumeUserToken видаляє токен і повертає його. Токен можна використати
+// лише раз; прострочений токен не знаходиться.
+func (r *UserTokenRepository) ConsumeUserToken(ctx context.Context, tokenHash string, purpose string, now time.Time) (*models.UserToken, error) {
+	var t models.UserToken
+	err := r.db.GetContext(ctx, &t,
+		`DELETE FROM user_tokens WHERE token_hash = ? AND purpose = ? AND expires_at > ? RETURNING *`,
+		tokenHash, purpose, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume user token: %w", err)
+	}
+	return &t, nil
+}
+
+// HasRecentToken повідомляє, чи видано користувачу токен з призначенням
+// purpose після since. Так обмежується частота листів.
+func (r *UserTokenRepository) HasRecentToken(ctx context.Context, userID string, purpose string, since time.Time) (bool, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM user_tokens WHERE user_id = ? AND purpose = ? AND created_at > ?`,
+		userID, purpose, since)
+	if err != nil {
+		return false, fmt.Errorf("failed to check user tokens: %w", err)
+	}
+	return count > 0, nil
+}
+
+// DeleteUserTokens видаляє всі токени користувача.
+func (r *UserTokenRepository) DeleteUserTokens(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_tokens WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user tokens: %w", err)
+	}
+	return nil
+}
+
+// PruneExpired видаляє прострочені токени.
+func (r *UserTokenRepository) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM user_tokens WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune user tokens: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func setupUserTokenTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE user_tokens (
+		token_hash TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		purpose TEXT NOT NULL,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestUserTokenRepository(t *testing.T) {
+	ctx := context.Background()
+	db := setupUserTokenTestDB(t)
+	repo := NewUserTokenRepository(db)
+	now := time.Now().Truncate(time.Second)
+
+	newToken := func(hash string, userID string, expiresAt time.Time) *models.UserToken {
+		return &models.UserToken{
+			TokenHash: hash,
+			UserID:    userID,
+			Purpose:   models.UserTokenPasswordReset,
+			ExpiresAt: expiresAt,
+			CreatedAt: now,
+		}
+	}
+
+	t.Run("ConsumeUserToken", func(t *testing.T) {
+		require.NoError(t, repo.CreateUserToken(ctx, newToken("hash-1", "user-1", now.Add(time.Minute))))
+
+		_, err := repo.GetUserToken(ctx, "hash-1", "other_purpose", now)
+		require.ErrorIs(t, err, sql.ErrNoRows, "a token must match its purpose")
+
+		token, err := repo.GetUserToken(ctx, "hash-1", models.UserTokenPasswordReset, now)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", token.UserID)
+
+		token, err = repo.ConsumeUserToken(ctx, "hash-1", models.UserTokenPasswordReset, now)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", token.UserID)
+
+		_, err = repo.ConsumeUserToken(ctx, "hash-1", models.UserTokenPasswordReset, now)
+		require.ErrorIs(t, err, sql.ErrNoRows, "a token must be usable only once")
+	})
+
+	t.Run("NewTokenReplacesOld", func(t *testing.T) {
+		require.NoError(t, repo.CreateUserToken(ctx, newToken("hash-2", "user-2", now.Add(time.Minute))))
+		require.NoError(t, repo.CreateUserToken(ctx, newToken("hash-3", "user-2", now.Add(time.Minute))))
+
+		_, err := repo.GetUserToken(ctx, "hash-2", models.UserTokenPasswordReset, now)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		_, err = repo.GetUserToken(ctx, "hash-3", models.UserTokenPasswordReset, now)
+		require.NoError(t, err)
+	})
+
+	t.Run("HasRecentToken", func(t *testing.T) {
+		recent, err := repo.HasRecentToken(ctx, "user-2", models.UserTokenPasswordReset, now.Add(-time.Minute))
+		require.NoError(t, err)
+		assert.True(t, recent)
+
+		recent, err = repo.HasRecentToken(ctx, "user-2", models.UserTokenPasswordReset, now)
+		require.NoError(t, err)
+		assert.False(t, recent)
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		require.NoError(t, repo.CreateUserToken(ctx, newToken("hash-4", "user-4", now.Add(-time.Second))))
+
+		_, err := repo.ConsumeUserToken(ctx, "hash-4", models.UserTokenPasswordReset, now)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("PruneExpired", func(t *testing.T) {
+		n, err := repo.PruneExpired(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("DeleteUserTokens", func(t *testing.T) {
+		require.NoError(t, repo.DeleteUserTokens(ctx, "user-2"))
+
+		_, err := repo.GetUserToken(ctx, "hash-3", models.UserTokenPasswordReset, now)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
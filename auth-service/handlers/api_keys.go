@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+const maxAPIKeyNameLength = 64
+
+type APIKeyResponse struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix" example:"ak_3f9c0a7b12de"`
+	// Scopes — дозволи ключа; порожній список означає всі дозволи користувача.
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// TenantID — організація, в якій діє ключ: та, в якій користувач був при
+	// його створенні.
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// APIKeyCreatedResponse містить сам ключ. Ключ зберігається лише у вигляді
+// хешу, тож показується один раз.
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" example:"nightly-backup"`
+	Scopes    []string   `json:"scopes" example:"product:read"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func newAPIKeyResponse(key *models.APIKey) APIKeyResponse {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		TenantID:   key.TenantID,
+	}
+}
+
+// verifyAPIKey знаходить ключ за префіксом, перевіряє його хеш і строк дії та
+// повертає claims власника. Ролі й дозволи читаються з БД при кожному запиті,
+// тож зміни ролей діють одразу; scopes ключа лише обмежують дозволи. Ключ
+// організації перестає діяти, коли власник виходить з неї.
+func verifyAPIKey(ctx context.Context, raw string) (*token.UserClaims, error) {
+	prefix, ok := token.APIKeyPrefix(raw)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+
+	repo := db.NewAPIKeyRepository(db.DB)
+	key, err := repo.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errAuthFailure, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(token.HashAPIKey(raw)), []byte(key.KeyHash)) != 1 {
+		return nil, errInvalidAPIKey
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, errInvalidAPIKey
+	}
+
+	user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, key.UserID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.Status != models.UserStatusActive) {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errAuthFailure, err)
+	}
+	access, err := loadTokenAccess(ctx, user.ID, key.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errAuthFailure, err)
+	}
+	if access.TenantID != key.TenantID {
+		return nil, errInvalidAPIKey
+	}
+	roles, permissions := access.Roles, access.Permissions
+
+	// Ролі передаються лише ключам без обмежень: інакше політика gateway могла
+	// б надати за роллю дозвіл, якого немає серед scopes ключа.
+	if len(key.Scopes) > 0 {
+		roles = nil
+		permissions = slices.DeleteFunc(permissions, func(p string) bool {
+			return !slices.Contains(key.Scopes, p)
+		})
+	}
+
+	if err := repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+		log.Println("API key error:", err)
+	}
+	claims := token.NewAPIKeyClaims(key.ID, user.ID, user.Login, roles, permissions, key.CreatedAt, key.ExpiresAt)
+	claims.TenantID, claims.TenantRole = access.TenantID, access.TenantRole
+	return claims, nil
+}
+
+// NewListAPIKeysHandler godoc
+// @Summary Список API ключів
+// @Description Повертає API ключі поточного користувача без самих ключів
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} APIKeyResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT користувача"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/api-keys [get]
+func NewListAPIKeysHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		keys, err := db.NewAPIKeyRepository(db.DB).ListUserAPIKeys(r.Context(), claims.ID)
+		if err != nil {
+			log.Println("List API keys error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		resp := make([]APIKeyResponse, 0, len(keys))
+		for i := range keys {
+			resp = append(resp, newAPIKeyResponse(&keys[i]))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewCreateAPIKeyHandler godoc
+// @Summary Створення API ключа
+// @Description Створює іменований API ключ для скриптів. Ключ передається в заголовку Authorization: ApiKey <ключ> або X-API-Key і повертається лише у цій відповіді. Scopes мають бути серед дозволів користувача; без scopes ключ отримує всі дозволи користувача. Без expires_at ключ безстроковий. Ключ діє в поточній організації користувача.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param request body CreateAPIKeyRequest true "Назва, scopes і строк дії ключа"
+// @Success 201 {object} APIKeyCreatedResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або недоступний scope"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/api-keys [post]
+func NewCreateAPIKeyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		var req CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if req.Name == "" || len(req.Name) > maxAPIKeyNameLength {
+			writeError(w, http.StatusBadRequest, "Name must be 1-64 characters")
+			return
+		}
+		now := time.Now()
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+			writeError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+
+		ctx := r.Context()
+		access, err := loadTokenAccess(ctx, claims.ID, claims.TenantID)
+		if err != nil {
+			log.Println("Create API key error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		for _, scope := range req.Scopes {
+			if !slices.Contains(access.Permissions, scope) {
+				writeError(w, http.StatusBadRequest, "Scope not granted to user: "+scope)
+				return
+			}
+		}
+
+		raw, prefix, hash, err := token.NewAPIKey()
+		if err != nil {
+			log.Println("Create API key error:", err)
+			writeError(w, http.StatusInternalServerError, "Failed to generate API key")
+			return
+		}
+		key := &models.APIKey{
+			ID:        uuid.NewString(),
+			UserID:    claims.ID,
+			Name:      req.Name,
+			Prefix:    prefix,
+			KeyHash:   hash,
+			CreatedAt: now,
+			ExpiresAt: req.ExpiresAt,
+			Scopes:    req.Scopes,
+			TenantID:  access.TenantID,
+		}
+		if err := db.NewAPIKeyRepository(db.DB).CreateAPIKey(ctx, key); err != nil {
+			log.Println("Create API key error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		log.Printf("API key %s created for user %s", key.Prefix, claims.ID)
+		writeJSON(w, http.StatusCreated, APIKeyCreatedResponse{
+			APIKeyResponse: newAPIKeyResponse(key),
+			Key:            raw,
+		})
+	}
+}
+
+// NewDeleteAPIKeyHandler godoc
+// @Summary Відкликання API ключа
+// @Description Видаляє API ключ поточного користувача; ключ одразу перестає діяти
+// @Tags api-keys
+// @Produce json
+// @Param id path string true "ID ключа"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 404 {object} models.ErrorResponse "Ключ не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/api-keys/{id} [delete]
+func NewDeleteAPIKeyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		deleted, err := db.NewAPIKeyRepository(db.DB).DeleteAPIKey(r.Context(), claims.ID, r.PathValue("id"))
+		if err != nil {
+			log.Println("Delete API key error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !deleted {
+			writeError(w, http.StatusNotFound, "API key not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "API key revoked"})
+	}
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+
+	// maxAuditLoginLength обмежує логін невдалого входу: його вводить хто завгодно.
+	maxAuditLoginLength = 64
+)
+
+type AuditEventResponse struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type" example:"login.success"`
+	// ActorID і ActorLogin — хто виконав дію; для токена сервісу ActorLogin
+	// має вигляд client:<id>, для невдалого входу — введений логін.
+	ActorID    string `json:"actor_id,omitempty"`
+	ActorLogin string `json:"actor_login,omitempty"`
+	// TargetID і TargetLogin — користувач, над яким виконано дію адміністратора.
+	TargetID    string    `json:"target_id,omitempty"`
+	TargetLogin string    `json:"target_login,omitempty"`
+	ClientIP    string    `json:"client_ip,omitempty" example:"203.0.113.7"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	Details     string    `json:"details,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AuditListResponse struct {
+	Events []AuditEventResponse `json:"events"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+var auditCSVHeader = []string{"id", "created_at", "type", "actor_id", "actor_login", "target_id", "target_login", "client_ip", "user_agent", "details"}
+
+func newAuditEventResponse(e *models.AuditEvent) AuditEventResponse {
+	return AuditEventResponse{
+		ID:          e.ID,
+		Type:        e.Type,
+		ActorID:     e.ActorID,
+		ActorLogin:  e.ActorLogin,
+		TargetID:    e.TargetID,
+		TargetLogin: e.TargetLogin,
+		ClientIP:    e.ClientIP,
+		UserAgent:   e.UserAgent,
+		Details:     e.Details,
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+// auditEvent створює подію eventType, автором якої є власник claims.
+func auditEvent(eventType string, claims *token.UserClaims) models.AuditEvent {
+	event := models.AuditEvent{Type: eventType, ActorID: claims.ID, ActorLogin: claims.Login}
+	if claims.IsClient() {
+		event.ActorLogin = "client:" + claims.ClientID
+	}
+	// Токеном імперсонації діє співробітник підтримки: він автор дії, а
+	// користувач токена — її ціль.
+	if claims.IsImpersonated() {
+		event.ActorID, event.ActorLogin = claims.Actor.ID, claims.Actor.Subject
+		event.TargetID, event.TargetLogin = claims.ID, claims.Login
+	}
+	return event
+}
+
+// userAuditEvent створює подію eventType, автором якої є user.
+func userAuditEvent(eventType string, user *models.User) models.AuditEvent {
+	return models.AuditEvent{Type: eventType, ActorID: user.ID, ActorLogin: user.Login}
+}
+
+// targetAuditEvent створює подію eventType, у якій власник claims виконав
+// дію над target.
+func targetAuditEvent(eventType string, claims *token.UserClaims, target *models.User) models.AuditEvent {
+	event := auditEvent(eventType, claims)
+	event.TargetID = target.ID
+	event.TargetLogin = target.Login
+	return event
+}
+
+// loginFailureEvent описує невдалий вхід з логіном login; user — власник
+// логіна, якщо він існує.
+func loginFailureEvent(login string, user *models.User, details string) models.AuditEvent {
+	event := models.AuditEvent{Type: models.AuditLoginFailure, ActorLogin: truncateString(login, maxAuditLoginLength), Details: details}
+	if user != nil {
+		event.ActorID = user.ID
+	}
+	return event
+}
+
+// recordAudit дописує подію в журнал аудиту з адресою і User-Agent запиту.
+// Помилка запису лише логується: вона не скасовує дію, яку описує подія.
+func recordAudit(r *http.Request, proxies utils.TrustedProxies, event models.AuditEvent) {
+	origin := newRequestOrigin(r, proxies)
+	event.ClientIP = origin.IP
+	event.UserAgent = origin.UserAgent
+	event.CreatedAt = time.Now()
+	// Дія над іншим користувачем у режимі імперсонації не повинна загубити,
+	// від чийого імені її виконано.
+	if claims := claimsFromContext(r.Context()); claims != nil && claims.IsImpersonated() {
+		if event.Details != "" {
+			event.Details += "; "
+		}
+		event.Details += "impersonating " + claims.Login
+	}
+	if err := db.NewAuditRepository(db.DB).AppendEvent(r.Context(), &event); err != nil {
+		log.Println("Audit log error:", err)
+	}
+}
+
+// auditFilter читає фільтри журналу з query запиту.
+func auditFilter(r *http.Request) (db.AuditFilter, error) {
+	q := r.URL.Query()
+	filter := db.AuditFilter{
+		Type:     q.Get("type"),
+		Actor:    q.Get("actor"),
+		Target:   q.Get("target"),
+		ClientIP: q.Get("ip"),
+	}
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return db.AuditFilter{}, errors.New("Invalid " + name + ": expected RFC 3339 time")
+		}
+		*dst = &t
+	}
+	return filter, nil
+}
+
+// NewListAuditHandler godoc
+// @Summary Журнал аудиту
+// @Description Повертає сторінку подій журналу аудиту, починаючи з найновішої: входи і невдалі спроби, оновлення токенів, виходи, зміни паролів і ролей, дії адміністраторів над обліковими записами: вимкнення, розблокування, скидання MFA, відкликання токенів, видалення (потрібен дозвіл audit:read)
+// @Tags audit
+// @Produce json
+// @Param type query string false "Тип події, наприклад login.failure"
+// @Param actor query string false "ID або логін автора дії"
+// @Param target query string false "ID або логін користувача, над яким виконано дію"
+// @Param ip query string false "IP адреса клієнта"
+// @Param from query string false "Початок періоду (RFC 3339, включно)"
+// @Param to query string false "Кінець періоду (RFC 3339, не включно)"
+// @Param limit query int false "Кількість записів від 1 до 500 (за замовчуванням 50)"
+// @Param offset query int false "Зсув від початку списку"
+// @Success 200 {object} AuditListResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/audit [get]
+func NewListAuditHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := auditFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit, err := queryLimit(r, defaultAuditLimit, maxAuditLimit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.Limit = limit
+		filter.Offset = offset
+
+		repo := db.NewAuditRepository(db.DB)
+		events, err := repo.ListEvents(r.Context(), filter)
+		if err != nil {
+			log.Println("List audit events error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		total, err := repo.CountEvents(r.Context(), filter)
+		if err != nil {
+			log.Println("Count audit events error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		resp := AuditListResponse{
+			Events: make([]AuditEventResponse, 0, len(events)),
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		}
+		for i := range events {
+			resp.Events = append(resp.Events, newAuditEventResponse(&events[i]))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewExportAuditHandler godoc
+// @Summary Експорт журналу аудиту
+// @Description Вивантажує всі події журналу аудиту, що відповідають фільтрам, файлом CSV або JSON, починаючи з найновішої (потрібен дозвіл audit:read). Фільтри ті самі, що в GET /api/audit.
+// @Tags audit
+// @Produce text/csv
+// @Produce json
+// @Param format query string false "csv (за замовчуванням) або json"
+// @Param type query string false "Тип події, наприклад login.failure"
+// @Param actor query string false "ID або логін автора дії"
+// @Param target query string false "ID або логін користувача, над яким виконано дію"
+// @Param ip query string false "IP адреса клієнта"
+// @Param from query string false "Початок періоду (RFC 3339, включно)"
+// @Param to query string false "Кінець періоду (RFC 3339, не включно)"
+// @Success 200 {array} AuditEventResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/audit/export [get]
+func NewExportAuditHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := auditFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "json" {
+			writeError(w, http.StatusBadRequest, "Invalid format: expected csv or json")
+			return
+		}
+
+		filename := "audit-" + time.Now().UTC().Format("20060102-150405") + "." + format
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+
+		// Після початку відповіді статус уже не змінити, тож помилка
+		// посередині вивантаження лише логується і обриває файл.
+		repo := db.NewAuditRepository(db.DB)
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			cw := csv.NewWriter(w)
+			cw.Write(auditCSVHeader)
+			err = repo.ExportEvents(r.Context(), filter, func(e *models.AuditEvent) error {
+				return cw.Write([]string{
+					strconv.FormatInt(e.ID, 10),
+					e.CreatedAt.UTC().Format(time.RFC3339),
+					e.Type,
+					e.ActorID,
+					utils.CSVCell(e.ActorLogin),
+					e.TargetID,
+					e.TargetLogin,
+					e.ClientIP,
+					utils.CSVCell(e.UserAgent),
+					utils.CSVCell(e.Details),
+				})
+			})
+			cw.Flush()
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			sep := "["
+			err = repo.ExportEvents(r.Context(), filter, func(e *models.AuditEvent) error {
+				if _, err := w.Write([]byte(sep)); err != nil {
+					return err
+				}
+				sep = ","
+				return enc.Encode(newAuditEventResponse(e))
+			})
+			if sep == "[" {
+				w.Write([]byte(sep))
+			}
+			w.Write([]byte("]\n"))
+		}
+		if err != nil {
+			log.Println("Export audit events error:", err)
+		}
+	}
+}
@@ -2,19 +2,35 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/lockout"
+	"ksv/rest-mikroservice/auth-service/mail"
+	"ksv/rest-mikroservice/auth-service/mfa"
 	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/passwords"
 	"ksv/rest-mikroservice/auth-service/token"
 	"ksv/rest-mikroservice/auth-service/utils"
 
-	"github.com/joho/godotenv"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAccessTokenDuration  = 15 * time.Minute
+	defaultRefreshTokenDuration = 30 * 24 * time.Hour
+
+	maxUserAgentLength = 512
 )
 
 type AuthRequest struct {
@@ -22,69 +38,132 @@ type AuthRequest struct {
 	Password string `json:"password"`
 }
 
+type RegisterRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	// Email потрібен для підтвердження облікового запису і скидання пароля;
+	// необов'язковий, лише якщо EMAIL_VERIFICATION_REQUIRED=false.
+	Email string `json:"email,omitempty" example:"user@example.com"`
+}
+
 type UserResponse struct {
-	ID      string `json:"id"`
-	Login   string `json:"login"`
-	IsAdmin bool   `json:"is_admin"`
+	ID    string `json:"id"`
+	Login string `json:"login"`
+	Email string `json:"email,omitempty"`
+	// Status — pending, поки email не підтверджено, active або disabled.
+	Status string   `json:"status" example:"active"`
+	Roles  []string `json:"roles"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 type AuthResponse struct {
-	Token     string       `json:"token"`
-	ExpiresAt time.Time    `json:"expires_at"`
-	User      UserResponse `json:"user"`
+	Token            string       `json:"token"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token"`
+	RefreshExpiresAt time.Time    `json:"refresh_expires_at"`
+	User             UserResponse `json:"user"`
+	// RecoveryCodes повертаються один раз, коли вхід завершує налаштування MFA.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+
+	// TenantID — організація, в якій діє токен; порожня, якщо користувач не
+	// входить у жодну.
+	TenantID   string `json:"tenant_id,omitempty"`
+	TenantRole string `json:"tenant_role,omitempty" example:"editor"`
+}
+
+// ValidateResponse — claims перевіреного токена. Імена полів збігаються з
+// claims JWT, тож відповідь можна декодувати прямо в token.UserClaims.
+type ValidateResponse struct {
+	ID          string   `json:"id"`
+	Login       string   `json:"login"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	ClientID    string   `json:"client_id,omitempty"`
+	APIKeyID    string   `json:"api_key_id,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
+	TokenID     string   `json:"jti"`
+	Subject     string   `json:"sub"`
+	IssuedAt    int64    `json:"iat"`
+	ExpiresAt   int64    `json:"exp"`
+
+	// Actor — хто діє від імені користувача, якщо це токен імперсонації.
+	Actor *token.Actor `json:"act,omitempty"`
+	// TenantID — організація, в якій діє токен.
+	TenantID   string `json:"tenant_id,omitempty"`
+	TenantRole string `json:"tenant_role,omitempty" example:"editor"`
 }
 
 // NewValidateTokenHandler godoc
 // @Summary Валідація токена
-// @Description Перевіряє дійсність JWT токена
+// @Description Перевіряє дійсність JWT токена або API ключа (Authorization: ApiKey чи X-API-Key) і повертає claims. Для безстрокового API ключа exp дорівнює 0. Токен завершеної сесії недійсний; перевірка оновлює час останньої активності сесії.
 // @Tags auth
-// @Produce plain
-// @Success 200 {string} string "OK"
+// @Produce json
+// @Success 200 {object} ValidateResponse
 // @Failure 401 {string} string "Invalid token"
+// @Failure 500 {string} string "Помилка сервера"
 // @Security BearerAuth
 // @Router /auth/validate [get]
-func NewValidateTokenHandler(secretKey string) http.HandlerFunc {
+func NewValidateTokenHandler(jwtMaker *token.JWTMaker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header is required", http.StatusUnauthorized)
+		claims, err := authenticate(r, jwtMaker)
+		if errors.Is(err, errAuthFailure) {
+			log.Println("Validate token error:", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
 			return
 		}
-
-		headerParts := strings.Split(authHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization format", http.StatusUnauthorized)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		tokenStr := headerParts[1]
+		touchSession(r.Context(), claims)
 
-		jwtMaker := token.NewJWTMaker(secretKey)
-
-		_, err := jwtMaker.VerifyToken(tokenStr)
-		if err != nil {
-			http.Error(w, "Invalid token: "+err.Error(), http.StatusUnauthorized)
-			return
+		resp := ValidateResponse{
+			ID:          claims.ID,
+			Login:       claims.Login,
+			Roles:       claims.Roles,
+			Permissions: claims.Permissions,
+			ClientID:    claims.ClientID,
+			APIKeyID:    claims.APIKeyID,
+			SessionID:   claims.SessionID,
+			TokenID:     claims.RegisteredClaims.ID,
+			Subject:     claims.Subject,
+			IssuedAt:    claims.IssuedAt.Unix(),
+			Actor:       claims.Actor,
+			TenantID:    claims.TenantID,
+			TenantRole:  claims.TenantRole,
 		}
-
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
+		if claims.ExpiresAt != nil {
+			resp.ExpiresAt = claims.ExpiresAt.Unix()
+		}
+		writeJSON(w, http.StatusOK, resp)
 	}
 }
 
 // NewAuthHandler godoc
 // @Summary Авторизація користувача
-// @Description Приймає логін і пароль, перевіряє користувача та повертає JWT токен
+// @Description Приймає логін і пароль, перевіряє користувача та повертає JWT токен і refresh токен. Якщо потрібен другий фактор, повертає 202 з токеном для POST /api/auth/mfa/verify. Після невдалих спроб вхід сповільнюється, а після кількох поспіль логін тимчасово блокується.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body AuthRequest true "Дані користувача"
 // @Success 200 {object} AuthResponse
+// @Success 202 {object} MFAChallengeResponse "Потрібен код другого фактора"
 // @Failure 400 {object} models.ErrorResponse "Некоректний запит"
 // @Failure 401 {object} models.ErrorResponse "Невірні дані"
+// @Failure 403 {object} models.ErrorResponse "Обліковий запис не активний: code account_pending (email не підтверджено) або account_disabled"
+// @Failure 423 {object} models.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} models.ErrorResponse "Забагато спроб (див. Retry-After)"
 // @Failure 500 {object} models.ErrorResponse "Помилка сервера"
 // @Router /api/auth [post]
-func NewAuthHandler(secretKey string) http.HandlerFunc {
+func NewAuthHandler(jwtMaker *token.JWTMaker, mfaCfg mfa.Config, guard *lockout.Guard, proxies utils.TrustedProxies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req AuthRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -93,53 +172,526 @@ func NewAuthHandler(secretKey string) http.HandlerFunc {
 			return
 		}
 
+		ctx := r.Context()
+		ip := proxies.ClientIP(r)
+		now := time.Now()
+
+		block, err := guard.Reserve(ctx, req.Login, ip, now)
+		if err != nil {
+			log.Println("Login attempts check error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if block != nil {
+			writeBlocked(w, block)
+			return
+		}
+
 		repo := db.NewUserRepository(db.DB)
-		user, err := repo.GetUserByLogin(context.Background(), req.Login)
+		user, err := repo.GetUserByLogin(ctx, req.Login)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Println("Get user error:", err)
+			if err := guard.Release(ctx, req.Login, ip); err != nil {
+				log.Println("Release login attempt error:", err)
+			}
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		if user == nil {
+			// Перевірка з фіктивним хешем вирівнює час відповіді, щоб не
+			// видавати, чи існує логін.
+			utils.CheckPassword(req.Password, dummyPasswordHash())
+		}
+		// Невдачу вже враховано в Reserve.
+		if user == nil || utils.CheckPassword(req.Password, user.Password) != nil {
+			log.Printf("Failed login for %q from %s", req.Login, ip)
+			recordAudit(r, proxies, loginFailureEvent(req.Login, user, "invalid credentials"))
+			writeError(w, http.StatusUnauthorized, "Invalid credentials")
+			return
+		}
+
+		if err := guard.Success(ctx, req.Login, ip); err != nil {
+			log.Println("Reset login attempts error:", err)
+		}
+		if utils.NeedsRehash(user.Password) {
+			rehashPassword(ctx, repo, user, req.Password)
+		}
+		// Стан перевіряється лише після пароля, щоб не видавати його
+		// стороннім.
+		if code, message := userStatusError(user); code != "" {
+			recordAudit(r, proxies, loginFailureEvent(req.Login, user, "account "+user.Status))
+			writeErrorCode(w, http.StatusForbidden, code, message)
+			return
+		}
+
+		challenge, err := startMFAChallenge(ctx, mfaCfg, user)
+		if err != nil {
+			log.Println("MFA challenge error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if challenge != nil {
+			writeJSON(w, http.StatusAccepted, challenge)
+			return
+		}
+
+		resp, err := issueTokens(ctx, jwtMaker, user, "", newRequestOrigin(r, proxies))
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Database error"})
+			log.Println("Issue tokens error:", err)
+			writeError(w, http.StatusInternalServerError, "Failed to create token")
 			return
 		}
+		recordAudit(r, proxies, userAuditEvent(models.AuditLoginSuccess, user))
 
-		if err := utils.CheckPassword(req.Password, user.Password); err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Invalid credentials"})
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// rehashPassword перераховує хеш пароля поточним алгоритмом і параметрами.
+// Помилка не заважає входу: хеш оновиться при наступному.
+func rehashPassword(ctx context.Context, repo *db.UserRepository, user *models.User, password string) {
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		log.Println("Rehash password error:", err)
+		return
+	}
+	user.Password = hashed
+	if err := repo.UpdateUser(ctx, user); err != nil {
+		log.Println("Rehash password error:", err)
+		return
+	}
+	log.Printf("Upgraded password hash for user %s", user.ID)
+}
+
+// dummyPasswordHash — хеш, з яким порівнюється пароль неіснуючого користувача.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := utils.HashPassword(uuid.NewString())
+	if err != nil {
+		log.Println("Dummy password hash error:", err)
+	}
+	return hash
+})
+
+func writeBlocked(w http.ResponseWriter, block *lockout.Block) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(block)))
+	if block.Locked {
+		writeError(w, http.StatusLocked, "Account is temporarily locked")
+		return
+	}
+	writeError(w, http.StatusTooManyRequests, "Too many login attempts")
+}
+
+// retryAfterSeconds округлює час до кінця блокування вгору, щонайменше до секунди.
+func retryAfterSeconds(block *lockout.Block) int {
+	return max(int(math.Ceil(block.RetryAfter.Seconds())), 1)
+}
+
+// NewRefreshHandler godoc
+// @Summary Оновлення токенів
+// @Description Обмінює refresh токен на нову пару access/refresh токенів. Повторне використання вже обміняного refresh токена відкликає всю його сім'ю.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh токен"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Недійсний refresh токен"
+// @Failure 403 {object} models.ErrorResponse "Обліковий запис не активний"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Router /api/auth/refresh [post]
+func NewRefreshHandler(jwtMaker *token.JWTMaker, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			writeError(w, http.StatusBadRequest, "Invalid request")
 			return
 		}
 
-		err = godotenv.Load(".env")
+		ctx := r.Context()
+		refreshRepo := db.NewRefreshTokenRepository(db.DB)
+
+		rt, err := refreshRepo.GetRefreshTokenByHash(ctx, token.HashRefreshToken(req.RefreshToken))
 		if err != nil {
-			log.Println("Warning: .env file not found, relying on system environment variables")
+			if errors.Is(err, sql.ErrNoRows) {
+				writeError(w, http.StatusUnauthorized, "Invalid refresh token")
+				return
+			}
+			log.Println("Get refresh token error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		if rt.RevokedAt != nil {
+			writeError(w, http.StatusUnauthorized, "Refresh token has been revoked")
+			return
+		}
+		if rt.UsedAt != nil {
+			revokeReusedFamily(ctx, rt)
+			writeError(w, http.StatusUnauthorized, "Refresh token has already been used")
+			return
 		}
-		tokenDurationStr := os.Getenv("JWT_EXPIRET_TIME")
-		if tokenDurationStr == "" {
-			log.Println("Warning: .env ket – JWT_EXPIRET_TIME not found")
+		if rt.ExpiresAt.Before(time.Now()) {
+			writeError(w, http.StatusUnauthorized, "Refresh token has expired")
+			return
 		}
-		tokenDuration, err := time.ParseDuration(tokenDurationStr)
+
+		marked, err := refreshRepo.MarkRefreshTokenUsed(ctx, rt.ID)
 		if err != nil {
-			log.Println("Time durattion set error")
+			log.Println("Mark refresh token error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !marked {
+			// Токен встиг використати паралельний запит.
+			revokeReusedFamily(ctx, rt)
+			writeError(w, http.StatusUnauthorized, "Refresh token has already been used")
+			return
 		}
 
-		jwtMaker := token.NewJWTMaker(secretKey)
+		userRepo := db.NewUserRepository(db.DB)
+		user, err := userRepo.GetUserByID(ctx, rt.UserID)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+		if !checkUserStatus(w, user) {
+			return
+		}
 
-		tokenString, claims, err := jwtMaker.CreateToken(user.ID, user.Login, user.IsAdmin, tokenDuration)
+		resp, err := issueTokens(ctx, jwtMaker, user, rt.FamilyID, newRequestOrigin(r, proxies))
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Failed to create token"})
+			log.Println("Issue tokens error:", err)
+			writeError(w, http.StatusInternalServerError, "Failed to create token")
 			return
 		}
+		event := userAuditEvent(models.AuditTokenRefresh, user)
+		event.Details = "session " + rt.FamilyID
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
 
-		userResponse := UserResponse{
-			ID:      user.ID,
-			Login:   user.Login,
-			IsAdmin: user.IsAdmin,
+// NewLogoutHandler godoc
+// @Summary Вихід із системи
+// @Description Відкликає поточний access токен і завершує його сесію разом із сім'єю refresh токенів. Якщо передано refresh токен, завершується і його сесія.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body LogoutRequest false "Refresh токен (необов'язково)"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/logout [post]
+func NewLogoutHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+		if claims.IsAPIKey() {
+			writeError(w, http.StatusBadRequest, "API keys are revoked with DELETE /api/auth/api-keys/{id}")
+			return
+		}
+
+		var req LogoutRequest
+		if err := decodeOptionalJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		ctx := r.Context()
+		revocations := db.NewRevocationRepository(db.DB)
+		if err := revocations.RevokeToken(ctx, claims.RegisteredClaims.ID, claims.RevocationSubject(), claims.ExpiresAt.Time); err != nil {
+			log.Println("Logout error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		sessions := db.NewSessionRepository(db.DB)
+		if claims.SessionID != "" {
+			if _, err := sessions.RevokeSession(ctx, claims.ID, claims.SessionID); err != nil {
+				log.Println("Logout error:", err)
+				writeError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+		}
+
+		if req.RefreshToken != "" && !claims.IsClient() {
+			rt, err := db.NewRefreshTokenRepository(db.DB).GetRefreshTokenByHash(ctx, token.HashRefreshToken(req.RefreshToken))
+			if err == nil && rt.UserID == claims.ID && rt.FamilyID != claims.SessionID {
+				if _, err := sessions.RevokeSession(ctx, claims.ID, rt.FamilyID); err != nil {
+					log.Println("Logout error:", err)
+					writeError(w, http.StatusInternalServerError, "Database error")
+					return
+				}
+			}
+		}
+
+		recordAudit(r, proxies, auditEvent(models.AuditLogout, claims))
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Logged out"})
+	}
+}
+
+// revokeAllUserTokens відкликає всі видані користувачу access і refresh
+// токени та завершує його сесії.
+func revokeAllUserTokens(ctx context.Context, userID string) error {
+	if err := db.NewRevocationRepository(db.DB).RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	_, err := db.NewSessionRepository(db.DB).RevokeUserSessions(ctx, userID, "")
+	return err
+}
+
+// revokeReusedFamily завершує сесію, refresh токен якої використано повторно:
+// відкликається вся сім'я, а access токени сесії перестають діяти.
+func revokeReusedFamily(ctx context.Context, rt *models.RefreshToken) {
+	log.Printf("Refresh token reuse detected for user %s, revoking family %s", rt.UserID, rt.FamilyID)
+	if _, err := db.NewSessionRepository(db.DB).RevokeSession(ctx, rt.UserID, rt.FamilyID); err != nil {
+		log.Println("Revoke refresh token family error:", err)
+	}
+}
+
+// requestOrigin — звідки надійшов запит: записується в нову сесію і в
+// журнал аудиту.
+type requestOrigin struct {
+	IP        string
+	UserAgent string
+}
+
+func newRequestOrigin(r *http.Request, proxies utils.TrustedProxies) requestOrigin {
+	return requestOrigin{IP: proxies.ClientIP(r), UserAgent: truncateString(r.UserAgent(), maxUserAgentLength)}
+}
+
+// truncateString обрізає s до n байтів, не розриваючи символ UTF-8.
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+// issueTokens створює access токен і refresh токен у сім'ї familyID
+// (порожній familyID починає нову сім'ю, тобто новий вхід). Сім'я — це і
+// сесія входу: нова сесія записується з origin, а оновлення токенів лише
+// продовжує її. Ролі й дозволи щоразу читаються з БД, тож оновлення токенів
+// підхоплює їх зміни. Новий вхід починається в організації за замовчуванням,
+// а оновлення залишає сесію в її поточній організації.
+func issueTokens(ctx context.Context, jwtMaker *token.JWTMaker, user *models.User, familyID string, origin requestOrigin) (AuthResponse, error) {
+	sessions := db.NewSessionRepository(db.DB)
+	newSession := familyID == ""
+	var tenantID string
+	if newSession {
+		familyID = uuid.NewString()
+		id, err := db.NewTenantRepository(db.DB).DefaultTenantID(ctx, user.ID)
+		if err != nil {
+			return AuthResponse{}, err
+		}
+		tenantID = id
+	} else {
+		session, err := sessions.GetSession(ctx, familyID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return AuthResponse{}, err
+		}
+		if session != nil {
+			tenantID = session.TenantID
+		}
+	}
+
+	access, err := loadTokenAccess(ctx, user.ID, tenantID)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	tokenString, claims, err := jwtMaker.CreateToken(user.ID, user.Login, access.Roles, access.Permissions, access.TenantID, access.TenantRole, familyID, accessTokenDuration())
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	rawRefresh, refreshHash, err := token.NewRefreshToken()
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	now := time.Now()
+	rt := &models.RefreshToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: refreshHash,
+		ExpiresAt: now.Add(refreshTokenDuration()),
+		CreatedAt: now,
+	}
+	if err := db.NewRefreshTokenRepository(db.DB).CreateRefreshToken(ctx, rt); err != nil {
+		return AuthResponse{}, err
+	}
+
+	renewed := false
+	if !newSession {
+		renewed, err = sessions.RenewSession(ctx, familyID, claims.RegisteredClaims.ID, now, rt.ExpiresAt)
+		if err != nil {
+			return AuthResponse{}, err
+		}
+		// Користувач міг вийти з організації сесії: далі вона діє без неї.
+		if renewed && access.TenantID != tenantID {
+			if err := sessions.SetSessionTenant(ctx, familyID, access.TenantID); err != nil {
+				return AuthResponse{}, err
+			}
+		}
+	}
+	// Сім'я, видана до появи сесій, не має запису: він створюється при її
+	// першому оновленні.
+	if !renewed {
+		err = sessions.CreateSession(ctx, &models.Session{
+			ID:        familyID,
+			UserID:    user.ID,
+			TokenID:   claims.RegisteredClaims.ID,
+			ClientIP:  origin.IP,
+			UserAgent: origin.UserAgent,
+			CreatedAt: now,
+			IssuedAt:  now,
+			ExpiresAt: rt.ExpiresAt,
+			TenantID:  access.TenantID,
+		})
+		if err != nil {
+			return AuthResponse{}, err
+		}
+	}
+
+	return AuthResponse{
+		Token:            tokenString,
+		ExpiresAt:        claims.ExpiresAt.Time,
+		RefreshToken:     rawRefresh,
+		RefreshExpiresAt: rt.ExpiresAt,
+		User: UserResponse{
+			ID:     user.ID,
+			Login:  user.Login,
+			Email:  user.Email,
+			Status: user.Status,
+			Roles:  access.Roles,
+		},
+		TenantID:   access.TenantID,
+		TenantRole: access.TenantRole,
+	}, nil
+}
+
+func accessTokenDuration() time.Duration {
+	return envDuration("JWT_EXPIRET_TIME", defaultAccessTokenDuration)
+}
+
+func refreshTokenDuration() time.Duration {
+	return envDuration("REFRESH_TOKEN_EXPIRE_TIME", defaultRefreshTokenDuration)
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Printf("Warning: %s not set, using %s", key, fallback)
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// NewRegisterHandler godoc
+// @Summary Реєстрація користувача
+// @Description Створює новий обліковий запис з роллю user. Якщо вказано email, на нього надсилається лист підтвердження; коли підтвердження обов'язкове (EMAIL_VERIFICATION_REQUIRED), email потрібен, а увійти можна лише після підтвердження.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RegisterRequest true "Дані нового користувача"
+// @Success 201 {object} UserResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або пароль не відповідає політиці"
+// @Failure 409 {object} models.ErrorResponse "Логін або email уже зайнятий"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Router /api/auth/register [post]
+func NewRegisterHandler(policy *passwords.Policy, mailer mail.Mailer, verification mail.VerificationConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RegisterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		if !utils.ValidLogin(req.Login) {
+			writeError(w, http.StatusBadRequest, "Login must be 3-32 characters: letters, digits, '.', '_' or '-', starting with a letter or digit")
+			return
+		}
+		email, ok := utils.NormalizeEmail(req.Email)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Invalid email")
+			return
+		}
+		if email == "" && verification.Required {
+			writeError(w, http.StatusBadRequest, "Email is required")
+			return
+		}
+		if !checkNewPassword(w, r.Context(), policy, &models.User{Login: req.Login}, req.Password) {
+			return
+		}
+
+		hashedPassword, err := utils.HashPassword(req.Password)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to hash password")
+			return
+		}
+
+		user := &models.User{
+			ID:        uuid.NewString(),
+			Login:     req.Login,
+			Email:     email,
+			Status:    models.UserStatusActive,
+			Password:  hashedPassword,
+			CreatedAt: time.Now(),
+		}
+		if verification.Required {
+			user.Status = models.UserStatusPending
+		}
+
+		repo := db.NewUserRepository(db.DB)
+		if err := repo.CreateUser(r.Context(), user); err != nil {
+			if errors.Is(err, db.ErrUserExists) {
+				writeError(w, http.StatusConflict, "Login is already taken")
+				return
+			}
+			if errors.Is(err, db.ErrEmailExists) {
+				writeError(w, http.StatusConflict, "Email is already in use")
+				return
+			}
+			log.Println("Register error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		roles := []string{models.RoleUser}
+		err = db.NewRoleRepository(db.DB).SetUserRoles(r.Context(), user.ID, roles)
+		if err == nil {
+			err = policy.Record(r.Context(), user.ID, hashedPassword)
+		}
+		if err != nil {
+			log.Println("Register error:", err)
+			// Без ролей чи історії паролів обліковий запис неповний, а повторна
+			// реєстрація впала б на зайнятому логіні, тож користувача видаляємо
+			// (решту його рядків прибере каскад), навіть якщо запит скасовано.
+			if err := repo.DeleteUser(context.WithoutCancel(r.Context()), user.ID); err != nil {
+				log.Println("Register cleanup error:", err)
+			}
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if user.Email != "" {
+			sendVerification(r.Context(), mailer, verification, user)
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(AuthResponse{
-			Token:     tokenString,
-			ExpiresAt: claims.ExpiresAt.Time,
-			User:      userResponse,
+		writeJSON(w, http.StatusCreated, UserResponse{
+			ID:     user.ID,
+			Login:  user.Login,
+			Email:  user.Email,
+			Status: user.Status,
+			Roles:  roles,
 		})
 	}
 }
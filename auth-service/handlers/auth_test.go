@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/mail"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/passwords"
+)
+
+func TestRegisterHandler_RemovesUserOnFailure(t *testing.T) {
+	setupHandlerTestDB(t)
+	ctx := context.Background()
+	policy, err := passwords.NewPolicy(db.NewPasswordHistoryRepository(db.DB), passwords.Config{MinLength: 10, HistorySize: 3}, "")
+	require.NoError(t, err)
+	handler := NewRegisterHandler(policy, mail.NewFileMailer(io.Discard, ""), mail.VerificationConfig{})
+
+	register := func() int {
+		r := newAuthedRequest(t, http.MethodPost, "/api/auth/register",
+			RegisterRequest{Login: "newcomer", Password: "correct-horse-battery"})
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	// Без ролі user призначення ролей падає вже після створення користувача.
+	roles := db.NewRoleRepository(db.DB)
+	userRole, err := roles.GetRole(ctx, models.RoleUser)
+	require.NoError(t, err)
+	require.NoError(t, roles.DeleteRole(ctx, models.RoleUser))
+
+	require.Equal(t, http.StatusInternalServerError, register())
+	_, err = db.NewUserRepository(db.DB).GetUserByLogin(ctx, "newcomer")
+	require.Error(t, err, "half-registered user must be removed")
+
+	userRole.CreatedAt = time.Now()
+	require.NoError(t, roles.CreateRole(ctx, userRole))
+	assert.Equal(t, http.StatusCreated, register(), "retry must not fail with login taken")
+}
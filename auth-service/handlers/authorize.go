@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/lockout"
+	"ksv/rest-mikroservice/auth-service/mfa"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/oidc"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+const loginPageServerError = "Помилка сервера. Спробуйте пізніше."
+
+//go:embed login.html
+var loginPageHTML string
+
+var loginPageTemplate = template.Must(template.New("login").Parse(loginPageHTML))
+
+// authorizeParams — параметри запиту авторизації (RFC 6749, розділ 4.1.1, і
+// RFC 7636). Сторінка входу передає їх далі прихованими полями форми.
+type authorizeParams struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+func parseAuthorizeParams(v url.Values) authorizeParams {
+	return authorizeParams{
+		ClientID:            v.Get("client_id"),
+		RedirectURI:         v.Get("redirect_uri"),
+		ResponseType:        v.Get("response_type"),
+		Scope:               v.Get("scope"),
+		State:               v.Get("state"),
+		Nonce:               v.Get("nonce"),
+		CodeChallenge:       v.Get("code_challenge"),
+		CodeChallengeMethod: v.Get("code_challenge_method"),
+	}
+}
+
+// loginPage — дані шаблону login.html. Без Form сторінка лише показує Error.
+type loginPage struct {
+	ClientName string
+	Params     authorizeParams
+	Form       bool
+	Login      string
+	MFAToken   string
+	Error      string
+}
+
+func renderLoginPage(w http.ResponseWriter, status int, page loginPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := loginPageTemplate.Execute(w, page); err != nil {
+		log.Println("Render login page error:", err)
+	}
+}
+
+// authorizeClient перевіряє client_id і redirect_uri. Доки вони не
+// перевірені, про помилку не можна повідомити редиректом — інакше
+// /oauth/authorize став би відкритим редиректом, — тож повертається текст
+// для сторінки. Порожній текст і nil клієнт означають помилку БД.
+func authorizeClient(ctx context.Context, p authorizeParams) (*models.Client, string, error) {
+	client, err := db.NewClientRepository(db.DB).GetClient(ctx, p.ClientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "Невідомий застосунок.", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if !slices.Contains(client.RedirectURIs, p.RedirectURI) {
+		return nil, "Адреса повернення (redirect_uri) не зареєстрована для цього застосунку.", nil
+	}
+	return client, "", nil
+}
+
+// checkAuthorizeParams повертає код помилки OAuth і опис, якщо запит
+// авторизації некоректний. Підтримується лише response_type=code з PKCE S256.
+func checkAuthorizeParams(p authorizeParams) (string, string) {
+	if p.ResponseType != "code" {
+		return "unsupported_response_type", "response_type must be code"
+	}
+	if _, ok := oidc.ParseScopes(p.Scope); !ok {
+		return "invalid_scope", "Supported scopes: " + strings.Join(oidc.SupportedScopes, " ")
+	}
+	if p.CodeChallenge == "" {
+		return "invalid_request", "code_challenge is required"
+	}
+	if p.CodeChallengeMethod != oidc.CodeChallengeS256 {
+		return "invalid_request", "code_challenge_method must be S256"
+	}
+	return "", ""
+}
+
+// validateAuthorize перевіряє запит авторизації і, якщо він некоректний,
+// показує сторінку з помилкою або повертає помилку на redirect_uri.
+func validateAuthorize(w http.ResponseWriter, r *http.Request, p authorizeParams) (*models.Client, bool) {
+	client, message, err := authorizeClient(r.Context(), p)
+	if err != nil {
+		log.Println("Authorize error:", err)
+		renderLoginPage(w, http.StatusInternalServerError, loginPage{Error: loginPageServerError})
+		return nil, false
+	}
+	if client == nil {
+		renderLoginPage(w, http.StatusBadRequest, loginPage{Error: message})
+		return nil, false
+	}
+	if code, description := checkAuthorizeParams(p); code != "" {
+		redirectAuthorize(w, r, p, url.Values{"error": {code}, "error_description": {description}})
+		return nil, false
+	}
+	return client, true
+}
+
+// redirectAuthorize повертає браузер на redirect_uri з параметрами values і state.
+func redirectAuthorize(w http.ResponseWriter, r *http.Request, p authorizeParams, values url.Values) {
+	u, err := url.Parse(p.RedirectURI)
+	if err != nil {
+		log.Println("Authorize redirect error:", err)
+		renderLoginPage(w, http.StatusInternalServerError, loginPage{Error: loginPageServerError})
+		return
+	}
+	q := u.Query()
+	for k, v := range values {
+		q[k] = v
+	}
+	if p.State != "" {
+		q.Set("state", p.State)
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func newLoginPage(client *models.Client, p authorizeParams) loginPage {
+	name := client.Name
+	if name == "" {
+		name = client.ID
+	}
+	return loginPage{ClientName: name, Params: p, Form: true}
+}
+
+// renderGuardResult показує сторінку входу з помилкою, якщо перевірку
+// checkGuarded не пройдено, і повертає false.
+func renderGuardResult(w http.ResponseWriter, page loginPage, block *lockout.Block, ok bool, err error, message string) bool {
+	switch {
+	case err != nil:
+		log.Println("Credentials check error:", err)
+		page.Error = loginPageServerError
+		renderLoginPage(w, http.StatusInternalServerError, page)
+	case block != nil:
+		seconds := retryAfterSeconds(block)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		if block.Locked {
+			page.Error = fmt.Sprintf("Обліковий запис тимчасово заблоковано. Спробуйте через %d с.", seconds)
+			renderLoginPage(w, http.StatusLocked, page)
+		} else {
+			page.Error = fmt.Sprintf("Забагато спроб входу. Спробуйте через %d с.", seconds)
+			renderLoginPage(w, http.StatusTooManyRequests, page)
+		}
+	case !ok:
+		page.Error = message
+		renderLoginPage(w, http.StatusUnauthorized, page)
+	}
+	return ok
+}
+
+// authorizePassword перевіряє логін і пароль з форми. Якщо потрібен другий
+// фактор, показує форму для коду і повертає false.
+func authorizePassword(w http.ResponseWriter, r *http.Request, mfaCfg mfa.Config, guard *lockout.Guard, proxies utils.TrustedProxies, page loginPage) (*models.User, bool) {
+	ctx := r.Context()
+	login, password := r.PostForm.Get("login"), r.PostForm.Get("password")
+	page.Login = login
+
+	repo := db.NewUserRepository(db.DB)
+	var user *models.User
+	block, ok, err := checkGuarded(ctx, guard, proxies.ClientIP(r), login, func() (bool, error) {
+		u, err := repo.GetUserByLogin(ctx, login)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+		if u == nil || utils.CheckPassword(password, u.Password) != nil {
+			if u == nil {
+				// Як і в NewAuthHandler, фіктивний хеш вирівнює час відповіді.
+				utils.CheckPassword(password, dummyPasswordHash())
+			}
+			recordAudit(r, proxies, loginFailureEvent(login, u, "invalid credentials"))
+			return false, nil
+		}
+		user = u
+		return true, nil
+	})
+	if !renderGuardResult(w, page, block, ok, err, "Невірний логін або пароль.") {
+		return nil, false
+	}
+	if utils.NeedsRehash(user.Password) {
+		rehashPassword(ctx, repo, user, password)
+	}
+	if !authorizeUserStatus(w, r, proxies, page, user) {
+		return nil, false
+	}
+
+	challenge, err := startMFAChallenge(ctx, mfaCfg, user)
+	if err != nil {
+		log.Println("MFA challenge error:", err)
+		page.Error = loginPageServerError
+		renderLoginPage(w, http.StatusInternalServerError, page)
+		return nil, false
+	}
+	if challenge == nil {
+		return user, true
+	}
+	if challenge.Enrollment != nil {
+		// Коди відновлення показуються лише один раз, а редирект на
+		// застосунок їх загубив би, тож MFA налаштовується через API.
+		page.Form = false
+		page.Error = "Ваша роль вимагає двофакторної автентифікації. Налаштуйте її, увійшовши через API, а потім спробуйте знову."
+		renderLoginPage(w, http.StatusForbidden, page)
+		return nil, false
+	}
+	page.MFAToken = challenge.MFAToken
+	renderLoginPage(w, http.StatusOK, page)
+	return nil, false
+}
+
+// authorizeMFA перевіряє код другого фактора для токена mfaToken з форми.
+func authorizeMFA(w http.ResponseWriter, r *http.Request, mfaCfg mfa.Config, guard *lockout.Guard, proxies utils.TrustedProxies, page loginPage, mfaToken string) (*models.User, bool) {
+	ctx := r.Context()
+	now := time.Now()
+
+	challenge, user, userMFA, ok := attemptMFAChallenge(ctx, mfaCfg, mfaToken, now)
+	if !ok || !userMFA.Enabled {
+		page.Error = "Код більше не дійсний. Увійдіть знову."
+		renderLoginPage(w, http.StatusUnauthorized, page)
+		return nil, false
+	}
+
+	page.MFAToken = mfaToken
+	block, ok, err := checkGuarded(ctx, guard, proxies.ClientIP(r), user.Login, func() (bool, error) {
+		ok, err := checkMFACode(ctx, userMFA, r.PostForm.Get("code"), now)
+		if err == nil && !ok {
+			recordAudit(r, proxies, loginFailureEvent(user.Login, user, "invalid MFA code"))
+		}
+		return ok, err
+	})
+	if !renderGuardResult(w, page, block, ok, err, "Невірний код.") {
+		return nil, false
+	}
+
+	if err := db.NewMFARepository(db.DB).DeleteChallenge(ctx, challenge.ID); err != nil {
+		log.Println("MFA challenge error:", err)
+	}
+	if !authorizeUserStatus(w, r, proxies, page, user) {
+		return nil, false
+	}
+	return user, true
+}
+
+// authorizeUserStatus показує сторінку з відмовою, якщо обліковий запис не
+// активний.
+func authorizeUserStatus(w http.ResponseWriter, r *http.Request, proxies utils.TrustedProxies, page loginPage, user *models.User) bool {
+	switch user.Status {
+	case models.UserStatusPending:
+		page.Error = "Підтвердіть email за посиланням з листа, щоб увійти."
+	case models.UserStatusDisabled:
+		page.Error = "Обліковий запис вимкнено."
+	default:
+		return true
+	}
+	recordAudit(r, proxies, loginFailureEvent(user.Login, user, "account "+user.Status))
+	page.Form = false
+	renderLoginPage(w, http.StatusForbidden, page)
+	return false
+}
+
+// issueAuthorizationCode зберігає хеш нового коду авторизації і повертає сам код.
+func issueAuthorizationCode(ctx context.Context, cfg oidc.Config, p authorizeParams, userID string, authTime time.Time) (string, error) {
+	raw, hash, err := oidc.NewAuthorizationCode()
+	if err != nil {
+		return "", err
+	}
+	scopes, _ := oidc.ParseScopes(p.Scope)
+	code := &models.AuthorizationCode{
+		CodeHash:      hash,
+		ClientID:      p.ClientID,
+		UserID:        userID,
+		RedirectURI:   p.RedirectURI,
+		Scope:         strings.Join(scopes, " "),
+		Nonce:         p.Nonce,
+		CodeChallenge: p.CodeChallenge,
+		AuthTime:      authTime,
+		ExpiresAt:     authTime.Add(cfg.CodeTTL),
+	}
+	if err := db.NewAuthorizationCodeRepository(db.DB).CreateAuthorizationCode(ctx, code); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// NewAuthorizeHandler godoc
+// @Summary Сторінка входу (authorization code)
+// @Description Початок grant authorization_code з PKCE (RFC 6749, розділ 4.1; RFC 7636) для браузерних застосунків. Показує сторінку входу; після входу браузер повертається на redirect_uri з параметрами code і state. Помилки в client_id або redirect_uri показуються на сторінці, інші повертаються на redirect_uri параметрами error і error_description.
+// @Tags oidc
+// @Produce html
+// @Param client_id query string true "ID клієнта"
+// @Param redirect_uri query string true "Зареєстрована адреса повернення"
+// @Param response_type query string true "code"
+// @Param scope query string false "openid, profile через пробіл"
+// @Param state query string false "Значення, яке повертається застосунку без змін"
+// @Param nonce query string false "Значення для claim nonce в ID токені"
+// @Param code_challenge query string true "BASE64URL(SHA256(code_verifier))"
+// @Param code_challenge_method query string true "S256"
+// @Success 200 {string} string "Сторінка входу"
+// @Success 302 {string} string "Помилка на redirect_uri"
+// @Failure 400 {string} string "Невідомий клієнт або redirect_uri"
+// @Router /oauth/authorize [get]
+func NewAuthorizeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := parseAuthorizeParams(r.URL.Query())
+		client, ok := validateAuthorize(w, r, p)
+		if !ok {
+			return
+		}
+
+		renderLoginPage(w, http.StatusOK, newLoginPage(client, p))
+	}
+}
+
+// NewAuthorizeLoginHandler godoc
+// @Summary Вхід на сторінці авторизації
+// @Description Приймає форму сторінки входу: логін і пароль, а якщо потрібен другий фактор — mfa_token і код. Невдалі спроби враховуються так само, як у POST /api/auth. Після успішного входу повертає браузер на redirect_uri з одноразовим кодом для POST /oauth/token.
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param login formData string false "Логін"
+// @Param password formData string false "Пароль"
+// @Param mfa_token formData string false "Токен другого кроку зі сторінки"
+// @Param code formData string false "Код TOTP або код відновлення"
+// @Success 200 {string} string "Форма для коду другого фактора"
+// @Success 302 {string} string "Редирект на redirect_uri з code і state"
+// @Failure 400 {string} string "Некоректний запит"
+// @Failure 401 {string} string "Невірні дані"
+// @Failure 423 {string} string "Логін тимчасово заблоковано"
+// @Failure 429 {string} string "Забагато спроб"
+// @Router /oauth/authorize [post]
+func NewAuthorizeLoginHandler(cfg oidc.Config, mfaCfg mfa.Config, guard *lockout.Guard, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			renderLoginPage(w, http.StatusBadRequest, loginPage{Error: "Некоректний запит."})
+			return
+		}
+		p := parseAuthorizeParams(r.PostForm)
+		client, ok := validateAuthorize(w, r, p)
+		if !ok {
+			return
+		}
+
+		page := newLoginPage(client, p)
+		var user *models.User
+		if mfaToken := r.PostForm.Get("mfa_token"); mfaToken != "" {
+			user, ok = authorizeMFA(w, r, mfaCfg, guard, proxies, page, mfaToken)
+		} else {
+			user, ok = authorizePassword(w, r, mfaCfg, guard, proxies, page)
+		}
+		if !ok {
+			return
+		}
+
+		code, err := issueAuthorizationCode(r.Context(), cfg, p, user.ID, time.Now())
+		if err != nil {
+			log.Println("Authorization code error:", err)
+			page.Error = loginPageServerError
+			renderLoginPage(w, http.StatusInternalServerError, page)
+			return
+		}
+
+		log.Printf("Authorization code issued to client %s for user %s", client.ID, user.ID)
+		event := userAuditEvent(models.AuditLoginSuccess, user)
+		event.Details = "authorization code for client " + client.ID
+		recordAudit(r, proxies, event)
+		redirectAuthorize(w, r, p, url.Values{"code": {code}})
+	}
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+var clientIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,63}$`)
+
+type ClientResponse struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// RedirectURIs — адреси повернення для grant authorization_code.
+	RedirectURIs []string `json:"redirect_uris"`
+	// Public — клієнт без секрету (браузерний застосунок з PKCE).
+	Public          bool      `json:"public"`
+	CreatedAt       time.Time `json:"created_at"`
+	SecretRotatedAt time.Time `json:"secret_rotated_at"`
+}
+
+// ClientSecretResponse повертається при створенні клієнта і ротації секрету.
+// Секрет не зберігається у відкритому вигляді, тож показується лише раз;
+// публічний клієнт секрету не має.
+type ClientSecretResponse struct {
+	ClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type CreateClientRequest struct {
+	ID           string   `json:"id" example:"nightly-reports"`
+	Name         string   `json:"name" example:"Нічні звіти"`
+	Scopes       []string `json:"scopes" example:"product:read"`
+	RedirectURIs []string `json:"redirect_uris" example:"https://app.example.com/callback"`
+	Public       bool     `json:"public"`
+}
+
+type UpdateClientRequest struct {
+	Name         string   `json:"name"`
+	Scopes       []string `json:"scopes"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+func newClientResponse(client *models.Client) ClientResponse {
+	scopes := client.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	redirectURIs := client.RedirectURIs
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+	return ClientResponse{
+		ID:              client.ID,
+		Name:            client.Name,
+		Scopes:          scopes,
+		RedirectURIs:    redirectURIs,
+		Public:          client.Public,
+		CreatedAt:       client.CreatedAt,
+		SecretRotatedAt: client.SecretRotatedAt,
+	}
+}
+
+// checkRedirectURIs перевіряє адреси повернення: абсолютні http(s) URL без
+// фрагмента (RFC 6749, розділ 3.1.2). Повертає некоректну адресу або "".
+func checkRedirectURIs(uris []string) string {
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || strings.Contains(uri, "#") {
+			return uri
+		}
+	}
+	return ""
+}
+
+func writeClientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "Client not found")
+	case errors.Is(err, db.ErrUnknownPermission):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, db.ErrClientExists):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Println("Client error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+	}
+}
+
+// revokeClientTokens відкликає всі access токени, видані клієнту.
+func revokeClientTokens(ctx context.Context, clientID string) error {
+	return db.NewRevocationRepository(db.DB).RevokeAllForUser(ctx, token.ClientRevocationKey(clientID))
+}
+
+// NewListClientsHandler godoc
+// @Summary Список OAuth клієнтів
+// @Description Повертає OAuth клієнтів разом з їх scopes і redirect URI (потрібен дозвіл clients:manage)
+// @Tags clients
+// @Produce json
+// @Success 200 {array} ClientResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/clients [get]
+func NewListClientsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clients, err := db.NewClientRepository(db.DB).ListClients(r.Context())
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+
+		resp := make([]ClientResponse, 0, len(clients))
+		for i := range clients {
+			resp = append(resp, newClientResponse(&clients[i]))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewGetClientHandler godoc
+// @Summary OAuth клієнт
+// @Description Повертає клієнта за ID (потрібен дозвіл clients:manage)
+// @Tags clients
+// @Produce json
+// @Param id path string true "ID клієнта"
+// @Success 200 {object} ClientResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Клієнта не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/clients/{id} [get]
+func NewGetClientHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client, err := db.NewClientRepository(db.DB).GetClient(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newClientResponse(client))
+	}
+}
+
+// checkClientScopes пише 403, якщо серед scopes є дозвіл, якого немає в
+// автора запиту: інакше clients:manage дозволяв би видати собі через
+// client_credentials будь-який дозвіл. Scopes із granted, які клієнт уже має,
+// не перевіряються, щоб можна було змінити інші поля клієнта.
+func checkClientScopes(w http.ResponseWriter, r *http.Request, scopes []string, granted []string) bool {
+	claims := claimsFromContext(r.Context())
+	for _, scope := range scopes {
+		if slices.Contains(granted, scope) || claims.HasPermission(scope) {
+			continue
+		}
+		writeError(w, http.StatusForbidden, "Scope not granted to caller: "+scope)
+		return false
+	}
+	return true
+}
+
+// NewCreateClientHandler godoc
+// @Summary Створення OAuth клієнта
+// @Description Створює OAuth клієнта (потрібен дозвіл clients:manage). Scopes — дозволи для grant client_credentials; додати можна лише ті, що є в автора запиту; redirect_uris — адреси повернення для grant authorization_code. Публічний клієнт (public) не має секрету, входить лише через authorization_code з PKCE і потребує хоча б одного redirect URI. Секрет повертається лише у цій відповіді.
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Param request body CreateClientRequest true "Новий клієнт"
+// @Success 201 {object} ClientSecretResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або невідомий дозвіл"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав або scope, якого немає в автора запиту"
+// @Failure 409 {object} models.ErrorResponse "Клієнт уже існує"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/clients [post]
+func NewCreateClientHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateClientRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if !clientIDPattern.MatchString(req.ID) {
+			writeError(w, http.StatusBadRequest, "Client ID must be 2-64 characters: lowercase letters, digits, '_' or '-', starting with a letter")
+			return
+		}
+		if uri := checkRedirectURIs(req.RedirectURIs); uri != "" {
+			writeError(w, http.StatusBadRequest, "Invalid redirect URI: "+uri)
+			return
+		}
+		if req.Public && len(req.RedirectURIs) == 0 {
+			writeError(w, http.StatusBadRequest, "Public clients require redirect_uris")
+			return
+		}
+
+		if !checkClientScopes(w, r, req.Scopes, nil) {
+			return
+		}
+
+		var secret, secretHash string
+		if !req.Public {
+			var err error
+			secret, secretHash, err = token.NewClientSecret()
+			if err != nil {
+				log.Println("Create client error:", err)
+				writeError(w, http.StatusInternalServerError, "Failed to generate client secret")
+				return
+			}
+		}
+
+		now := time.Now()
+		client := &models.Client{
+			ID:              req.ID,
+			Name:            req.Name,
+			SecretHash:      secretHash,
+			CreatedAt:       now,
+			SecretRotatedAt: now,
+			Public:          req.Public,
+			Scopes:          req.Scopes,
+			RedirectURIs:    req.RedirectURIs,
+		}
+		repo := db.NewClientRepository(db.DB)
+		if err := repo.CreateClient(r.Context(), client); err != nil {
+			writeClientError(w, err)
+			return
+		}
+		created, err := repo.GetClient(r.Context(), client.ID)
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+
+		log.Printf("OAuth client %s created", created.ID)
+		writeJSON(w, http.StatusCreated, ClientSecretResponse{
+			ClientResponse: newClientResponse(created),
+			ClientSecret:   secret,
+		})
+	}
+}
+
+// NewUpdateClientHandler godoc
+// @Summary Зміна OAuth клієнта
+// @Description Змінює назву клієнта і замінює його scopes і redirect URI (потрібен дозвіл clients:manage). Нові scopes мають бути серед дозволів автора запиту. Зміни потрапляють у токени, видані після цього.
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Param id path string true "ID клієнта"
+// @Param request body UpdateClientRequest true "Назва, scopes і redirect URI клієнта"
+// @Success 200 {object} ClientResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або невідомий дозвіл"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав або scope, якого немає в автора запиту"
+// @Failure 404 {object} models.ErrorResponse "Клієнта не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/clients/{id} [put]
+func NewUpdateClientHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req UpdateClientRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if uri := checkRedirectURIs(req.RedirectURIs); uri != "" {
+			writeError(w, http.StatusBadRequest, "Invalid redirect URI: "+uri)
+			return
+		}
+
+		repo := db.NewClientRepository(db.DB)
+		client, err := repo.GetClient(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+		if client.Public && len(req.RedirectURIs) == 0 {
+			writeError(w, http.StatusBadRequest, "Public clients require redirect_uris")
+			return
+		}
+		if !checkClientScopes(w, r, req.Scopes, client.Scopes) {
+			return
+		}
+
+		client.Name = req.Name
+		client.Scopes = req.Scopes
+		client.RedirectURIs = req.RedirectURIs
+		if err := repo.UpdateClient(r.Context(), client); err != nil {
+			writeClientError(w, err)
+			return
+		}
+		updated, err := repo.GetClient(r.Context(), client.ID)
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newClientResponse(updated))
+	}
+}
+
+// NewRotateClientSecretHandler godoc
+// @Summary Ротація секрету OAuth клієнта
+// @Description Видає клієнту новий секрет; старий одразу перестає діяти, а видані раніше токени клієнта відкликаються (потрібен дозвіл clients:manage). Секрет повертається лише у цій відповіді.
+// @Tags clients
+// @Produce json
+// @Param id path string true "ID клієнта"
+// @Success 200 {object} ClientSecretResponse
+// @Failure 400 {object} models.ErrorResponse "Публічний клієнт не має секрету"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Клієнта не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/clients/{id}/rotate-secret [post]
+func NewRotateClientSecretHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := r.PathValue("id")
+
+		repo := db.NewClientRepository(db.DB)
+		client, err := repo.GetClient(ctx, id)
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+		if client.Public {
+			writeError(w, http.StatusBadRequest, "Public clients have no secret")
+			return
+		}
+
+		secret, secretHash, err := token.NewClientSecret()
+		if err != nil {
+			log.Println("Rotate client secret error:", err)
+			writeError(w, http.StatusInternalServerError, "Failed to generate client secret")
+			return
+		}
+
+		rotated, err := repo.RotateSecret(ctx, id, secretHash, time.Now())
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+		if !rotated {
+			writeError(w, http.StatusNotFound, "Client not found")
+			return
+		}
+		if err := revokeClientTokens(ctx, id); err != nil {
+			writeClientError(w, err)
+			return
+		}
+		client, err = repo.GetClient(ctx, id)
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+
+		log.Printf("OAuth client %s secret rotated", id)
+		writeJSON(w, http.StatusOK, ClientSecretResponse{
+			ClientResponse: newClientResponse(client),
+			ClientSecret:   secret,
+		})
+	}
+}
+
+// NewDeleteClientHandler godoc
+// @Summary Видалення OAuth клієнта
+// @Description Видаляє клієнта і відкликає всі видані йому токени (потрібен дозвіл clients:manage)
+// @Tags clients
+// @Produce json
+// @Param id path string true "ID клієнта"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Клієнта не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/clients/{id} [delete]
+func NewDeleteClientHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := r.PathValue("id")
+
+		deleted, err := db.NewClientRepository(db.DB).DeleteClient(ctx, id)
+		if err != nil {
+			writeClientError(w, err)
+			return
+		}
+		if !deleted {
+			writeError(w, http.StatusNotFound, "Client not found")
+			return
+		}
+		if err := revokeClientTokens(ctx, id); err != nil {
+			writeClientError(w, err)
+			return
+		}
+
+		log.Printf("OAuth client %s deleted", id)
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Client deleted"})
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/mail"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+// Коди відмови у вході для облікових записів, що не активні.
+const (
+	errCodeAccountPending  = "account_pending"
+	errCodeAccountDisabled = "account_disabled"
+)
+
+type VerifyEmailRequest struct {
+	// Token — токен з листа підтвердження email.
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" example:"user@example.com"`
+}
+
+// userStatusError повертає код і повідомлення відмови для користувача, який
+// не може увійти, або порожній код для активного.
+func userStatusError(user *models.User) (string, string) {
+	switch user.Status {
+	case models.UserStatusPending:
+		return errCodeAccountPending, "Email address is not verified"
+	case models.UserStatusDisabled:
+		return errCodeAccountDisabled, "Account is disabled"
+	}
+	return "", ""
+}
+
+// checkUserStatus пише 403 з кодом причини, якщо користувач не може увійти.
+func checkUserStatus(w http.ResponseWriter, user *models.User) bool {
+	code, message := userStatusError(user)
+	if code == "" {
+		return true
+	}
+	writeErrorCode(w, http.StatusForbidden, code, message)
+	return false
+}
+
+// sendUserToken створює одноразовий токен з призначенням purpose і надсилає
+// його користувачу листом з compose. Якщо з попереднього листа минуло менше
+// interval, нічого не робить і повертає false. Лист надсилається у фоні:
+// час відповіді не має видавати, чи знайдено користувача.
+func sendUserToken(ctx context.Context, mailer mail.Mailer, user *models.User, purpose string, ttl time.Duration, interval time.Duration, compose func(raw string) (mail.Message, error)) (bool, error) {
+	tokens := db.NewUserTokenRepository(db.DB)
+	now := time.Now()
+	recent, err := tokens.HasRecentToken(ctx, user.ID, purpose, now.Add(-interval))
+	if err != nil {
+		return false, err
+	}
+	if recent {
+		return false, nil
+	}
+
+	raw, hash, err := token.NewUserToken()
+	if err != nil {
+		return false, err
+	}
+	msg, err := compose(raw)
+	if err != nil {
+		return false, fmt.Errorf("failed to compose email: %w", err)
+	}
+	if err := tokens.CreateUserToken(ctx, &models.UserToken{
+		TokenHash: hash,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}); err != nil {
+		return false, err
+	}
+
+	go func() {
+		if err := mailer.Send(context.Background(), msg); err != nil {
+			log.Printf("Send %s email to user %s error: %v", purpose, user.ID, err)
+		}
+	}()
+	return true, nil
+}
+
+func verificationMessage(user *models.User, verificationToken string, cfg mail.VerificationConfig) (mail.Message, error) {
+	link, err := cfg.Link(verificationToken)
+	if err != nil {
+		return mail.Message{}, err
+	}
+	action := "Щоб підтвердити адресу, перейдіть за посиланням:\n" + link
+	if link == "" {
+		action = "Щоб підтвердити адресу, використайте токен підтвердження:\n" + verificationToken
+	}
+
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Підтвердження email",
+		Body: fmt.Sprintf("Вітаємо, %s!\n\n"+
+			"Цю адресу вказано для облікового запису %s.\n"+
+			"%s\n\n"+
+			"Токен дійсний %d год. і може бути використаний лише один раз.\n"+
+			"Якщо ви не реєструвалися, просто проігноруйте цей лист.\n",
+			user.Login, user.Login, action, max(int(cfg.TTL.Hours()), 1)),
+	}, nil
+}
+
+// sendVerification надсилає лист підтвердження email користувача. Помилка
+// лише логується: лист можна запросити знову.
+func sendVerification(ctx context.Context, mailer mail.Mailer, cfg mail.VerificationConfig, user *models.User) {
+	_, err := sendUserToken(ctx, mailer, user, models.UserTokenEmailVerification, cfg.TTL, 0, func(raw string) (mail.Message, error) {
+		return verificationMessage(user, raw, cfg)
+	})
+	if err != nil {
+		log.Println("Send verification email error:", err)
+	}
+}
+
+// NewVerifyEmailHandler godoc
+// @Summary Підтвердження email
+// @Description Підтверджує email користувача за одноразовим токеном з листа. Обліковий запис, що чекав на підтвердження, стає активним.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Токен з листа"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит, недійсний або прострочений токен"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Router /api/auth/email/verify [post]
+func NewVerifyEmailHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req VerifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		ctx := r.Context()
+		verification, err := db.NewUserTokenRepository(db.DB).ConsumeUserToken(ctx, token.HashUserToken(req.Token), models.UserTokenEmailVerification, time.Now())
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeError(w, http.StatusBadRequest, "Invalid or expired verification token")
+				return
+			}
+			log.Println("Verify email error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		repo := db.NewUserRepository(db.DB)
+		user, err := repo.GetUserByID(ctx, verification.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeError(w, http.StatusBadRequest, "Invalid or expired verification token")
+				return
+			}
+			log.Println("Verify email error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		// Зміна email видаляє токени користувача, тож токен стосується
+		// поточної адреси.
+		verified, err := repo.VerifyEmail(ctx, user.ID, user.Email, time.Now())
+		if err != nil {
+			log.Println("Verify email error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !verified {
+			writeError(w, http.StatusBadRequest, "Invalid or expired verification token")
+			return
+		}
+
+		event := userAuditEvent(models.AuditEmailVerify, user)
+		event.Details = user.Email
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Email verified"})
+	}
+}
+
+// NewResendVerificationHandler godoc
+// @Summary Повторний лист підтвердження email
+// @Description Надсилає новий лист підтвердження на непідтверджений email. Відповідь однакова, чи існує такий email, чи ні. Новий лист скасовує токен з попереднього; лист надсилається не частіше ніж раз на EMAIL_VERIFICATION_INTERVAL.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResendVerificationRequest true "Email користувача"
+// @Success 202 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Router /api/auth/email/resend [post]
+func NewResendVerificationHandler(mailer mail.Mailer, cfg mail.VerificationConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ResendVerificationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		email, ok := utils.NormalizeEmail(req.Email)
+		if !ok || email == "" {
+			writeError(w, http.StatusBadRequest, "Invalid email")
+			return
+		}
+
+		accepted := models.SuccessResponse{Message: "If the email is registered and not yet verified, a verification link has been sent"}
+
+		ctx := r.Context()
+		user, err := db.NewUserRepository(db.DB).GetUserByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusAccepted, accepted)
+				return
+			}
+			log.Println("Resend verification error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if user.EmailVerifiedAt != nil || user.Status == models.UserStatusDisabled {
+			writeJSON(w, http.StatusAccepted, accepted)
+			return
+		}
+
+		if _, err := sendUserToken(ctx, mailer, user, models.UserTokenEmailVerification, cfg.TTL, cfg.Interval, func(raw string) (mail.Message, error) {
+			return verificationMessage(user, raw, cfg)
+		}); err != nil {
+			log.Println("Resend verification error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, accepted)
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	issuedTokenTypeAccess  = "urn:ietf:params:oauth:token-type:access_token"
+
+	maxImpersonationReasonLength = 256
+)
+
+// TokenExchangeResponse — відповідь обміну токена за RFC 8693, розділ 2.2.1.
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type" example:"urn:ietf:params:oauth:token-type:access_token"`
+	TokenType       string `json:"token_type" example:"Bearer"`
+	// ExpiresIn — строк дії токена в секундах.
+	ExpiresIn int64 `json:"expires_in" example:"600"`
+	// User — користувач, від імені якого виданий токен.
+	User UserResponse `json:"user"`
+}
+
+// findUser шукає користувача за ID, а якщо такого немає — за логіном.
+func findUser(ctx context.Context, subject string) (*models.User, error) {
+	repo := db.NewUserRepository(db.DB)
+	user, err := repo.GetUserByID(ctx, subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repo.GetUserByLogin(ctx, subject)
+	}
+	return user, err
+}
+
+// NewTokenExchangeHandler godoc
+// @Summary Вхід від імені користувача
+// @Description Обмін токена за RFC 8693 для співробітників підтримки (потрібен дозвіл users:impersonate): поточний токен адміністратора обмінюється на короткостроковий access токен користувача requested_subject з claim act, що містить ID і логін адміністратора. Токен діє в організації користувача за замовчуванням. Refresh токен не видається; токен діє, поки активна сесія адміністратора. Не можна увійти від імені себе, неактивного користувача або користувача з дозволами, яких немає в адміністратора. Токеном імперсонації не можна змінити пароль, MFA, API ключі чи сесії користувача. Видача токена записується в журнал аудиту, а дії з ним — від імені адміністратора.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "urn:ietf:params:oauth:grant-type:token-exchange"
+// @Param requested_subject formData string true "ID або логін користувача"
+// @Param requested_token_type formData string false "Лише urn:ietf:params:oauth:token-type:access_token"
+// @Param reason formData string false "Причина, наприклад номер звернення; записується в журнал аудиту"
+// @Success 200 {object} TokenExchangeResponse
+// @Failure 400 {object} models.ErrorResponse "invalid_request, unsupported_grant_type або invalid_target (користувача не знайдено, він неактивний або це ви самі)"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав, потрібен JWT користувача, або в користувача є дозволи, яких немає у вас"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/token/exchange [post]
+func NewTokenExchangeHandler(jwtMaker *token.JWTMaker, ttl time.Duration, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+		if claims.IsClient() || claims.IsAPIKey() {
+			writeError(w, http.StatusForbidden, "User token required")
+			return
+		}
+		if claims.IsImpersonated() {
+			writeError(w, http.StatusForbidden, "Not allowed while impersonating")
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		switch r.PostForm.Get("grant_type") {
+		case grantTypeTokenExchange:
+		case "":
+			writeError(w, http.StatusBadRequest, "invalid_request")
+			return
+		default:
+			writeError(w, http.StatusBadRequest, "unsupported_grant_type")
+			return
+		}
+		if t := r.PostForm.Get("requested_token_type"); t != "" && t != issuedTokenTypeAccess {
+			writeError(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+		subject := strings.TrimSpace(r.PostForm.Get("requested_subject"))
+		if subject == "" {
+			writeError(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+		reason := truncateString(strings.TrimSpace(r.PostForm.Get("reason")), maxImpersonationReasonLength)
+
+		ctx := r.Context()
+		user, err := findUser(ctx, subject)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusBadRequest, "invalid_target")
+			return
+		}
+		if err != nil {
+			log.Println("Token exchange error:", err)
+			writeError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+		if user.ID == claims.ID || user.Status != models.UserStatusActive {
+			writeError(w, http.StatusBadRequest, "invalid_target")
+			return
+		}
+
+		tenantID, err := db.NewTenantRepository(db.DB).DefaultTenantID(ctx, user.ID)
+		if err != nil {
+			log.Println("Token exchange error:", err)
+			writeError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+		access, err := loadTokenAccess(ctx, user.ID, tenantID)
+		if err != nil {
+			log.Println("Token exchange error:", err)
+			writeError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+		// Імперсонація не повинна давати більше прав, ніж є в самого адміністратора.
+		if p := missingPermission(claims, access.Permissions); p != "" {
+			log.Printf("User %s denied impersonation of %s: missing permission %s", claims.Login, user.Login, p)
+			writeError(w, http.StatusForbidden, "access_denied")
+			return
+		}
+
+		actor := &token.Actor{ID: claims.ID, Subject: claims.Login}
+		tokenString, issued, err := jwtMaker.CreateImpersonationToken(user.ID, user.Login, access.Roles, access.Permissions, access.TenantID, access.TenantRole, actor, claims.SessionID, ttl)
+		if err != nil {
+			log.Println("Token exchange error:", err)
+			writeError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+
+		event := targetAuditEvent(models.AuditImpersonationStart, claims, user)
+		event.Details = "token " + issued.RegisteredClaims.ID
+		if reason != "" {
+			event.Details += "; reason: " + reason
+		}
+		recordAudit(r, proxies, event)
+		log.Printf("User %s impersonated by %s", user.Login, claims.Login)
+
+		writeJSON(w, http.StatusOK, TokenExchangeResponse{
+			AccessToken:     tokenString,
+			IssuedTokenType: issuedTokenTypeAccess,
+			TokenType:       "Bearer",
+			ExpiresIn:       int64(ttl.Seconds()),
+			User: UserResponse{
+				ID:     user.ID,
+				Login:  user.Login,
+				Email:  user.Email,
+				Status: user.Status,
+				Roles:  access.Roles,
+			},
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+// Значення token_type у відповіді інтроспекції.
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+	TokenTypeAPIKey  = "api_key"
+)
+
+// IntrospectionResponse — відповідь за RFC 7662. Для недійсного токена
+// заповнене лише active = false.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty" example:"access_token"`
+	// Scope — дозволи користувача або клієнта через пробіл.
+	Scope    string `json:"scope,omitempty" example:"product:read product:write"`
+	Username string `json:"username,omitempty"`
+	// ClientID — клієнт, якому виданий токен grant client_credentials.
+	ClientID string `json:"client_id,omitempty"`
+	APIKeyID string `json:"api_key_id,omitempty"`
+	// SessionID — сесія входу, до якої належить access токен.
+	SessionID string `json:"sid,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+
+	ID          string   `json:"id,omitempty"`
+	Login       string   `json:"login,omitempty"`
+	IsAdmin     bool     `json:"is_admin,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+
+	// Actor — хто діє від імені користувача токена імперсонації (RFC 8693).
+	Actor *token.Actor `json:"act,omitempty"`
+	// TenantID — організація, в якій діє токен.
+	TenantID   string `json:"tenant_id,omitempty"`
+	TenantRole string `json:"tenant_role,omitempty" example:"editor"`
+}
+
+func introspectAccessToken(claims *token.UserClaims) IntrospectionResponse {
+	resp := IntrospectionResponse{
+		Active:      true,
+		TokenType:   TokenTypeAccess,
+		Scope:       strings.Join(claims.Permissions, " "),
+		Username:    claims.Login,
+		ClientID:    claims.ClientID,
+		APIKeyID:    claims.APIKeyID,
+		SessionID:   claims.SessionID,
+		Subject:     claims.Subject,
+		TokenID:     claims.RegisteredClaims.ID,
+		ID:          claims.ID,
+		Login:       claims.Login,
+		IsAdmin:     claims.HasRole(models.RoleAdmin),
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		Actor:       claims.Actor,
+		TenantID:    claims.TenantID,
+		TenantRole:  claims.TenantRole,
+	}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+	return resp
+}
+
+// introspectRefreshToken повертає відповідь для чинного refresh токена або
+// false, якщо такого немає.
+func introspectRefreshToken(r *http.Request, raw string) (IntrospectionResponse, bool) {
+	ctx := r.Context()
+	rt, err := db.NewRefreshTokenRepository(db.DB).GetRefreshTokenByHash(ctx, token.HashRefreshToken(raw))
+	if err != nil || rt.UsedAt != nil || rt.RevokedAt != nil || !rt.ExpiresAt.After(time.Now()) {
+		return IntrospectionResponse{}, false
+	}
+	user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, rt.UserID)
+	if err != nil || user.Status != models.UserStatusActive {
+		return IntrospectionResponse{}, false
+	}
+
+	return IntrospectionResponse{
+		Active:    true,
+		TokenType: TokenTypeRefresh,
+		Username:  user.Login,
+		Subject:   user.Login,
+		Exp:       rt.ExpiresAt.Unix(),
+		Iat:       rt.CreatedAt.Unix(),
+		ID:        user.ID,
+		Login:     user.Login,
+	}, true
+}
+
+// NewIntrospectHandler godoc
+// @Summary Інтроспекція токена
+// @Description Повертає стан і дані access токена, refresh токена або API ключа за RFC 7662. Доступно лише OAuth клієнтам зі scope tokens:introspect, що автентифікуються секретом (HTTP Basic або client_id і client_secret у формі).
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Токен для перевірки"
+// @Param token_type_hint formData string false "access_token або refresh_token"
+// @Success 200 {object} IntrospectionResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Невірні облікові дані клієнта"
+// @Failure 403 {object} models.ErrorResponse "Клієнт не має scope tokens:introspect"
+// @Security BasicAuth
+// @Router /oauth/introspect [post]
+func NewIntrospectHandler(jwtMaker *token.JWTMaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+
+		client, ok := authenticateClient(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="introspect"`)
+			writeError(w, http.StatusUnauthorized, "invalid_client")
+			return
+		}
+		if !slices.Contains(client.Scopes, models.PermissionTokensIntrospect) {
+			writeError(w, http.StatusForbidden, "unauthorized_client")
+			return
+		}
+
+		raw := r.PostForm.Get("token")
+		if raw == "" {
+			writeError(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+
+		if _, ok := token.APIKeyPrefix(raw); ok {
+			claims, err := verifyAPIKey(r.Context(), raw)
+			if errors.Is(err, errAuthFailure) {
+				log.Println("Introspect error:", err)
+				writeError(w, http.StatusInternalServerError, "server_error")
+				return
+			}
+			if err == nil {
+				resp := introspectAccessToken(claims)
+				resp.TokenType = TokenTypeAPIKey
+				writeJSON(w, http.StatusOK, resp)
+				return
+			}
+			writeJSON(w, http.StatusOK, IntrospectionResponse{Active: false})
+			return
+		}
+
+		// token_type_hint лише підказка (RFC 7662, розділ 2.1): перевіряються
+		// обидва типи токенів.
+		if claims, err := jwtMaker.VerifyToken(raw); err == nil {
+			touchSession(r.Context(), claims)
+			writeJSON(w, http.StatusOK, introspectAccessToken(claims))
+			return
+		}
+		if resp, ok := introspectRefreshToken(r, raw); ok {
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, IntrospectionResponse{Active: false})
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+// NewJWKSHandler godoc
+// @Summary Публічні ключі підпису (JWKS)
+// @Description Повертає публічні ключі для локальної перевірки JWT іншими сервісами. При підписі спільним секретом (HS256) список порожній.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} token.JWKS
+// @Router /.well-known/jwks.json [get]
+func NewJWKSHandler(jwtMaker *token.JWTMaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		writeJSON(w, http.StatusOK, jwtMaker.JWKS())
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/keys"
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+type SigningKeyResponse struct {
+	ID          string     `json:"id"`
+	Algorithm   string     `json:"algorithm"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+	RetiresAt   *time.Time `json:"retires_at,omitempty"`
+}
+
+type GenerateKeyRequest struct {
+	Algorithm string `json:"algorithm" example:"ES256"`
+	Activate  bool   `json:"activate"`
+}
+
+type PromoteKeyRequest struct {
+	RetirePreviousAt *time.Time `json:"retire_previous_at,omitempty"`
+}
+
+type RetireKeyRequest struct {
+	RetiresAt *time.Time `json:"retires_at,omitempty"`
+}
+
+func newSigningKeyResponse(sk *models.SigningKey) SigningKeyResponse {
+	return SigningKeyResponse{
+		ID:          sk.ID,
+		Algorithm:   sk.Algorithm,
+		Status:      sk.Status,
+		CreatedAt:   sk.CreatedAt,
+		ActivatedAt: sk.ActivatedAt,
+		RetiresAt:   sk.RetiresAt,
+	}
+}
+
+// decodeOptionalJSON декодує тіло запиту, якщо воно є.
+func decodeOptionalJSON(r *http.Request, v any) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func writeKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "Signing key not found")
+	case errors.Is(err, keys.ErrActiveKey), errors.Is(err, keys.ErrRetired), errors.Is(err, keys.ErrRetiring):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Println("Signing key error:", err)
+		writeError(w, http.StatusInternalServerError, "Failed to update signing keys")
+	}
+}
+
+// NewListKeysHandler godoc
+// @Summary Список ключів підпису
+// @Description Повертає метадані ключів підпису JWT без приватної частини (потрібен дозвіл keys:manage)
+// @Tags keys
+// @Produce json
+// @Success 200 {array} SigningKeyResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/keys [get]
+func NewListKeysHandler(manager *keys.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stored, err := manager.List(r.Context())
+		if err != nil {
+			writeKeyError(w, err)
+			return
+		}
+
+		resp := make([]SigningKeyResponse, 0, len(stored))
+		for i := range stored {
+			resp = append(resp, newSigningKeyResponse(&stored[i]))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewGenerateKeyHandler godoc
+// @Summary Генерація ключа підпису
+// @Description Створює новий ключ (HS256, RS256, ES256 або EdDSA). Без activate ключ лише публікується в JWKS і чекає на промоцію (потрібен дозвіл keys:manage).
+// @Tags keys
+// @Accept json
+// @Produce json
+// @Param request body GenerateKeyRequest true "Алгоритм ключа"
+// @Success 201 {object} SigningKeyResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/keys [post]
+func NewGenerateKeyHandler(manager *keys.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		switch req.Algorithm {
+		case "HS256", "RS256", "ES256", "EdDSA":
+		default:
+			writeError(w, http.StatusBadRequest, "Algorithm must be one of HS256, RS256, ES256, EdDSA")
+			return
+		}
+
+		sk, err := manager.Generate(r.Context(), req.Algorithm, req.Activate)
+		if err != nil {
+			writeKeyError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, newSigningKeyResponse(sk))
+	}
+}
+
+// NewPromoteKeyHandler godoc
+// @Summary Активація ключа підпису
+// @Description Робить ключ активним для підпису. Попередній активний ключ лишається дійсним для перевірки до retire_previous_at. Ключ, якому вже призначено дату виведення, активувати не можна (потрібен дозвіл keys:manage).
+// @Tags keys
+// @Accept json
+// @Produce json
+// @Param kid path string true "Ідентифікатор ключа"
+// @Param request body PromoteKeyRequest false "Дата виведення попереднього ключа"
+// @Success 200 {object} SigningKeyResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Ключ не знайдено"
+// @Failure 409 {object} models.ErrorResponse "Ключ виведено або призначено до виведення з обігу"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/keys/{kid}/promote [post]
+func NewPromoteKeyHandler(manager *keys.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PromoteKeyRequest
+		if err := decodeOptionalJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		sk, err := manager.Promote(r.Context(), r.PathValue("kid"), req.RetirePreviousAt)
+		if err != nil {
+			writeKeyError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newSigningKeyResponse(sk))
+	}
+}
+
+// NewRetireKeyHandler godoc
+// @Summary Виведення ключа підпису з обігу
+// @Description Призначає дату, після якої токени з цим kid не приймаються (за замовчуванням — негайно). Активний ключ вивести не можна (потрібен дозвіл keys:manage).
+// @Tags keys
+// @Accept json
+// @Produce json
+// @Param kid path string true "Ідентифікатор ключа"
+// @Param request body RetireKeyRequest false "Дата виведення"
+// @Success 200 {object} SigningKeyResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Ключ не знайдено"
+// @Failure 409 {object} models.ErrorResponse "Ключ активний"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/keys/{kid}/retire [post]
+func NewRetireKeyHandler(manager *keys.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RetireKeyRequest
+		if err := decodeOptionalJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		sk, err := manager.Retire(r.Context(), r.PathValue("kid"), req.RetiresAt)
+		if err != nil {
+			writeKeyError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newSigningKeyResponse(sk))
+	}
+}
@@ -0,0 +1,566 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/lockout"
+	"ksv/rest-mikroservice/auth-service/mfa"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+type MFASetupResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/rest-mikroservice:admin?algorithm=SHA1&digits=6&issuer=rest-mikroservice&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
+
+type MFAChallengeResponse struct {
+	MFAToken  string    `json:"mfa_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Enrollment заповнений, якщо роль користувача вимагає MFA, а його ще не
+	// налаштовано: перший код з цього секрету одночасно вмикає MFA.
+	Enrollment *MFASetupResponse `json:"enrollment,omitempty"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code" example:"123456"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type MFADisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" example:"123456"`
+}
+
+type MFAStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// startMFAChallenge повертає challenge, якщо для входу user потрібен другий
+// фактор, або nil.
+func startMFAChallenge(ctx context.Context, cfg mfa.Config, user *models.User) (*MFAChallengeResponse, error) {
+	repo := db.NewMFARepository(db.DB)
+	userMFA, err := repo.GetUserMFA(ctx, user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var enrollment *MFASetupResponse
+	if userMFA == nil || !userMFA.Enabled {
+		required, err := db.NewRoleRepository(db.DB).UserRequiresMFA(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+		enrollment, err = setupMFA(ctx, cfg, user.ID, user.Login)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	raw, hash, err := mfa.NewChallengeToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	challenge := &models.MFAChallenge{
+		ID:        hash,
+		UserID:    user.ID,
+		ExpiresAt: now.Add(cfg.ChallengeTTL),
+		CreatedAt: now,
+	}
+	if err := repo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return &MFAChallengeResponse{
+		MFAToken:   raw,
+		ExpiresAt:  challenge.ExpiresAt,
+		Enrollment: enrollment,
+	}, nil
+}
+
+// setupMFA створює новий секрет, що чекає підтвердження першим кодом.
+func setupMFA(ctx context.Context, cfg mfa.Config, userID string, login string) (*MFASetupResponse, error) {
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.NewMFARepository(db.DB).SavePendingMFA(ctx, userID, secret, time.Now()); err != nil {
+		return nil, err
+	}
+	return &MFASetupResponse{
+		Secret:     secret,
+		OTPAuthURI: mfa.KeyURI(cfg.Issuer, login, secret),
+	}, nil
+}
+
+// checkMFACode перевіряє код TOTP або код відновлення і позначає його
+// використаним.
+func checkMFACode(ctx context.Context, userMFA *models.UserMFA, code string, now time.Time) (bool, error) {
+	repo := db.NewMFARepository(db.DB)
+	if !mfa.IsTOTPCode(code) {
+		return repo.UseRecoveryCode(ctx, userMFA.UserID, mfa.HashRecoveryCode(code), now)
+	}
+	step, ok := mfa.Validate(userMFA.Secret, code, now)
+	if !ok {
+		return false, nil
+	}
+	return repo.UseStep(ctx, userMFA.UserID, step)
+}
+
+// enableMFA підтверджує очікуваний секрет кодом TOTP і повертає нові коди
+// відновлення.
+func enableMFA(ctx context.Context, userMFA *models.UserMFA, code string, now time.Time) ([]string, bool, error) {
+	step, ok := mfa.Validate(userMFA.Secret, code, now)
+	if !ok {
+		return nil, false, nil
+	}
+	codes, hashes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := db.NewMFARepository(db.DB).EnableMFA(ctx, userMFA.UserID, step, hashes, now); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
+
+// guardedCheck перевіряє облікові дані користувача з login так само, як
+// вхід: з урахуванням затримок і блокування після невдач. Якщо check
+// повертає false, невдача рахується і клієнт отримує 401 з message.
+func guardedCheck(w http.ResponseWriter, r *http.Request, guard *lockout.Guard, proxies utils.TrustedProxies, login string, message string, check func() (bool, error)) bool {
+	block, ok, err := checkGuarded(r.Context(), guard, proxies.ClientIP(r), login, check)
+	switch {
+	case err != nil:
+		log.Println("Credentials check error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+	case block != nil:
+		writeBlocked(w, block)
+	case !ok:
+		writeError(w, http.StatusUnauthorized, message)
+	}
+	return ok
+}
+
+// checkGuarded виконує check, якщо login і ip не заблоковані, і враховує
+// результат у лічильниках невдалих входів. Повертає block, якщо перевірку
+// не виконано через блокування.
+func checkGuarded(ctx context.Context, guard *lockout.Guard, ip string, login string, check func() (bool, error)) (*lockout.Block, bool, error) {
+	block, err := guard.Reserve(ctx, login, ip, time.Now())
+	if err != nil || block != nil {
+		return block, false, err
+	}
+
+	ok, err := check()
+	if err != nil {
+		if err := guard.Release(ctx, login, ip); err != nil {
+			log.Println("Release login attempt error:", err)
+		}
+		return nil, false, err
+	}
+	if !ok {
+		log.Printf("Failed credentials check for %q from %s", login, ip)
+		return nil, false, nil
+	}
+
+	if err := guard.Success(ctx, login, ip); err != nil {
+		log.Println("Reset login attempts error:", err)
+	}
+	return nil, true, nil
+}
+
+// attemptMFAChallenge враховує спробу за токеном другого кроку і повертає
+// challenge разом з користувачем і його MFA. false — токен недійсний,
+// прострочений або спроби з ним вичерпано.
+func attemptMFAChallenge(ctx context.Context, cfg mfa.Config, rawToken string, now time.Time) (*models.MFAChallenge, *models.User, *models.UserMFA, bool) {
+	repo := db.NewMFARepository(db.DB)
+	challenge, err := repo.AttemptChallenge(ctx, mfa.HashChallengeToken(rawToken), now)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Println("MFA challenge error:", err)
+		}
+		return nil, nil, nil, false
+	}
+	if challenge.Attempts > cfg.MaxAttempts {
+		if err := repo.DeleteChallenge(ctx, challenge.ID); err != nil {
+			log.Println("MFA challenge error:", err)
+		}
+		return nil, nil, nil, false
+	}
+
+	user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, nil, false
+	}
+	userMFA, err := repo.GetUserMFA(ctx, user.ID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Println("MFA challenge error:", err)
+		}
+		return nil, nil, nil, false
+	}
+	return challenge, user, userMFA, true
+}
+
+// NewMFAVerifyHandler godoc
+// @Summary Другий крок входу
+// @Description Обмінює токен з відповіді 202 на POST /api/auth і код TOTP (або одноразовий код відновлення) на JWT токен і refresh токен. Якщо вхід завершує налаштування MFA, у відповіді є коди відновлення — вони показуються лише один раз.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body MFAVerifyRequest true "Токен другого кроку і код"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Недійсний токен або код"
+// @Failure 403 {object} models.ErrorResponse "Обліковий запис не активний"
+// @Failure 423 {object} models.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} models.ErrorResponse "Забагато спроб (див. Retry-After)"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Router /api/auth/mfa/verify [post]
+func NewMFAVerifyHandler(jwtMaker *token.JWTMaker, cfg mfa.Config, guard *lockout.Guard, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MFAVerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		ctx := r.Context()
+		now := time.Now()
+		challenge, user, userMFA, ok := attemptMFAChallenge(ctx, cfg, req.MFAToken, now)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+			return
+		}
+
+		var recoveryCodes []string
+		if !guardedCheck(w, r, guard, proxies, user.Login, "Invalid MFA code", func() (bool, error) {
+			var ok bool
+			var err error
+			if userMFA.Enabled {
+				ok, err = checkMFACode(ctx, userMFA, req.Code, now)
+			} else {
+				recoveryCodes, ok, err = enableMFA(ctx, userMFA, req.Code, now)
+			}
+			if err == nil && !ok {
+				recordAudit(r, proxies, loginFailureEvent(user.Login, user, "invalid MFA code"))
+			}
+			return ok, err
+		}) {
+			return
+		}
+
+		if err := db.NewMFARepository(db.DB).DeleteChallenge(ctx, challenge.ID); err != nil {
+			log.Println("MFA challenge error:", err)
+		}
+		if !checkUserStatus(w, user) {
+			return
+		}
+
+		resp, err := issueTokens(ctx, jwtMaker, user, "", newRequestOrigin(r, proxies))
+		if err != nil {
+			log.Println("Issue tokens error:", err)
+			writeError(w, http.StatusInternalServerError, "Failed to create token")
+			return
+		}
+		resp.RecoveryCodes = recoveryCodes
+		event := userAuditEvent(models.AuditLoginSuccess, user)
+		event.Details = "mfa"
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewMFAStatusHandler godoc
+// @Summary Стан MFA
+// @Description Показує, чи ввімкнено MFA для поточного користувача, чи вимагає його роль і скільки лишилось кодів відновлення
+// @Tags mfa
+// @Produce json
+// @Success 200 {object} MFAStatusResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/mfa [get]
+func NewMFAStatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+		ctx := r.Context()
+
+		var resp MFAStatusResponse
+		userMFA, err := db.NewMFARepository(db.DB).GetUserMFA(ctx, claims.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Println("MFA status error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		resp.Enabled = userMFA != nil && userMFA.Enabled
+
+		resp.Required, err = db.NewRoleRepository(db.DB).UserRequiresMFA(ctx, claims.ID)
+		if err != nil {
+			log.Println("MFA status error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if resp.Enabled {
+			resp.RecoveryCodesLeft, err = db.NewMFARepository(db.DB).CountRecoveryCodes(ctx, claims.ID)
+			if err != nil {
+				log.Println("MFA status error:", err)
+				writeError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewMFASetupHandler godoc
+// @Summary Початок налаштування MFA
+// @Description Створює новий секрет TOTP для поточного користувача. otpauth_uri можна показати як QR код. MFA вмикається після підтвердження кодом через POST /api/auth/mfa/enable.
+// @Tags mfa
+// @Produce json
+// @Success 200 {object} MFASetupResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 409 {object} models.ErrorResponse "MFA уже ввімкнено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/mfa/setup [post]
+func NewMFASetupHandler(cfg mfa.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		resp, err := setupMFA(r.Context(), cfg, claims.ID, claims.Login)
+		if err != nil {
+			if errors.Is(err, db.ErrMFAEnabled) {
+				writeError(w, http.StatusConflict, "MFA is already enabled")
+				return
+			}
+			log.Println("MFA setup error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewMFAEnableHandler godoc
+// @Summary Увімкнення MFA
+// @Description Підтверджує секрет з POST /api/auth/mfa/setup кодом TOTP і вмикає MFA. Повертає коди відновлення — вони показуються лише один раз.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body MFACodeRequest true "Код TOTP"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит, невірний код або налаштування не розпочато"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 409 {object} models.ErrorResponse "MFA уже ввімкнено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/mfa/enable [post]
+func NewMFAEnableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		var req MFACodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		ctx := r.Context()
+		userMFA, err := db.NewMFARepository(db.DB).GetUserMFA(ctx, claims.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusBadRequest, "MFA setup has not been started")
+			return
+		}
+		if err != nil {
+			log.Println("MFA enable error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if userMFA.Enabled {
+			writeError(w, http.StatusConflict, "MFA is already enabled")
+			return
+		}
+
+		codes, ok, err := enableMFA(ctx, userMFA, req.Code, time.Now())
+		if err != nil {
+			if errors.Is(err, db.ErrMFAEnabled) {
+				writeError(w, http.StatusConflict, "MFA is already enabled")
+				return
+			}
+			log.Println("MFA enable error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Invalid MFA code")
+			return
+		}
+
+		log.Printf("MFA enabled for user %s", claims.ID)
+		writeJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// loadEnabledMFA повертає MFA поточного користувача або пише 400, якщо його
+// не ввімкнено.
+func loadEnabledMFA(w http.ResponseWriter, r *http.Request, userID string) (*models.UserMFA, bool) {
+	userMFA, err := db.NewMFARepository(db.DB).GetUserMFA(r.Context(), userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println("Get user mfa error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return nil, false
+	}
+	if userMFA == nil || !userMFA.Enabled {
+		writeError(w, http.StatusBadRequest, "MFA is not enabled")
+		return nil, false
+	}
+	return userMFA, true
+}
+
+// NewMFADisableHandler godoc
+// @Summary Вимкнення MFA
+// @Description Вимикає MFA поточного користувача. Потрібні пароль і код TOTP або код відновлення. Недоступно, якщо MFA вимагає роль користувача.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body MFADisableRequest true "Пароль і код"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або MFA не ввімкнено"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано, невірний пароль або код"
+// @Failure 403 {object} models.ErrorResponse "MFA вимагає роль користувача, або це не JWT самого користувача"
+// @Failure 423 {object} models.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} models.ErrorResponse "Забагато спроб (див. Retry-After)"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/mfa/disable [post]
+func NewMFADisableHandler(guard *lockout.Guard, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		var req MFADisableRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		ctx := r.Context()
+		required, err := db.NewRoleRepository(db.DB).UserRequiresMFA(ctx, claims.ID)
+		if err != nil {
+			log.Println("MFA disable error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if required {
+			writeError(w, http.StatusForbidden, "MFA is required for your role")
+			return
+		}
+
+		userMFA, ok := loadEnabledMFA(w, r, claims.ID)
+		if !ok {
+			return
+		}
+		user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, claims.ID)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+
+		if !guardedCheck(w, r, guard, proxies, user.Login, "Invalid password or MFA code", func() (bool, error) {
+			if utils.CheckPassword(req.Password, user.Password) != nil {
+				return false, nil
+			}
+			return checkMFACode(ctx, userMFA, req.Code, time.Now())
+		}) {
+			return
+		}
+
+		if err := db.NewMFARepository(db.DB).DeleteUserMFA(ctx, user.ID); err != nil {
+			log.Println("MFA disable error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		log.Printf("MFA disabled for user %s", user.ID)
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "MFA disabled"})
+	}
+}
+
+// NewRegenerateRecoveryCodesHandler godoc
+// @Summary Нові коди відновлення
+// @Description Замінює всі коди відновлення поточного користувача новими. Потрібен код TOTP або код відновлення.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body MFACodeRequest true "Код"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або MFA не ввімкнено"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано або невірний код"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 423 {object} models.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} models.ErrorResponse "Забагато спроб (див. Retry-After)"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/mfa/recovery-codes [post]
+func NewRegenerateRecoveryCodesHandler(guard *lockout.Guard, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		var req MFACodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		ctx := r.Context()
+		userMFA, ok := loadEnabledMFA(w, r, claims.ID)
+		if !ok {
+			return
+		}
+		if !guardedCheck(w, r, guard, proxies, claims.Login, "Invalid MFA code", func() (bool, error) {
+			return checkMFACode(ctx, userMFA, req.Code, time.Now())
+		}) {
+			return
+		}
+
+		codes, hashes, err := mfa.GenerateRecoveryCodes()
+		if err != nil {
+			log.Println("Recovery codes error:", err)
+			writeError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+			return
+		}
+		if err := db.NewMFARepository(db.DB).ReplaceRecoveryCodes(ctx, claims.ID, hashes); err != nil {
+			log.Println("Recovery codes error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+var (
+	errInvalidToken  = errors.New("Invalid token")
+	errInvalidAPIKey = errors.New("Invalid API key")
+	// errAuthFailure — облікові дані не вдалося перевірити (наприклад, через
+	// помилку БД); на неї відповідаємо 500, а не 401.
+	errAuthFailure = errors.New("failed to check credentials")
+)
+
+func bearerToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", errors.New("Authorization header is required")
+	}
+
+	headerParts := strings.Split(authHeader, " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return "", errors.New("Invalid authorization format")
+	}
+
+	return headerParts[1], nil
+}
+
+// apiKeyFromRequest повертає API ключ із заголовка Authorization: ApiKey
+// або X-API-Key.
+func apiKeyFromRequest(r *http.Request) (string, bool) {
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok && key != "" {
+		return key, true
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, true
+	}
+	return "", false
+}
+
+// authenticate перевіряє Bearer токен або API ключ запиту.
+func authenticate(r *http.Request, jwtMaker *token.JWTMaker) (*token.UserClaims, error) {
+	if key, ok := apiKeyFromRequest(r); ok {
+		return verifyAPIKey(r.Context(), key)
+	}
+
+	tokenStr, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := jwtMaker.VerifyToken(tokenStr)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAuthFailure) {
+		log.Println("Authentication error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	writeError(w, http.StatusUnauthorized, err.Error())
+}
+
+func claimsFromContext(ctx context.Context) *token.UserClaims {
+	claims, _ := ctx.Value(claimsContextKey).(*token.UserClaims)
+	return claims
+}
+
+// RequireAuth перевіряє Bearer токен або API ключ і кладе claims у контекст запиту.
+func RequireAuth(jwtMaker *token.JWTMaker, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := authenticate(r, jwtMaker)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, claims)))
+	}
+}
+
+// RequireUser пропускає лише JWT користувачів: ендпоінти облікового запису
+// (пароль, MFA, API ключі) не мають сенсу для токенів клієнтів, а API ключ
+// не повинен давати змогу випустити новий ключ чи змінити пароль.
+func RequireUser(jwtMaker *token.JWTMaker, next http.HandlerFunc) http.HandlerFunc {
+	return RequireAuth(jwtMaker, func(w http.ResponseWriter, r *http.Request) {
+		if claims := claimsFromContext(r.Context()); claims == nil || claims.IsClient() || claims.IsAPIKey() {
+			writeError(w, http.StatusForbidden, "User token required")
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// RequireAccountOwner пропускає лише JWT самого користувача: токеном
+// імперсонації не можна змінювати пароль, MFA, API ключі чи сесії.
+func RequireAccountOwner(jwtMaker *token.JWTMaker, next http.HandlerFunc) http.HandlerFunc {
+	return RequireUser(jwtMaker, func(w http.ResponseWriter, r *http.Request) {
+		if claimsFromContext(r.Context()).IsImpersonated() {
+			writeError(w, http.StatusForbidden, "Not allowed while impersonating")
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// RequirePermission пропускає лише запити з токеном, що має дозвіл permission.
+func RequirePermission(jwtMaker *token.JWTMaker, permission string, next http.HandlerFunc) http.HandlerFunc {
+	return RequireAuth(jwtMaker, func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+		if claims == nil || !claims.HasPermission(permission) {
+			writeError(w, http.StatusForbidden, "Missing permission: "+permission)
+			return
+		}
+
+		next(w, r)
+	})
+}
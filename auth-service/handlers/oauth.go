@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/oidc"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+const (
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeAuthorizationCode = "authorization_code"
+)
+
+// TokenResponse — відповідь ендпоінта токенів за RFC 6749, розділ 5.1.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	// ExpiresIn — строк дії токена в секундах.
+	ExpiresIn int64 `json:"expires_in" example:"900"`
+	// Scope — видані дозволи (client_credentials) або scopes OpenID Connect
+	// (authorization_code) через пробіл.
+	Scope string `json:"scope" example:"product:read"`
+	// RefreshToken і IDToken видаються лише за grant authorization_code;
+	// IDToken — якщо запитано scope openid.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// authenticateClient перевіряє облікові дані OAuth клієнта з заголовка
+// Authorization: Basic або з полів форми client_id і client_secret.
+func authenticateClient(r *http.Request) (*models.Client, bool) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if id == "" || secret == "" {
+		return nil, false
+	}
+
+	client, err := db.NewClientRepository(db.DB).GetClient(r.Context(), id)
+	if err != nil || client.SecretHash == "" {
+		return nil, false
+	}
+	hash := token.HashClientSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 {
+		return nil, false
+	}
+	return client, true
+}
+
+// tokenClient визначає клієнта запиту до ендпоінта токенів: конфіденційний
+// клієнт автентифікується секретом, публічний передає лише client_id.
+func tokenClient(r *http.Request) (*models.Client, bool) {
+	if _, _, ok := r.BasicAuth(); ok || r.PostFormValue("client_secret") != "" {
+		return authenticateClient(r)
+	}
+	id := r.PostFormValue("client_id")
+	if id == "" {
+		return nil, false
+	}
+	client, err := db.NewClientRepository(db.DB).GetClient(r.Context(), id)
+	if err != nil || !client.Public {
+		return nil, false
+	}
+	return client, true
+}
+
+// requestedScopes повертає запитані scopes або всі scopes клієнта, якщо
+// параметр scope не задано. false — клієнту дозволено не все запитане.
+func requestedScopes(client *models.Client, scope string) ([]string, bool) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return client.Scopes, true
+	}
+
+	scopes := []string{}
+	for _, s := range requested {
+		if !slices.Contains(client.Scopes, s) {
+			return nil, false
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, true
+}
+
+// NewTokenHandler godoc
+// @Summary Видача токенів OAuth
+// @Description Ендпоінт токенів OAuth 2.0. Grant client_credentials (RFC 6749, розділ 4.4) видає access токен сервісу: клієнт передає id і секрет через HTTP Basic або client_id і client_secret у формі, а токен містить лише дозволені клієнту scopes. Grant authorization_code (розділ 4.1) обмінює код з /oauth/authorize і code_verifier PKCE на access і refresh токени користувача, а зі scope openid — ще й ID токен. Публічний клієнт передає лише client_id.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "client_credentials або authorization_code"
+// @Param scope formData string false "client_credentials: потрібні дозволи через пробіл; за замовчуванням — усі дозволені клієнту"
+// @Param code formData string false "authorization_code: код з /oauth/authorize"
+// @Param redirect_uri formData string false "authorization_code: той самий redirect_uri, що в запиті авторизації"
+// @Param code_verifier formData string false "authorization_code: PKCE code_verifier"
+// @Param client_id formData string false "ID клієнта, якщо не використовується HTTP Basic"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} models.ErrorResponse "invalid_request, invalid_grant, unauthorized_client, unsupported_grant_type або invalid_scope"
+// @Failure 401 {object} models.ErrorResponse "invalid_client"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BasicAuth
+// @Router /oauth/token [post]
+func NewTokenHandler(jwtMaker *token.JWTMaker, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		client, ok := tokenClient(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+			writeError(w, http.StatusUnauthorized, "invalid_client")
+			return
+		}
+
+		switch r.PostForm.Get("grant_type") {
+		case grantTypeClientCredentials:
+			clientCredentialsGrant(w, r, jwtMaker, client)
+		case grantTypeAuthorizationCode:
+			authorizationCodeGrant(w, r, jwtMaker, proxies, client)
+		case "":
+			writeError(w, http.StatusBadRequest, "invalid_request")
+		default:
+			writeError(w, http.StatusBadRequest, "unsupported_grant_type")
+		}
+	}
+}
+
+func clientCredentialsGrant(w http.ResponseWriter, r *http.Request, jwtMaker *token.JWTMaker, client *models.Client) {
+	if client.Public {
+		writeError(w, http.StatusBadRequest, "unauthorized_client")
+		return
+	}
+
+	scopes, ok := requestedScopes(client, r.PostForm.Get("scope"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_scope")
+		return
+	}
+
+	duration := accessTokenDuration()
+	tokenString, _, err := jwtMaker.CreateClientToken(client.ID, scopes, duration)
+	if err != nil {
+		log.Println("Client token error:", err)
+		writeError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TokenResponse{
+		AccessToken: tokenString,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(duration.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	})
+}
+
+// authorizationCodeGrant обмінює код авторизації на токени користувача. Код
+// одноразовий: він видаляється ще до перевірки client_id, redirect_uri і PKCE.
+func authorizationCodeGrant(w http.ResponseWriter, r *http.Request, jwtMaker *token.JWTMaker, proxies utils.TrustedProxies, client *models.Client) {
+	rawCode := r.PostForm.Get("code")
+	redirectURI := r.PostForm.Get("redirect_uri")
+	verifier := r.PostForm.Get("code_verifier")
+	if rawCode == "" || redirectURI == "" || verifier == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	ctx := r.Context()
+	code, err := db.NewAuthorizationCodeRepository(db.DB).ConsumeAuthorizationCode(ctx, oidc.HashAuthorizationCode(rawCode), time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if err != nil {
+		log.Println("Authorization code error:", err)
+		writeError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	if code.ClientID != client.ID || code.RedirectURI != redirectURI || !oidc.VerifyCodeChallenge(verifier, code.CodeChallenge) {
+		log.Printf("Rejected authorization code for client %s", client.ID)
+		writeError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, code.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if err != nil {
+		log.Println("Authorization code error:", err)
+		writeError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	if user.Status != models.UserStatusActive {
+		writeError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	auth, err := issueTokens(ctx, jwtMaker, user, "", newRequestOrigin(r, proxies))
+	if err != nil {
+		log.Println("Issue tokens error:", err)
+		writeError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	duration := accessTokenDuration()
+	resp := TokenResponse{
+		AccessToken:  auth.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(duration.Seconds()),
+		Scope:        code.Scope,
+		RefreshToken: auth.RefreshToken,
+	}
+
+	scopes := strings.Fields(code.Scope)
+	if slices.Contains(scopes, oidc.ScopeOpenID) {
+		login := ""
+		if slices.Contains(scopes, oidc.ScopeProfile) {
+			login = user.Login
+		}
+		resp.IDToken, err = jwtMaker.CreateIDToken(user.ID, login, client.ID, code.Nonce, code.AuthTime, duration)
+		if err != nil {
+			log.Println("ID token error:", err)
+			writeError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
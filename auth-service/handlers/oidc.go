@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/oidc"
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+// UserinfoResponse — відповідь /userinfo: стандартні claims OpenID Connect
+// разом з даними UserResponse.
+type UserinfoResponse struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username"`
+	UserResponse
+}
+
+// NewDiscoveryHandler godoc
+// @Summary OpenID Connect discovery
+// @Description Повертає метадані провайдера: адреси ендпоінтів, підтримувані grant, scopes, методи PKCE і алгоритми підпису ID токенів
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} oidc.Discovery
+// @Router /.well-known/openid-configuration [get]
+func NewDiscoveryHandler(jwtMaker *token.JWTMaker, cfg oidc.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		writeJSON(w, http.StatusOK, oidc.NewDiscovery(cfg.Issuer, jwtMaker.SigningAlgorithms()))
+	}
+}
+
+// NewUserinfoHandler godoc
+// @Summary Дані користувача (OpenID Connect)
+// @Description Повертає дані власника access токена: sub — ID користувача, логін, email і ролі. Ролі читаються з БД, тож відображають поточний стан.
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} UserinfoResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT користувача"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /userinfo [get]
+// @Router /userinfo [post]
+func NewUserinfoHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+		ctx := r.Context()
+
+		user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, claims.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusUnauthorized, "User not found")
+			return
+		}
+		if err != nil {
+			log.Println("Userinfo error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		roles, err := db.NewRoleRepository(db.DB).GetUserRoles(ctx, user.ID)
+		if err != nil {
+			log.Println("Userinfo error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, UserinfoResponse{
+			Subject:           user.ID,
+			PreferredUsername: user.Login,
+			UserResponse: UserResponse{
+				ID:     user.ID,
+				Login:  user.Login,
+				Email:  user.Email,
+				Status: user.Status,
+				Roles:  roles,
+			},
+		})
+	}
+}
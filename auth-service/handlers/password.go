@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/lockout"
+	"ksv/rest-mikroservice/auth-service/mail"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/passwords"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"user@example.com"`
+}
+
+type CompletePasswordResetRequest struct {
+	// Token — токен з листа скидання пароля.
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// NewChangePasswordHandler godoc
+// @Summary Зміна власного пароля
+// @Description Змінює пароль поточного користувача. Потрібен чинний пароль; новий має відповідати політиці паролів. Після зміни всі токени користувача відкликаються, тож потрібно увійти знову.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Поточний і новий паролі"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або пароль не відповідає політиці"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано або невірний поточний пароль"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 423 {object} models.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} models.ErrorResponse "Забагато спроб (див. Retry-After)"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/password [post]
+func NewChangePasswordHandler(policy *passwords.Policy, guard *lockout.Guard, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		var req ChangePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		ctx := r.Context()
+		user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, claims.ID)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+
+		// Поточний пароль можна підбирати так само, як при вході, тож невдачі
+		// рахуються тим самим лічильником.
+		if !guardedCheck(w, r, guard, proxies, user.Login, "Invalid current password", func() (bool, error) {
+			return utils.CheckPassword(req.CurrentPassword, user.Password) == nil, nil
+		}) {
+			return
+		}
+
+		if !checkNewPassword(w, ctx, policy, user, req.NewPassword) {
+			return
+		}
+		if err := setPassword(ctx, policy, user, req.NewPassword); err != nil {
+			log.Println("Change password error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		recordAudit(r, proxies, userAuditEvent(models.AuditPasswordChange, user))
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Password changed"})
+	}
+}
+
+// checkNewPassword перевіряє пароль політикою. Якщо пароль не підходить,
+// пише відповідь і повертає false.
+func checkNewPassword(w http.ResponseWriter, ctx context.Context, policy *passwords.Policy, user *models.User, password string) bool {
+	err := policy.Check(ctx, user, password)
+	if err == nil {
+		return true
+	}
+	var policyErr *passwords.PolicyError
+	if errors.As(err, &policyErr) {
+		writeError(w, http.StatusBadRequest, policyErr.Error())
+		return false
+	}
+	log.Println("Password policy check error:", err)
+	writeError(w, http.StatusInternalServerError, "Database error")
+	return false
+}
+
+// setPassword зберігає новий пароль існуючого користувача, додає його в
+// історію і відкликає всі токени користувача.
+func setPassword(ctx context.Context, policy *passwords.Policy, user *models.User, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+
+	if err := db.NewUserRepository(db.DB).UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if err := policy.Record(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+	return revokeAllUserTokens(ctx, user.ID)
+}
+
+// NewForgotPasswordHandler godoc
+// @Summary Запит на скидання пароля
+// @Description Надсилає на email користувача лист з одноразовим токеном скидання пароля. Відповідь однакова, чи існує користувач з таким email, чи ні. Новий лист скасовує токен з попереднього; лист надсилається не частіше ніж раз на PASSWORD_RESET_INTERVAL.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Email користувача"
+// @Success 202 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Router /api/auth/password/forgot [post]
+func NewForgotPasswordHandler(mailer mail.Mailer, cfg passwords.ResetConfig, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		email, ok := utils.NormalizeEmail(req.Email)
+		if !ok || email == "" {
+			writeError(w, http.StatusBadRequest, "Invalid email")
+			return
+		}
+
+		// Відповідь не залежить від того, чи знайдено користувача, щоб
+		// ендпоінтом не можна було перевіряти, які email зареєстровані.
+		accepted := models.SuccessResponse{Message: "If the email is registered, a password reset link has been sent"}
+
+		ctx := r.Context()
+		user, err := db.NewUserRepository(db.DB).GetUserByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusAccepted, accepted)
+				return
+			}
+			log.Println("Forgot password error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		// Вимкненому користувачу лист не надсилається: увійти він однаково
+		// не зможе.
+		if user.Status == models.UserStatusDisabled {
+			writeJSON(w, http.StatusAccepted, accepted)
+			return
+		}
+
+		sent, err := sendUserToken(ctx, mailer, user, models.UserTokenPasswordReset, cfg.TTL, cfg.Interval, func(raw string) (mail.Message, error) {
+			return passwordResetMessage(user, raw, cfg)
+		})
+		if err != nil {
+			log.Println("Forgot password error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !sent {
+			writeJSON(w, http.StatusAccepted, accepted)
+			return
+		}
+
+		recordAudit(r, proxies, userAuditEvent(models.AuditPasswordResetRequest, user))
+
+		writeJSON(w, http.StatusAccepted, accepted)
+	}
+}
+
+func passwordResetMessage(user *models.User, resetToken string, cfg passwords.ResetConfig) (mail.Message, error) {
+	link, err := cfg.Link(resetToken)
+	if err != nil {
+		return mail.Message{}, err
+	}
+	action := "Щоб встановити новий пароль, перейдіть за посиланням:\n" + link
+	if link == "" {
+		action = "Щоб встановити новий пароль, використайте токен скидання:\n" + resetToken
+	}
+
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Скидання пароля",
+		Body: fmt.Sprintf("Вітаємо, %s!\n\n"+
+			"Ми отримали запит на скидання пароля до вашого облікового запису.\n"+
+			"%s\n\n"+
+			"Токен дійсний %d хв. і може бути використаний лише один раз.\n"+
+			"Якщо ви не запитували скидання пароля, просто проігноруйте цей лист.\n",
+			user.Login, action, max(int(cfg.TTL.Minutes()), 1)),
+	}, nil
+}
+
+// NewCompletePasswordResetHandler godoc
+// @Summary Скидання пароля за токеном
+// @Description Встановлює новий пароль за одноразовим токеном з листа скидання пароля. Новий пароль має відповідати політиці паролів. Після скидання всі токени користувача відкликаються, а блокування входу знімається.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body CompletePasswordResetRequest true "Токен з листа і новий пароль"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит, недійсний або прострочений токен, пароль не відповідає політиці"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Router /api/auth/password/reset [post]
+func NewCompletePasswordResetHandler(policy *passwords.Policy, guard *lockout.Guard, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CompletePasswordResetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		ctx := r.Context()
+		tokens := db.NewUserTokenRepository(db.DB)
+		hash := token.HashUserToken(req.Token)
+		resetToken, err := tokens.GetUserToken(ctx, hash, models.UserTokenPasswordReset, time.Now())
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeError(w, http.StatusBadRequest, "Invalid or expired reset token")
+				return
+			}
+			log.Println("Reset password error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, resetToken.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeError(w, http.StatusBadRequest, "Invalid or expired reset token")
+				return
+			}
+			log.Println("Reset password error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		// Токен використовується лише після перевірки пароля політикою, щоб
+		// користувач міг виправити пароль і спробувати знову.
+		if !checkNewPassword(w, ctx, policy, user, req.Password) {
+			return
+		}
+		if _, err := tokens.ConsumeUserToken(ctx, hash, models.UserTokenPasswordReset, time.Now()); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeError(w, http.StatusBadRequest, "Invalid or expired reset token")
+				return
+			}
+			log.Println("Reset password error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if err := setPassword(ctx, policy, user, req.Password); err != nil {
+			log.Println("Reset password error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if err := guard.Unlock(ctx, user.Login); err != nil {
+			log.Println("Reset password error:", err)
+		}
+
+		event := userAuditEvent(models.AuditPasswordReset, user)
+		event.Details = "reset token"
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Password updated"})
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, models.ErrorResponse{Error: message})
+}
+
+// writeErrorCode пише помилку з машинним кодом, за яким клієнт розрізняє
+// причини відмови з однаковим статусом.
+func writeErrorCode(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, models.ErrorResponse{Error: message, Code: code})
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+)
+
+var (
+	rolePattern       = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+	permissionPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*(:[a-z0-9_-]+)+$`)
+)
+
+type RoleResponse struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	RequireMFA  bool      `json:"require_mfa"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" example:"catalog_editor"`
+	Description string   `json:"description"`
+	RequireMFA  bool     `json:"require_mfa"`
+	Permissions []string `json:"permissions" example:"product:read,product:write"`
+}
+
+type UpdateRoleRequest struct {
+	Description string   `json:"description"`
+	RequireMFA  bool     `json:"require_mfa"`
+	Permissions []string `json:"permissions"`
+}
+
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CreatePermissionRequest struct {
+	Name        string `json:"name" example:"inventory:write"`
+	Description string `json:"description"`
+}
+
+func newRoleResponse(role *models.Role) RoleResponse {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return RoleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		RequireMFA:  role.RequireMFA,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+	}
+}
+
+func writeRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "Role not found")
+	case errors.Is(err, db.ErrUnknownPermission):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, db.ErrRoleExists), errors.Is(err, db.ErrPermissionExists):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Println("Role error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+	}
+}
+
+// NewListRolesHandler godoc
+// @Summary Список ролей
+// @Description Повертає ролі разом з їх дозволами (потрібен дозвіл roles:manage)
+// @Tags roles
+// @Produce json
+// @Success 200 {array} RoleResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/roles [get]
+func NewListRolesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roles, err := db.NewRoleRepository(db.DB).ListRoles(r.Context())
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		resp := make([]RoleResponse, 0, len(roles))
+		for i := range roles {
+			resp = append(resp, newRoleResponse(&roles[i]))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewCreateRoleHandler godoc
+// @Summary Створення ролі
+// @Description Створює роль з набором дозволів (потрібен дозвіл roles:manage). Надати можна лише дозволи, які є у вас. Якщо require_mfa, користувачі з роллю входять лише з другим фактором.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param request body CreateRoleRequest true "Нова роль"
+// @Success 201 {object} RoleResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або невідомий дозвіл"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав або дозвіл, якого немає у вас"
+// @Failure 409 {object} models.ErrorResponse "Роль уже існує"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/roles [post]
+func NewCreateRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if !rolePattern.MatchString(req.Name) {
+			writeError(w, http.StatusBadRequest, "Role name must be 2-32 characters: lowercase letters, digits, '_' or '-', starting with a letter")
+			return
+		}
+		if p := missingPermission(claimsFromContext(r.Context()), req.Permissions); p != "" {
+			writeError(w, http.StatusForbidden, "You cannot grant a permission you do not have: "+p)
+			return
+		}
+
+		role := &models.Role{
+			Name:        req.Name,
+			Description: req.Description,
+			RequireMFA:  req.RequireMFA,
+			CreatedAt:   time.Now(),
+			Permissions: req.Permissions,
+		}
+		repo := db.NewRoleRepository(db.DB)
+		if err := repo.CreateRole(r.Context(), role); err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		created, err := repo.GetRole(r.Context(), role.Name)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, newRoleResponse(created))
+	}
+}
+
+// NewUpdateRoleHandler godoc
+// @Summary Зміна ролі
+// @Description Змінює опис ролі і вимогу MFA та замінює її дозволи (потрібен дозвіл roles:manage). Додати можна лише дозволи, які є у вас. Роль admin має зберегти дозвіл roles:manage. Зміни потрапляють у токени при наступному вході або оновленні токенів.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param name path string true "Назва ролі"
+// @Param request body UpdateRoleRequest true "Опис, вимога MFA і дозволи ролі"
+// @Success 200 {object} RoleResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або невідомий дозвіл"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав або дозвіл, якого немає у вас"
+// @Failure 404 {object} models.ErrorResponse "Роль не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/roles/{name} [put]
+func NewUpdateRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req UpdateRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		repo := db.NewRoleRepository(db.DB)
+		role, err := repo.GetRole(r.Context(), r.PathValue("name"))
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		if role.Name == models.RoleAdmin && !slices.Contains(req.Permissions, models.PermissionRolesManage) {
+			writeError(w, http.StatusBadRequest, "The admin role must keep the roles:manage permission")
+			return
+		}
+		// Перевіряються лише нові дозволи: наявні можна лишити, навіть якщо
+		// їх немає в автора запиту, щоб він міг змінити опис чи вимогу MFA.
+		var added []string
+		for _, p := range req.Permissions {
+			if !slices.Contains(role.Permissions, p) {
+				added = append(added, p)
+			}
+		}
+		if p := missingPermission(claimsFromContext(r.Context()), added); p != "" {
+			writeError(w, http.StatusForbidden, "You cannot grant a permission you do not have: "+p)
+			return
+		}
+
+		role.Description = req.Description
+		role.RequireMFA = req.RequireMFA
+		role.Permissions = req.Permissions
+		if err := repo.UpdateRole(r.Context(), role); err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		updated, err := repo.GetRole(r.Context(), role.Name)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newRoleResponse(updated))
+	}
+}
+
+// NewDeleteRoleHandler godoc
+// @Summary Видалення ролі
+// @Description Видаляє роль і забирає її в усіх користувачів. Вбудовані ролі admin і user видалити не можна (потрібен дозвіл roles:manage).
+// @Tags roles
+// @Produce json
+// @Param name path string true "Назва ролі"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Вбудована роль"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Роль не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/roles/{name} [delete]
+func NewDeleteRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == models.RoleAdmin || name == models.RoleUser {
+			writeError(w, http.StatusBadRequest, "Built-in roles cannot be deleted")
+			return
+		}
+
+		repo := db.NewRoleRepository(db.DB)
+		if _, err := repo.GetRole(r.Context(), name); err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		if err := repo.DeleteRole(r.Context(), name); err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Role deleted"})
+	}
+}
+
+// NewListPermissionsHandler godoc
+// @Summary Список дозволів
+// @Description Повертає всі дозволи, які можна надати ролям (потрібен дозвіл roles:manage)
+// @Tags roles
+// @Produce json
+// @Success 200 {array} PermissionResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/permissions [get]
+func NewListPermissionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		permissions, err := db.NewRoleRepository(db.DB).ListPermissions(r.Context())
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		resp := make([]PermissionResponse, 0, len(permissions))
+		for _, p := range permissions {
+			resp = append(resp, PermissionResponse{Name: p.Name, Description: p.Description})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewCreatePermissionHandler godoc
+// @Summary Створення дозволу
+// @Description Створює дозвіл у форматі resource:action, наприклад inventory:write (потрібен дозвіл roles:manage)
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param request body CreatePermissionRequest true "Новий дозвіл"
+// @Success 201 {object} PermissionResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 409 {object} models.ErrorResponse "Дозвіл уже існує"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/permissions [post]
+func NewCreatePermissionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreatePermissionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if len(req.Name) > 64 || !permissionPattern.MatchString(req.Name) {
+			writeError(w, http.StatusBadRequest, "Permission must look like resource:action, using lowercase letters, digits, '_' or '-'")
+			return
+		}
+
+		permission := &models.Permission{Name: req.Name, Description: req.Description}
+		if err := db.NewRoleRepository(db.DB).CreatePermission(r.Context(), permission); err != nil {
+			writeRoleError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, PermissionResponse{Name: permission.Name, Description: permission.Description})
+	}
+}
+
+// NewDeletePermissionHandler godoc
+// @Summary Видалення дозволу
+// @Description Видаляє дозвіл і забирає його в усіх ролей. Вбудовані дозволи видалити не можна (потрібен дозвіл roles:manage).
+// @Tags roles
+// @Produce json
+// @Param name path string true "Назва дозволу"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Вбудований дозвіл"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Дозвіл не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/permissions/{name} [delete]
+func NewDeletePermissionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if slices.Contains(models.BuiltinPermissions, name) {
+			writeError(w, http.StatusBadRequest, "Built-in permissions cannot be deleted")
+			return
+		}
+
+		deleted, err := db.NewRoleRepository(db.DB).DeletePermission(r.Context(), name)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		if !deleted {
+			writeError(w, http.StatusNotFound, "Permission not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Permission deleted"})
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+// setupHandlerTestDB підміняє db.DB тимчасовою БД з усіма міграціями.
+func setupHandlerTestDB(t *testing.T) {
+	t.Helper()
+	prev := db.DB
+	db.InitDB(filepath.Join(t.TempDir(), "auth.db"))
+	t.Cleanup(func() {
+		db.DB.Close()
+		db.DB = prev
+	})
+}
+
+// newAuthedRequest будує запит з JSON тілом і claims автора в контексті,
+// як їх кладе RequireAuth.
+func newAuthedRequest(t *testing.T, method, path string, body any, permissions ...string) *http.Request {
+	t.Helper()
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	r := httptest.NewRequest(method, path, bytes.NewReader(b))
+	claims := &token.UserClaims{ID: "author", Login: "author", Permissions: permissions}
+	return r.WithContext(context.WithValue(r.Context(), claimsContextKey, claims))
+}
+
+func TestCreateRoleHandler_Escalation(t *testing.T) {
+	setupHandlerTestDB(t)
+	handler := NewCreateRoleHandler()
+
+	tests := []struct {
+		name        string
+		permissions []string
+		want        int
+	}{
+		{"OwnPermissions", []string{models.PermissionProductRead}, http.StatusCreated},
+		{"PermissionCallerLacks", []string{models.PermissionProductRead, models.PermissionUsersManage}, http.StatusForbidden},
+		{"KeysManage", []string{models.PermissionKeysManage}, http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := CreateRoleRequest{Name: "role_" + string(rune('a'+i)), Permissions: tt.permissions}
+			r := newAuthedRequest(t, http.MethodPost, "/api/roles", req,
+				models.PermissionRolesManage, models.PermissionProductRead)
+			w := httptest.NewRecorder()
+			handler(w, r)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+
+			_, err := db.NewRoleRepository(db.DB).GetRole(context.Background(), req.Name)
+			if tt.want == http.StatusCreated {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err, "role must not be created")
+			}
+		})
+	}
+}
+
+func TestUpdateRoleHandler_Escalation(t *testing.T) {
+	setupHandlerTestDB(t)
+	handler := NewUpdateRoleHandler()
+	repo := db.NewRoleRepository(db.DB)
+	ctx := context.Background()
+
+	// У ролі вже є keys:manage, якого немає в автора запиту.
+	require.NoError(t, repo.CreateRole(ctx, &models.Role{
+		Name:        "operator",
+		CreatedAt:   time.Now(),
+		Permissions: []string{models.PermissionProductRead, models.PermissionKeysManage},
+	}))
+
+	update := func(req UpdateRoleRequest) *httptest.ResponseRecorder {
+		r := newAuthedRequest(t, http.MethodPut, "/api/roles/operator", req,
+			models.PermissionRolesManage, models.PermissionProductRead, models.PermissionProductWrite)
+		r.SetPathValue("name", "operator")
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	t.Run("KeepsExistingPermissions", func(t *testing.T) {
+		w := update(UpdateRoleRequest{
+			Description: "changed",
+			Permissions: []string{models.PermissionProductRead, models.PermissionKeysManage, models.PermissionProductWrite},
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		role, err := repo.GetRole(ctx, "operator")
+		require.NoError(t, err)
+		assert.Equal(t, "changed", role.Description)
+		assert.ElementsMatch(t, []string{models.PermissionProductRead, models.PermissionKeysManage, models.PermissionProductWrite}, role.Permissions)
+	})
+
+	t.Run("AddsPermissionCallerLacks", func(t *testing.T) {
+		w := update(UpdateRoleRequest{
+			Description: "escalated",
+			Permissions: []string{models.PermissionProductRead, models.PermissionKeysManage, models.PermissionUsersManage},
+		})
+		require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+		role, err := repo.GetRole(ctx, "operator")
+		require.NoError(t, err)
+		assert.Equal(t, "changed", role.Description)
+		assert.NotContains(t, role.Permissions, models.PermissionUsersManage)
+	})
+}
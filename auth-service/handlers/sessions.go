@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+// sessionTouchInterval — як часто оновлюється last_seen_at сесії: частіші
+// перевірки токена не пишуть у БД.
+const sessionTouchInterval = time.Minute
+
+// SessionResponse — активна сесія входу. ID сесії збігається з claim sid
+// її access токенів.
+type SessionResponse struct {
+	ID string `json:"id"`
+	// TokenID — jti останнього виданого сесії access токена.
+	TokenID   string    `json:"token_id"`
+	ClientIP  string    `json:"client_ip" example:"203.0.113.7"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	IssuedAt  time.Time `json:"issued_at"`
+	// ExpiresAt — кінець строку дії refresh токена сесії.
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	// Current позначає сесію, якою виконано цей запит.
+	Current bool `json:"current"`
+	// TenantID — організація, в якій зараз діє сесія.
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+func newSessionResponse(s *models.Session, currentID string) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		TokenID:    s.TokenID,
+		ClientIP:   s.ClientIP,
+		UserAgent:  s.UserAgent,
+		CreatedAt:  s.CreatedAt,
+		IssuedAt:   s.IssuedAt,
+		ExpiresAt:  s.ExpiresAt,
+		LastSeenAt: s.LastSeenAt,
+		Current:    s.ID == currentID,
+		TenantID:   s.TenantID,
+	}
+}
+
+// touchSession записує час перевірки токена сесії. Помилка лише логується:
+// вона не робить токен недійсним.
+func touchSession(ctx context.Context, claims *token.UserClaims) {
+	if claims.SessionID == "" {
+		return
+	}
+	now := time.Now()
+	if err := db.NewSessionRepository(db.DB).TouchSession(ctx, claims.SessionID, now, now.Add(-sessionTouchInterval)); err != nil {
+		log.Println("Touch session error:", err)
+	}
+}
+
+func writeSessions(w http.ResponseWriter, r *http.Request, userID string) {
+	claims := claimsFromContext(r.Context())
+
+	sessions, err := db.NewSessionRepository(db.DB).ListUserSessions(r.Context(), userID, time.Now())
+	if err != nil {
+		log.Println("List sessions error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	resp := make([]SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		resp = append(resp, newSessionResponse(&sessions[i], claims.SessionID))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func revokeSession(w http.ResponseWriter, r *http.Request, userID string, sessionID string) {
+	revoked, err := db.NewSessionRepository(db.DB).RevokeSession(r.Context(), userID, sessionID)
+	if err != nil {
+		log.Println("Revoke session error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if !revoked {
+		writeError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	log.Printf("Session %s of user %s revoked", sessionID, userID)
+	writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Session revoked"})
+}
+
+// NewListSessionsHandler godoc
+// @Summary Активні сесії
+// @Description Повертає активні сесії входу поточного користувача: звідки виконано вхід і коли токен сесії востаннє перевірявся
+// @Tags sessions
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT користувача"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/sessions [get]
+func NewListSessionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeSessions(w, r, claimsFromContext(r.Context()).ID)
+	}
+}
+
+// NewRevokeSessionHandler godoc
+// @Summary Завершення сесії
+// @Description Завершує сесію поточного користувача: її refresh токени відкликаються, а access токени одразу перестають проходити перевірку
+// @Tags sessions
+// @Produce json
+// @Param id path string true "ID сесії"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 404 {object} models.ErrorResponse "Сесію не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/sessions/{id} [delete]
+func NewRevokeSessionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		revokeSession(w, r, claimsFromContext(r.Context()).ID, r.PathValue("id"))
+	}
+}
+
+// NewRevokeOtherSessionsHandler godoc
+// @Summary Завершення інших сесій
+// @Description Завершує всі сесії поточного користувача, крім тієї, якою виконано запит
+// @Tags sessions
+// @Produce json
+// @Success 200 {object} RevokeSessionsResponse
+// @Failure 400 {object} models.ErrorResponse "Токен не належить жодній сесії"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/sessions/revoke-others [post]
+func NewRevokeOtherSessionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+		// Без sid невідомо, яку сесію залишити.
+		if claims.SessionID == "" {
+			writeError(w, http.StatusBadRequest, "Token has no session")
+			return
+		}
+
+		revoked, err := db.NewSessionRepository(db.DB).RevokeUserSessions(r.Context(), claims.ID, claims.SessionID)
+		if err != nil {
+			log.Println("Revoke sessions error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		log.Printf("%d other sessions of user %s revoked", revoked, claims.ID)
+		writeJSON(w, http.StatusOK, RevokeSessionsResponse{Revoked: revoked})
+	}
+}
+
+// NewListUserSessionsHandler godoc
+// @Summary Активні сесії користувача
+// @Description Повертає активні сесії входу користувача (потрібен дозвіл users:manage)
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Success 200 {array} SessionResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id}/sessions [get]
+func NewListUserSessionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadUser(w, r)
+		if !ok {
+			return
+		}
+		writeSessions(w, r, user.ID)
+	}
+}
+
+// NewRevokeUserSessionHandler godoc
+// @Summary Завершення сесії користувача
+// @Description Завершує сесію користувача; її токени одразу перестають проходити перевірку (потрібен дозвіл users:manage). Усі сесії завершує POST /api/users/{id}/revoke-tokens.
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Param sid path string true "ID сесії"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача або сесію не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id}/sessions/{sid} [delete]
+func NewRevokeUserSessionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+		revokeSession(w, r, user.ID, r.PathValue("sid"))
+	}
+}
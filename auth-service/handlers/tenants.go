@@ -0,0 +1,630 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+const maxTenantNameLength = 100
+
+type TenantRequest struct {
+	Name string `json:"name" example:"Acme"`
+}
+
+type TenantResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name" example:"Acme"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TenantMemberRequest задає роль користувача в організації: admin, editor
+// або member.
+type TenantMemberRequest struct {
+	Role string `json:"role" example:"editor"`
+}
+
+type TenantMemberResponse struct {
+	UserID    string    `json:"user_id"`
+	Login     string    `json:"login"`
+	Role      string    `json:"role" example:"editor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserTenantResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name" example:"Acme"`
+	Role string `json:"role" example:"editor"`
+	// Current позначає організацію, в якій діє токен запиту.
+	Current bool `json:"current"`
+}
+
+// SwitchTenantRequest — організація, в яку переходить сесія; порожній
+// tenant_id виводить сесію з організації.
+type SwitchTenantRequest struct {
+	TenantID string `json:"tenant_id"`
+}
+
+func newTenantResponse(t *models.Tenant) TenantResponse {
+	return TenantResponse{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt}
+}
+
+func newTenantMemberResponse(m *models.TenantMember) TenantMemberResponse {
+	return TenantMemberResponse{UserID: m.UserID, Login: m.Login, Role: m.Role, CreatedAt: m.CreatedAt}
+}
+
+// tokenAccess — ролі й дозволи, з якими видається токен, і організація, в
+// якій він діє.
+type tokenAccess struct {
+	Roles       []string
+	Permissions []string
+	TenantID    string
+	TenantRole  string
+}
+
+// loadTokenAccess читає глобальні ролі й дозволи користувача і додає до них
+// дозволи його ролі в організації tenantID. Якщо користувач у ній не діє,
+// TenantID відповіді порожній.
+func loadTokenAccess(ctx context.Context, userID string, tenantID string) (tokenAccess, error) {
+	roleRepo := db.NewRoleRepository(db.DB)
+	roles, err := roleRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return tokenAccess{}, err
+	}
+	permissions, err := roleRepo.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return tokenAccess{}, err
+	}
+	access := tokenAccess{Roles: roles, Permissions: permissions}
+	if tenantID == "" {
+		return access, nil
+	}
+
+	role, err := tenantRole(ctx, userID, tenantID, permissions)
+	if err != nil || role == "" {
+		return access, err
+	}
+	access.TenantID, access.TenantRole = tenantID, role
+	for _, p := range models.TenantRolePermissions[role] {
+		if !slices.Contains(access.Permissions, p) {
+			access.Permissions = append(access.Permissions, p)
+		}
+	}
+	return access, nil
+}
+
+// tenantRole повертає роль користувача в організації або порожній рядок,
+// якщо він у ній не діє. Адміністратор платформи (дозвіл tenants:manage)
+// діє в будь-якій організації як її адміністратор; роль в організації не дає
+// дозволів платформи.
+func tenantRole(ctx context.Context, userID string, tenantID string, permissions []string) (string, error) {
+	repo := db.NewTenantRepository(db.DB)
+	member, err := repo.GetMember(ctx, tenantID, userID)
+	if err == nil {
+		return member.Role, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	if !slices.Contains(permissions, models.PermissionTenantsManage) {
+		return "", nil
+	}
+	if _, err := repo.GetTenant(ctx, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return models.TenantRoleAdmin, nil
+}
+
+// loadManagedTenant повертає організацію з {id} шляху, якщо користувач запиту
+// — її адміністратор або адміністратор платформи. Учасникам інших організацій
+// вона не видна: 404, як і для неіснуючої.
+func loadManagedTenant(w http.ResponseWriter, r *http.Request) (*models.Tenant, bool) {
+	ctx := r.Context()
+	claims := claimsFromContext(ctx)
+	repo := db.NewTenantRepository(db.DB)
+
+	tenant, err := repo.GetTenant(ctx, r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "Tenant not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Println("Load tenant error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return nil, false
+	}
+	if claims.HasPermission(models.PermissionTenantsManage) {
+		return tenant, true
+	}
+
+	member, err := repo.GetMember(ctx, tenant.ID, claims.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "Tenant not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Println("Load tenant error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return nil, false
+	}
+	if member.Role != models.TenantRoleAdmin {
+		writeError(w, http.StatusForbidden, "Tenant admin required")
+		return nil, false
+	}
+	return tenant, true
+}
+
+// keepsTenantAdmin перевіряє, що зміна ролі адміністратора member не залишить
+// організацію без адміністраторів.
+func keepsTenantAdmin(ctx context.Context, member *models.TenantMember) (bool, error) {
+	if member.Role != models.TenantRoleAdmin {
+		return true, nil
+	}
+	admins, err := db.NewTenantRepository(db.DB).CountAdmins(ctx, member.TenantID)
+	if err != nil {
+		return false, err
+	}
+	return admins > 1, nil
+}
+
+// NewListTenantsHandler godoc
+// @Summary Список організацій
+// @Description Повертає всі організації (потрібен дозвіл tenants:manage)
+// @Tags tenants
+// @Produce json
+// @Success 200 {array} TenantResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/tenants [get]
+func NewListTenantsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenants, err := db.NewTenantRepository(db.DB).ListTenants(r.Context())
+		if err != nil {
+			log.Println("List tenants error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		resp := make([]TenantResponse, 0, len(tenants))
+		for i := range tenants {
+			resp = append(resp, newTenantResponse(&tenants[i]))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewCreateTenantHandler godoc
+// @Summary Створення організації
+// @Description Створює організацію (потрібен дозвіл tenants:manage). Учасників і адміністраторів організації додає PUT /api/tenants/{id}/members/{user}.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param request body TenantRequest true "Назва організації"
+// @Success 201 {object} TenantResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректна назва"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 409 {object} models.ErrorResponse "Організація з такою назвою вже існує"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/tenants [post]
+func NewCreateTenantHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TenantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > maxTenantNameLength {
+			writeError(w, http.StatusBadRequest, "Name must be 1-100 characters")
+			return
+		}
+
+		tenant := &models.Tenant{ID: uuid.NewString(), Name: name, CreatedAt: time.Now()}
+		err := db.NewTenantRepository(db.DB).CreateTenant(r.Context(), tenant)
+		if errors.Is(err, db.ErrTenantExists) {
+			writeError(w, http.StatusConflict, "Tenant already exists")
+			return
+		}
+		if err != nil {
+			log.Println("Create tenant error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		event := auditEvent(models.AuditTenantCreate, claimsFromContext(r.Context()))
+		event.Details = "tenant " + tenant.ID + " (" + tenant.Name + ")"
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusCreated, newTenantResponse(tenant))
+	}
+}
+
+// NewDeleteTenantHandler godoc
+// @Summary Видалення організації
+// @Description Видаляє організацію і членство в ній та завершує сесії, що діють в організації (потрібен дозвіл tenants:manage). API ключі організації перестають діяти. Дані сервісів, що належать організації, не видаляються.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "ID організації"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Організацію не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/tenants/{id} [delete]
+func NewDeleteTenantHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		repo := db.NewTenantRepository(db.DB)
+
+		tenant, err := repo.GetTenant(ctx, r.PathValue("id"))
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "Tenant not found")
+			return
+		}
+		if err != nil {
+			log.Println("Delete tenant error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if err := repo.DeleteTenant(ctx, tenant.ID); err != nil {
+			log.Println("Delete tenant error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		revoked, err := db.NewSessionRepository(db.DB).RevokeTenantSessions(ctx, tenant.ID, "")
+		if err != nil {
+			log.Println("Delete tenant error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		event := auditEvent(models.AuditTenantDelete, claimsFromContext(ctx))
+		event.Details = "tenant " + tenant.ID + " (" + tenant.Name + ")"
+		recordAudit(r, proxies, event)
+		log.Printf("Tenant %s deleted, %d sessions revoked", tenant.ID, revoked)
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Tenant deleted"})
+	}
+}
+
+// NewListTenantMembersHandler godoc
+// @Summary Учасники організації
+// @Description Повертає учасників організації та їх ролі. Доступно адміністратору організації та адміністратору платформи (дозвіл tenants:manage).
+// @Tags tenants
+// @Produce json
+// @Param id path string true "ID організації"
+// @Success 200 {array} TenantMemberResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібна роль адміністратора організації"
+// @Failure 404 {object} models.ErrorResponse "Організацію не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/tenants/{id}/members [get]
+func NewListTenantMembersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := loadManagedTenant(w, r)
+		if !ok {
+			return
+		}
+
+		members, err := db.NewTenantRepository(db.DB).ListMembers(r.Context(), tenant.ID)
+		if err != nil {
+			log.Println("List tenant members error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		resp := make([]TenantMemberResponse, 0, len(members))
+		for i := range members {
+			resp = append(resp, newTenantMemberResponse(&members[i]))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewSetTenantMemberHandler godoc
+// @Summary Додавання учасника організації
+// @Description Додає користувача в організацію або змінює його роль: admin керує учасниками й даними організації, editor змінює дані, member лише читає. Доступно адміністратору організації та адміністратору платформи. При зміні ролі сесії користувача в організації завершуються, щоб токени зі старою роллю перестали діяти. Не можна понизити останнього адміністратора.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "ID організації"
+// @Param user path string true "ID або логін користувача"
+// @Param request body TenantMemberRequest true "Роль в організації"
+// @Success 200 {object} TenantMemberResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректна роль"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібна роль адміністратора організації"
+// @Failure 404 {object} models.ErrorResponse "Організацію або користувача не знайдено"
+// @Failure 409 {object} models.ErrorResponse "Це останній адміністратор організації"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/tenants/{id}/members/{user} [put]
+func NewSetTenantMemberHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := loadManagedTenant(w, r)
+		if !ok {
+			return
+		}
+
+		var req TenantMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if _, ok := models.TenantRolePermissions[req.Role]; !ok {
+			writeError(w, http.StatusBadRequest, "Invalid tenant role")
+			return
+		}
+
+		ctx := r.Context()
+		user, err := findUser(ctx, r.PathValue("user"))
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		if err != nil {
+			log.Println("Set tenant member error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		repo := db.NewTenantRepository(db.DB)
+		member, err := repo.GetMember(ctx, tenant.ID, user.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Println("Set tenant member error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		previous := ""
+		if member != nil {
+			previous = member.Role
+			if previous == req.Role {
+				writeJSON(w, http.StatusOK, newTenantMemberResponse(member))
+				return
+			}
+			keeps, err := keepsTenantAdmin(ctx, member)
+			if err != nil {
+				log.Println("Set tenant member error:", err)
+				writeError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+			if !keeps {
+				writeError(w, http.StatusConflict, "Tenant must keep at least one admin")
+				return
+			}
+		} else {
+			member = &models.TenantMember{TenantID: tenant.ID, TenantName: tenant.Name, UserID: user.ID, Login: user.Login, CreatedAt: time.Now()}
+		}
+		member.Role = req.Role
+
+		if err := repo.SetMember(ctx, member); err != nil {
+			log.Println("Set tenant member error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if previous != "" {
+			if _, err := db.NewSessionRepository(db.DB).RevokeTenantSessions(ctx, tenant.ID, user.ID); err != nil {
+				log.Println("Set tenant member error:", err)
+				writeError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+		}
+
+		event := targetAuditEvent(models.AuditTenantMemberSet, claimsFromContext(ctx), user)
+		event.Details = "tenant " + tenant.ID + ": role " + req.Role
+		if previous != "" {
+			event.Details = "tenant " + tenant.ID + ": role " + previous + " -> " + req.Role
+		}
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, newTenantMemberResponse(member))
+	}
+}
+
+// NewRemoveTenantMemberHandler godoc
+// @Summary Видалення учасника організації
+// @Description Видаляє користувача з організації і завершує його сесії в ній. Доступно адміністратору організації та адміністратору платформи. Не можна видалити останнього адміністратора.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "ID організації"
+// @Param user path string true "ID або логін користувача"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібна роль адміністратора організації"
+// @Failure 404 {object} models.ErrorResponse "Організацію або учасника не знайдено"
+// @Failure 409 {object} models.ErrorResponse "Це останній адміністратор організації"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/tenants/{id}/members/{user} [delete]
+func NewRemoveTenantMemberHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := loadManagedTenant(w, r)
+		if !ok {
+			return
+		}
+
+		ctx := r.Context()
+		user, err := findUser(ctx, r.PathValue("user"))
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "Member not found")
+			return
+		}
+		if err != nil {
+			log.Println("Remove tenant member error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		repo := db.NewTenantRepository(db.DB)
+		member, err := repo.GetMember(ctx, tenant.ID, user.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "Member not found")
+			return
+		}
+		if err != nil {
+			log.Println("Remove tenant member error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		keeps, err := keepsTenantAdmin(ctx, member)
+		if err != nil {
+			log.Println("Remove tenant member error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !keeps {
+			writeError(w, http.StatusConflict, "Tenant must keep at least one admin")
+			return
+		}
+
+		if _, err := repo.RemoveMember(ctx, tenant.ID, user.ID); err != nil {
+			log.Println("Remove tenant member error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if _, err := db.NewSessionRepository(db.DB).RevokeTenantSessions(ctx, tenant.ID, user.ID); err != nil {
+			log.Println("Remove tenant member error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		event := targetAuditEvent(models.AuditTenantMemberRemove, claimsFromContext(ctx), user)
+		event.Details = "tenant " + tenant.ID + ": role " + member.Role
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Member removed"})
+	}
+}
+
+// NewListUserTenantsHandler godoc
+// @Summary Мої організації
+// @Description Повертає організації, в які входить поточний користувач, і його роль у кожній
+// @Tags auth
+// @Produce json
+// @Success 200 {array} UserTenantResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT користувача"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/tenants [get]
+func NewListUserTenantsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		members, err := db.NewTenantRepository(db.DB).ListUserTenants(r.Context(), claims.ID)
+		if err != nil {
+			log.Println("List user tenants error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		resp := make([]UserTenantResponse, 0, len(members))
+		for _, m := range members {
+			resp = append(resp, UserTenantResponse{ID: m.TenantID, Name: m.TenantName, Role: m.Role, Current: m.TenantID == claims.TenantID})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewSwitchTenantHandler godoc
+// @Summary Перехід в іншу організацію
+// @Description Переводить поточну сесію в організацію tenant_id і видає нові токени з її роллю; поточний access токен відкликається. Порожній tenant_id виводить сесію з організації. Адміністратор платформи може перейти в будь-яку організацію.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body SwitchTenantRequest true "ID організації"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або токен не належить жодній сесії"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Потрібен JWT самого користувача, а не API ключ чи токен імперсонації"
+// @Failure 404 {object} models.ErrorResponse "Організацію не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/tenant [post]
+func NewSwitchTenantHandler(jwtMaker *token.JWTMaker, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+
+		var req SwitchTenantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		// Організація зберігається в сесії: без sid її нема куди записати.
+		if claims.SessionID == "" {
+			writeError(w, http.StatusBadRequest, "Token has no session")
+			return
+		}
+
+		ctx := r.Context()
+		user, err := db.NewUserRepository(db.DB).GetUserByID(ctx, claims.ID)
+		if err != nil {
+			log.Println("Switch tenant error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if !checkUserStatus(w, user) {
+			return
+		}
+		if req.TenantID != "" {
+			access, err := loadTokenAccess(ctx, user.ID, req.TenantID)
+			if err != nil {
+				log.Println("Switch tenant error:", err)
+				writeError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+			if access.TenantID == "" {
+				writeError(w, http.StatusNotFound, "Tenant not found")
+				return
+			}
+		}
+
+		if err := db.NewSessionRepository(db.DB).SetSessionTenant(ctx, claims.SessionID, req.TenantID); err != nil {
+			log.Println("Switch tenant error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if err := db.NewRevocationRepository(db.DB).RevokeToken(ctx, claims.RegisteredClaims.ID, claims.RevocationSubject(), claims.ExpiresAt.Time); err != nil {
+			log.Println("Switch tenant error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		resp, err := issueTokens(ctx, jwtMaker, user, claims.SessionID, newRequestOrigin(r, proxies))
+		if err != nil {
+			log.Println("Switch tenant error:", err)
+			writeError(w, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+
+		event := auditEvent(models.AuditTenantSwitch, claims)
+		event.Details = "no tenant"
+		if req.TenantID != "" {
+			event.Details = "tenant " + req.TenantID
+		}
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/bulk"
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/passwords"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+// maxImportSize обмежує розмір файлу імпорту користувачів.
+const maxImportSize = 10 << 20
+
+// importFormat визначає формат файлу імпорту з параметра format або
+// Content-Type запиту; за замовчуванням — CSV.
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		return bulk.FormatJSON
+	}
+	return bulk.FormatCSV
+}
+
+func queryBool(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New("Invalid " + name)
+	}
+	return b, nil
+}
+
+// NewImportUsersHandler godoc
+// @Summary Імпорт користувачів
+// @Description Створює або оновлює користувачів з файлу CSV або JSON (потрібен дозвіл users:manage). Користувач шукається за логіном, тож повторний імпорт того самого файлу нічого не змінює; порожні поля існуючого користувача не змінюються. CSV має рядок заголовка з колонкою login і необов'язковими email, email_verified (true або false), status, roles (через кому), password і password_hash (bcrypt); JSON — масив об'єктів з тими самими полями, roles — масив. Інші колонки, наприклад з експорту, пропускаються. Помилка в рядку не зупиняє імпорт: вона повертається в результаті рядка. Ролі з дозволами, яких немає в автора імпорту, не надаються, а користувачі з такими дозволами не змінюються. Email з імпорту не підтверджений, поки в рядку не задано email_verified: true, — користувач може запросити лист через POST /api/auth/email/resend; зміна пароля або вимкнення облікового запису відкликає токени користувача.
+// @Tags users
+// @Accept text/csv
+// @Accept json
+// @Produce json
+// @Param format query string false "csv або json (за замовчуванням визначається з Content-Type, інакше csv)"
+// @Param dry_run query bool false "Лише перевірити файл і показати, що буде зроблено"
+// @Param generate_passwords query bool false "Згенерувати пароль новим користувачам без password і password_hash; пароль повертається лише в цій відповіді"
+// @Param file body string true "Вміст файлу (не більше 10 МБ і 10000 рядків)"
+// @Success 200 {object} bulk.Result
+// @Failure 400 {object} models.ErrorResponse "Некоректний файл"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 413 {object} models.ErrorResponse "Файл завеликий"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/import [post]
+func NewImportUsersHandler(policy *passwords.Policy, proxies utils.TrustedProxies) http.HandlerFunc {
+	importer := bulk.NewImporter(policy)
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := importFormat(r)
+		if format != bulk.FormatCSV && format != bulk.FormatJSON {
+			writeError(w, http.StatusBadRequest, "Invalid format: expected csv or json")
+			return
+		}
+		dryRun, err := queryBool(r, "dry_run")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		generate, err := queryBool(r, "generate_passwords")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		records, err := bulk.Parse(http.MaxBytesReader(w, r.Body, maxImportSize), format)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, "File is too large")
+				return
+			}
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		claims := claimsFromContext(r.Context())
+		opts := bulk.Options{DryRun: dryRun, GeneratePasswords: generate}
+		if claims != nil {
+			opts.ActorID = claims.ID
+			opts.HasPermission = claims.HasPermission
+		}
+		result, err := importer.Import(r.Context(), records, opts)
+		if result != nil && !dryRun {
+			for _, row := range result.Rows {
+				if row.Action != bulk.ActionCreated && row.Action != bulk.ActionUpdated {
+					continue
+				}
+				event := targetAuditEvent(models.AuditUserImport, claims, &models.User{ID: row.UserID, Login: row.Login})
+				event.Details = row.Summary()
+				recordAudit(r, proxies, event)
+			}
+		}
+		if err != nil {
+			log.Println("Import users error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// NewExportUsersHandler godoc
+// @Summary Експорт користувачів
+// @Description Вивантажує користувачів, що відповідають фільтрам, файлом CSV або JSON з ролями і без хешів паролів (потрібен дозвіл users:manage). Файл можна знову передати в POST /api/users/import.
+// @Tags users
+// @Produce text/csv
+// @Produce json
+// @Param format query string false "csv (за замовчуванням) або json"
+// @Param login query string false "Пошук за логіном"
+// @Param status query string false "Стан облікового запису: pending, active або disabled"
+// @Success 200 {array} bulk.ExportedUser
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/export [get]
+func NewExportUsersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = bulk.FormatCSV
+		}
+		if format != bulk.FormatCSV && format != bulk.FormatJSON {
+			writeError(w, http.StatusBadRequest, "Invalid format: expected csv or json")
+			return
+		}
+		filter := db.UserFilter{
+			Login:  r.URL.Query().Get("login"),
+			Status: r.URL.Query().Get("status"),
+		}
+
+		filename := "users-" + time.Now().UTC().Format("20060102-150405") + "." + format
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		if format == bulk.FormatCSV {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+
+		// Після початку відповіді статус уже не змінити, тож помилка
+		// посередині вивантаження лише логується і обриває файл.
+		if err := bulk.Export(r.Context(), w, format, filter); err != nil {
+			log.Println("Export users error:", err)
+		}
+	}
+}
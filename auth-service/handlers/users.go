@@ -0,0 +1,662 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/lockout"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/passwords"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+const (
+	defaultUsersLimit = 20
+	maxUsersLimit     = 100
+)
+
+type UserDetailsResponse struct {
+	ID              string     `json:"id"`
+	Login           string     `json:"login"`
+	Email           string     `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Status          string     `json:"status" example:"active"`
+	Roles           []string   `json:"roles"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
+type UserListResponse struct {
+	Users  []UserDetailsResponse `json:"users"`
+	Total  int                   `json:"total"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+}
+
+// UpdateUserRequest змінює лише передані поля; порожній email видаляє його.
+type UpdateUserRequest struct {
+	Roles *[]string `json:"roles"`
+	Email *string   `json:"email" example:"user@example.com"`
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+func newUserDetailsResponse(user *models.User, roles []string) UserDetailsResponse {
+	if roles == nil {
+		roles = []string{}
+	}
+	return UserDetailsResponse{
+		ID:              user.ID,
+		Login:           user.Login,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Status:          user.Status,
+		Roles:           roles,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, errors.New("Invalid " + name)
+	}
+	return n, nil
+}
+
+// queryLimit читає параметр limit: від 1 до maxLimit, за замовчуванням def.
+func queryLimit(r *http.Request, def int, maxLimit int) (int, error) {
+	limit, err := queryInt(r, "limit", def)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return 0, fmt.Errorf("Invalid limit: expected 1-%d", maxLimit)
+	}
+	return limit, nil
+}
+
+// loadUser повертає користувача з {id} шляху або пише 404/500 у відповідь.
+func loadUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	repo := db.NewUserRepository(db.DB)
+	user, err := repo.GetUserByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "User not found")
+			return nil, false
+		}
+		log.Println("Get user error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return nil, false
+	}
+	return user, true
+}
+
+// loadManagedUser — як loadUser, але пише 403, якщо в користувача є дозволи,
+// яких немає в автора запиту: users:manage не повинен давати змоги змінити
+// пароль, email чи ролі адміністратора і так захопити його обліковий запис.
+func loadManagedUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := loadUser(w, r)
+	if !ok {
+		return nil, false
+	}
+	permissions, err := db.NewRoleRepository(db.DB).GetUserPermissions(r.Context(), user.ID)
+	if err != nil {
+		log.Println("Get user permissions error:", err)
+		writeError(w, http.StatusInternalServerError, "Database error")
+		return nil, false
+	}
+	if p := missingPermission(claimsFromContext(r.Context()), permissions); p != "" {
+		writeError(w, http.StatusForbidden, "User has a permission you do not have: "+p)
+		return nil, false
+	}
+	return user, true
+}
+
+// missingPermission повертає перший дозвіл із permissions, якого немає в
+// claims, або порожній рядок.
+func missingPermission(claims *token.UserClaims, permissions []string) string {
+	for _, p := range permissions {
+		if !claims.HasPermission(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// checkGrantableRoles пише 403, якщо серед roles, яких користувач ще не має
+// (current), є роль з дозволом, якого немає в автора запиту. Невідомі ролі
+// пропускаються: їх відхилить SetUserRoles.
+func checkGrantableRoles(w http.ResponseWriter, r *http.Request, roles []string, current []string) bool {
+	claims := claimsFromContext(r.Context())
+	repo := db.NewRoleRepository(db.DB)
+	for _, name := range roles {
+		if slices.Contains(current, name) {
+			continue
+		}
+		role, err := repo.GetRole(r.Context(), name)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			log.Println("Get role error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return false
+		}
+		if p := missingPermission(claims, role.Permissions); p != "" {
+			writeError(w, http.StatusForbidden, "Role "+name+" grants a permission you do not have: "+p)
+			return false
+		}
+	}
+	return true
+}
+
+// NewListUsersHandler godoc
+// @Summary Список користувачів
+// @Description Повертає сторінку користувачів з пошуком за частиною логіна (потрібен дозвіл users:manage)
+// @Tags users
+// @Produce json
+// @Param login query string false "Пошук за логіном"
+// @Param status query string false "Стан облікового запису: pending, active або disabled"
+// @Param limit query int false "Кількість записів від 1 до 100 (за замовчуванням 20)"
+// @Param offset query int false "Зсув від початку списку"
+// @Success 200 {object} UserListResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users [get]
+func NewListUsersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := queryLimit(r, defaultUsersLimit, maxUsersLimit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filter := db.UserFilter{
+			Login:  r.URL.Query().Get("login"),
+			Status: r.URL.Query().Get("status"),
+			Limit:  limit,
+			Offset: offset,
+		}
+		switch filter.Status {
+		case "", models.UserStatusPending, models.UserStatusActive, models.UserStatusDisabled:
+		default:
+			writeError(w, http.StatusBadRequest, "Invalid status")
+			return
+		}
+
+		repo := db.NewUserRepository(db.DB)
+		users, err := repo.ListUsers(r.Context(), filter)
+		if err != nil {
+			log.Println("List users error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		total, err := repo.CountUsers(r.Context(), filter)
+		if err != nil {
+			log.Println("Count users error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		ids := make([]string, 0, len(users))
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+		roles, err := db.NewRoleRepository(db.DB).GetRolesForUsers(r.Context(), ids)
+		if err != nil {
+			log.Println("List users error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		resp := UserListResponse{
+			Users:  make([]UserDetailsResponse, 0, len(users)),
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		}
+		for i := range users {
+			resp.Users = append(resp.Users, newUserDetailsResponse(&users[i], roles[users[i].ID]))
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NewGetUserHandler godoc
+// @Summary Отримання користувача
+// @Description Повертає користувача за ID (потрібен дозвіл users:manage)
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Success 200 {object} UserDetailsResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id} [get]
+func NewGetUserHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadUser(w, r)
+		if !ok {
+			return
+		}
+
+		roles, err := db.NewRoleRepository(db.DB).GetUserRoles(r.Context(), user.ID)
+		if err != nil {
+			log.Println("Get user roles error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newUserDetailsResponse(user, roles))
+	}
+}
+
+// NewUpdateUserHandler godoc
+// @Summary Зміна користувача
+// @Description Замінює ролі та/або email користувача; поля, яких немає в запиті, не змінюються (потрібен дозвіл users:manage). Нові ролі потрапляють у токени при наступному вході або оновленні токенів.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Param request body UpdateUserRequest true "Нові ролі та/або email"
+// @Success 200 {object} UserDetailsResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит, email або невідома роль"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 409 {object} models.ErrorResponse "Email уже використовується"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id} [patch]
+func NewUpdateUserHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req UpdateUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Roles == nil && req.Email == nil) {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		var email string
+		if req.Email != nil {
+			var ok bool
+			if email, ok = utils.NormalizeEmail(*req.Email); !ok {
+				writeError(w, http.StatusBadRequest, "Invalid email")
+				return
+			}
+		}
+
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+
+		claims := claimsFromContext(r.Context())
+		if req.Roles != nil && claims != nil && claims.ID == user.ID &&
+			claims.HasRole(models.RoleAdmin) && !slices.Contains(*req.Roles, models.RoleAdmin) {
+			writeError(w, http.StatusBadRequest, "You cannot remove your own admin role")
+			return
+		}
+
+		roleRepo := db.NewRoleRepository(db.DB)
+		previous, err := roleRepo.GetUserRoles(r.Context(), user.ID)
+		if err != nil {
+			log.Println("Update user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if req.Roles != nil && !checkGrantableRoles(w, r, *req.Roles, previous) {
+			return
+		}
+
+		if req.Email != nil && email != user.Email {
+			previous := user.Email
+			user.Email = email
+			// Нова адреса ще не підтверджена.
+			user.EmailVerifiedAt = nil
+			if err := db.NewUserRepository(db.DB).UpdateUser(r.Context(), user); err != nil {
+				if errors.Is(err, db.ErrEmailExists) {
+					writeError(w, http.StatusConflict, "Email is already in use")
+					return
+				}
+				log.Println("Update user error:", err)
+				writeError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+			// Токени з листів, надісланих на стару адресу, більше не дійсні.
+			if err := db.NewUserTokenRepository(db.DB).DeleteUserTokens(r.Context(), user.ID); err != nil {
+				log.Println("Update user error:", err)
+				writeError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+
+			event := targetAuditEvent(models.AuditEmailChange, claims, user)
+			event.Details = "email: " + previous + " -> " + email
+			recordAudit(r, proxies, event)
+		}
+
+		if req.Roles == nil {
+			writeJSON(w, http.StatusOK, newUserDetailsResponse(user, previous))
+			return
+		}
+		if err := roleRepo.SetUserRoles(r.Context(), user.ID, *req.Roles); err != nil {
+			if errors.Is(err, db.ErrUnknownRole) {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			log.Println("Update user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		updated, err := roleRepo.GetUserRoles(r.Context(), user.ID)
+		if err != nil {
+			log.Println("Update user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		event := targetAuditEvent(models.AuditRolesChange, claims, user)
+		event.Details = "roles: " + strings.Join(previous, ",") + " -> " + strings.Join(updated, ",")
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, newUserDetailsResponse(user, updated))
+	}
+}
+
+// NewResetPasswordHandler godoc
+// @Summary Скидання пароля користувача
+// @Description Встановлює користувачу новий пароль, що відповідає політиці паролів, і відкликає всі його токени (потрібен дозвіл users:manage)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Param request body ResetPasswordRequest true "Новий пароль"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит або пароль не відповідає політиці"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id}/password [put]
+func NewResetPasswordHandler(policy *passwords.Policy, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+
+		if !checkNewPassword(w, r.Context(), policy, user, req.Password) {
+			return
+		}
+		if err := setPassword(r.Context(), policy, user, req.Password); err != nil {
+			log.Println("Reset password error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		recordAudit(r, proxies, targetAuditEvent(models.AuditPasswordReset, claimsFromContext(r.Context()), user))
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "Password updated"})
+	}
+}
+
+// NewDeleteUserHandler godoc
+// @Summary Видалення користувача
+// @Description Видаляє користувача за ID (потрібен дозвіл users:manage)
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id} [delete]
+func NewDeleteUserHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+
+		if claims := claimsFromContext(r.Context()); claims != nil && claims.ID == user.ID {
+			writeError(w, http.StatusBadRequest, "You cannot delete your own account")
+			return
+		}
+
+		// Access токени не зберігаються в БД, тож їх треба відкликати до
+		// видалення користувача; решту його даних видаляє каскад.
+		if err := revokeAllUserTokens(r.Context(), user.ID); err != nil {
+			log.Println("Delete user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if err := db.NewUserRepository(db.DB).DeleteUser(r.Context(), user.ID); err != nil {
+			log.Println("Delete user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		recordAudit(r, proxies, targetAuditEvent(models.AuditUserDelete, claimsFromContext(r.Context()), user))
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "User deleted"})
+	}
+}
+
+// NewRevokeUserTokensHandler godoc
+// @Summary Відкликання всіх токенів користувача
+// @Description Робить недійсними всі access і refresh токени, видані користувачу до цього моменту, і завершує всі його сесії (потрібен дозвіл users:manage)
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id}/revoke-tokens [post]
+func NewRevokeUserTokensHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := revokeAllUserTokens(r.Context(), user.ID); err != nil {
+			log.Println("Revoke user tokens error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		recordAudit(r, proxies, targetAuditEvent(models.AuditUserTokensRevoke, claimsFromContext(r.Context()), user))
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "All tokens revoked"})
+	}
+}
+
+// NewUnlockUserHandler godoc
+// @Summary Розблокування входу користувача
+// @Description Знімає блокування і затримку входу, накладені після невдалих спроб (потрібен дозвіл users:manage)
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id}/unlock [post]
+func NewUnlockUserHandler(guard *lockout.Guard, proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := guard.Unlock(r.Context(), user.Login); err != nil {
+			log.Println("Unlock user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		recordAudit(r, proxies, targetAuditEvent(models.AuditUserUnlock, claimsFromContext(r.Context()), user))
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "User unlocked"})
+	}
+}
+
+// NewDisableUserHandler godoc
+// @Summary Вимкнення користувача
+// @Description Вимикає обліковий запис, не видаляючи його: усі токени і сесії користувача відкликаються, а вхід, оновлення токенів і API ключі перестають працювати до ввімкнення (потрібен дозвіл users:manage)
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse "Спроба вимкнути власний обліковий запис"
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id}/disable [post]
+func NewDisableUserHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+
+		claims := claimsFromContext(r.Context())
+		if claims != nil && claims.ID == user.ID {
+			writeError(w, http.StatusBadRequest, "You cannot disable your own account")
+			return
+		}
+		if user.Status == models.UserStatusDisabled {
+			writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "User disabled"})
+			return
+		}
+
+		user.Status = models.UserStatusDisabled
+		if err := db.NewUserRepository(db.DB).UpdateUser(r.Context(), user); err != nil {
+			log.Println("Disable user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if err := revokeAllUserTokens(r.Context(), user.ID); err != nil {
+			log.Println("Disable user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		recordAudit(r, proxies, targetAuditEvent(models.AuditUserDisable, claims, user))
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "User disabled"})
+	}
+}
+
+// NewEnableUserHandler godoc
+// @Summary Ввімкнення користувача
+// @Description Робить обліковий запис активним: знову вмикає вимкнений або активує той, що чекає на підтвердження email (потрібен дозвіл users:manage)
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id}/enable [post]
+func NewEnableUserHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+		if user.Status == models.UserStatusActive {
+			writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "User enabled"})
+			return
+		}
+
+		previous := user.Status
+		user.Status = models.UserStatusActive
+		if err := db.NewUserRepository(db.DB).UpdateUser(r.Context(), user); err != nil {
+			log.Println("Enable user error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		event := targetAuditEvent(models.AuditUserEnable, claimsFromContext(r.Context()), user)
+		event.Details = "status: " + previous + " -> " + user.Status
+		recordAudit(r, proxies, event)
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "User enabled"})
+	}
+}
+
+// NewResetUserMFAHandler godoc
+// @Summary Скидання MFA користувача
+// @Description Вимикає MFA користувача, наприклад після втрати пристрою (потрібен дозвіл users:manage). Якщо роль вимагає MFA, при наступному вході користувач налаштує його заново.
+// @Tags users
+// @Produce json
+// @Param id path string true "ID користувача"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} models.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} models.ErrorResponse "Користувача не знайдено"
+// @Failure 500 {object} models.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/users/{id}/mfa [delete]
+func NewResetUserMFAHandler(proxies utils.TrustedProxies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := loadManagedUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := db.NewMFARepository(db.DB).DeleteUserMFA(r.Context(), user.ID); err != nil {
+			log.Println("Reset user MFA error:", err)
+			writeError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		recordAudit(r, proxies, targetAuditEvent(models.AuditUserMFAReset, claimsFromContext(r.Context()), user))
+
+		writeJSON(w, http.StatusOK, models.SuccessResponse{Message: "MFA reset"})
+	}
+}
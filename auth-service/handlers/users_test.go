@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/lockout"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+func TestAdminUserActions_RecordAudit(t *testing.T) {
+	setupHandlerTestDB(t)
+	ctx := context.Background()
+	target := &models.User{ID: "target", Login: "target", Password: "x", CreatedAt: time.Now()}
+	require.NoError(t, db.NewUserRepository(db.DB).CreateUser(ctx, target))
+
+	guard := lockout.NewGuard(db.NewLoginAttemptRepository(db.DB), lockout.Config{MaxFailures: 3, LockoutDuration: time.Minute})
+	var proxies utils.TrustedProxies
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		handler   http.HandlerFunc
+		eventType string
+	}{
+		{"RevokeTokens", http.MethodPost, "/api/users/target/revoke-tokens", NewRevokeUserTokensHandler(proxies), models.AuditUserTokensRevoke},
+		{"Unlock", http.MethodPost, "/api/users/target/unlock", NewUnlockUserHandler(guard, proxies), models.AuditUserUnlock},
+		{"ResetMFA", http.MethodDelete, "/api/users/target/mfa", NewResetUserMFAHandler(proxies), models.AuditUserMFAReset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newAuthedRequest(t, tt.method, tt.path, nil, models.PermissionUsersManage)
+			r.SetPathValue("id", target.ID)
+			w := httptest.NewRecorder()
+			tt.handler(w, r)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			events, err := db.NewAuditRepository(db.DB).ListEvents(ctx, db.AuditFilter{Type: tt.eventType, Limit: 10})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, "author", events[0].ActorID)
+			assert.Equal(t, target.ID, events[0].TargetID)
+			assert.Equal(t, target.Login, events[0].TargetLogin)
+		})
+	}
+}
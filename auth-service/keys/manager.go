@@ -0,0 +1,230 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+var (
+	ErrActiveKey = errors.New("the active signing key cannot be retired, promote another key first")
+	ErrRetired   = errors.New("signing key is retired")
+	ErrRetiring  = errors.New("signing key is scheduled for retirement")
+)
+
+// Manager керує ключами підпису, що зберігаються в БД, і синхронізує з ними
+// KeySet, яким користується JWTMaker. Ключ із конфігурації (JWT_SECRET_KEY або
+// JWT_SIGNING_KEY_FILE) підписує токени, поки в БД немає активного ключа.
+// Після першої промоції ключа з БД він лишається дійсним для перевірки ще
+// overlap, а потім виводиться з обігу, як і будь-який замінений ключ.
+type Manager struct {
+	repo     *db.SigningKeyRepository
+	set      *token.KeySet
+	fallback *token.SigningKey
+	overlap  time.Duration
+
+	sealer *Sealer
+}
+
+// NewManager створює менеджер. overlap — скільки попередній активний ключ
+// лишається дійсним для перевірки після ротації, якщо дату не задано явно.
+func NewManager(repo *db.SigningKeyRepository, fallback *token.SigningKey, overlap time.Duration) *Manager {
+	return &Manager{
+		repo:     repo,
+		set:      token.NewKeySet(fallback),
+		fallback: fallback,
+		overlap:  overlap,
+	}
+}
+
+// WithSealer вмикає шифрування приватних ключів у БД. Ключі, збережені
+// раніше відкритим текстом, шифруються при наступному Reload.
+func (m *Manager) WithSealer(sealer *Sealer) *Manager {
+	m.sealer = sealer
+	return m
+}
+
+func (m *Manager) KeySet() *token.KeySet {
+	return m.set
+}
+
+// Reload перечитує ключі з БД.
+func (m *Manager) Reload(ctx context.Context) error {
+	stored, err := m.repo.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	active := m.fallback
+	others := []*token.SigningKey{}
+	var firstActivated *time.Time
+	for _, sk := range stored {
+		if sk.ActivatedAt != nil && (firstActivated == nil || sk.ActivatedAt.Before(*firstActivated)) {
+			firstActivated = sk.ActivatedAt
+		}
+		if sk.Status == models.KeyStatusRetired {
+			continue
+		}
+		encoded, err := m.openPrivateKey(ctx, &sk)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", sk.ID, err)
+			continue
+		}
+		key, err := token.UnmarshalPrivateKey(sk.ID, sk.Algorithm, encoded)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", sk.ID, err)
+			continue
+		}
+		if sk.RetiresAt != nil {
+			key.RetiresAt = *sk.RetiresAt
+		}
+		if sk.Status == models.KeyStatusActive {
+			active = key
+			continue
+		}
+		others = append(others, key)
+	}
+	if active != m.fallback {
+		// Ключ із конфігурації не можна вивести через CLI, тож його дата
+		// виведення рахується від першої промоції ключа з БД.
+		fallback := *m.fallback
+		if firstActivated != nil {
+			fallback.RetiresAt = firstActivated.Add(m.overlap)
+		}
+		if fallback.Usable(time.Now()) {
+			others = append(others, &fallback)
+		}
+	}
+
+	m.set.Replace(active, others...)
+	return nil
+}
+
+// openPrivateKey повертає розшифрований приватний ключ sk. Ключ, збережений
+// відкритим текстом, шифрується в БД, якщо шифрування ввімкнено.
+func (m *Manager) openPrivateKey(ctx context.Context, sk *models.SigningKey) (string, error) {
+	if isSealed(sk.PrivateKey) {
+		if m.sealer == nil {
+			return "", ErrNoKEK
+		}
+		return m.sealer.Open(sk.ID, sk.PrivateKey)
+	}
+	if m.sealer != nil {
+		sealed, err := m.sealer.Seal(sk.ID, sk.PrivateKey)
+		if err != nil {
+			return "", err
+		}
+		if err := m.repo.UpdatePrivateKey(ctx, sk.ID, sealed); err != nil {
+			log.Printf("Encrypt signing key %s error: %v", sk.ID, err)
+		}
+	}
+	return sk.PrivateKey, nil
+}
+
+// Run періодично перечитує ключі, щоб зміни, зроблені через CLI або іншим
+// екземпляром сервісу, застосовувались без перезапуску.
+func (m *Manager) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := m.Reload(context.Background()); err != nil {
+			log.Println("Reload signing keys error:", err)
+		}
+	}
+}
+
+func (m *Manager) List(ctx context.Context) ([]models.SigningKey, error) {
+	return m.repo.ListSigningKeys(ctx)
+}
+
+// Generate створює новий ключ. Неактивований ключ одразу публікується в JWKS,
+// щоб сервіси встигли його отримати до промоції.
+func (m *Manager) Generate(ctx context.Context, alg string, activate bool) (*models.SigningKey, error) {
+	key, err := token.GenerateKey("", alg)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := token.MarshalPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if m.sealer != nil {
+		if encoded, err = m.sealer.Seal(key.ID, encoded); err != nil {
+			return nil, err
+		}
+	}
+
+	sk := &models.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Method.Alg(),
+		PrivateKey: encoded,
+		Status:     models.KeyStatusPending,
+		CreatedAt:  time.Now(),
+	}
+	if err := m.repo.CreateSigningKey(ctx, sk); err != nil {
+		return nil, err
+	}
+
+	if activate {
+		return m.Promote(ctx, sk.ID, nil)
+	}
+	return sk, m.Reload(ctx)
+}
+
+// Promote робить ключ активним. Попередній активний ключ приймається для
+// перевірки до retirePreviousAt (за замовчуванням — now + overlap).
+func (m *Manager) Promote(ctx context.Context, id string, retirePreviousAt *time.Time) (*models.SigningKey, error) {
+	sk, err := m.repo.GetSigningKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Ключ, дату виведення якого вже призначено, не повертається в обіг:
+	// активація скинула б retires_at.
+	if sk.Status == models.KeyStatusRetired || (sk.RetiresAt != nil && !sk.RetiresAt.After(time.Now())) {
+		return nil, ErrRetired
+	}
+	if sk.RetiresAt != nil {
+		return nil, ErrRetiring
+	}
+
+	retireAt := time.Now().Add(m.overlap)
+	if retirePreviousAt != nil {
+		retireAt = *retirePreviousAt
+	}
+	if err := m.repo.ActivateSigningKey(ctx, id, retireAt); err != nil {
+		return nil, err
+	}
+	if err := m.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return m.repo.GetSigningKey(ctx, id)
+}
+
+// Retire виводить ключ з обігу в момент at (за замовчуванням — негайно).
+func (m *Manager) Retire(ctx context.Context, id string, at *time.Time) (*models.SigningKey, error) {
+	sk, err := m.repo.GetSigningKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sk.Status == models.KeyStatusActive {
+		return nil, ErrActiveKey
+	}
+
+	retireAt := time.Now()
+	if at != nil {
+		retireAt = *at
+	}
+	if err := m.repo.RetireSigningKey(ctx, id, retireAt); err != nil {
+		return nil, err
+	}
+	if err := m.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return m.repo.GetSigningKey(ctx, id)
+}
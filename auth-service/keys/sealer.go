@@ -0,0 +1,76 @@
+package keys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix позначає приватний ключ, зашифрований ключем шифрування ключів
+// (KEK). Ключі без префікса збережено до того, як KEK було налаштовано.
+const sealedPrefix = "enc:v1:"
+
+// minKEKLength — KEK задається рядком, з якого ключ AES-256 отримується через
+// SHA-256, тож він має бути не коротшим за сам ключ.
+const minKEKLength = 32
+
+var ErrNoKEK = errors.New("signing key is encrypted but no key encryption key is configured")
+
+// Sealer шифрує приватні ключі підпису перед збереженням у БД (AES-256-GCM),
+// щоб копії файлу БД було недостатньо для підробки токенів.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+func NewSealer(kek string) (*Sealer, error) {
+	if len(kek) < minKEKLength {
+		return nil, fmt.Errorf("key encryption key must be at least %d characters long", minKEKLength)
+	}
+	sum := sha256.Sum256([]byte(kek))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal шифрує закодований приватний ключ. id ключа входить в автентифіковані
+// дані, тож зашифрований ключ не можна підставити під інший kid.
+func (s *Sealer) Seal(id string, plaintext string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(plaintext), []byte(id))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open — зворотна операція до Seal.
+func (s *Sealer) Open(id string, stored string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("error decoding encrypted signing key: %w", err)
+	}
+	if len(data) < s.aead.NonceSize() {
+		return "", errors.New("encrypted signing key is truncated")
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting signing key: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func isSealed(stored string) bool {
+	return strings.HasPrefix(stored, sealedPrefix)
+}
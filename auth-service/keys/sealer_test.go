@@ -0,0 +1,34 @@
+package keys
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealer(t *testing.T) {
+	sealer, err := NewSealer(strings.Repeat("k", minKEKLength))
+	require.NoError(t, err)
+
+	sealed, err := sealer.Seal("kid-1", "private key")
+	require.NoError(t, err)
+	assert.True(t, isSealed(sealed))
+	assert.NotContains(t, sealed, "private key")
+
+	plaintext, err := sealer.Open("kid-1", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "private key", plaintext)
+
+	_, err = sealer.Open("kid-2", sealed)
+	assert.Error(t, err, "a sealed key must not open under another kid")
+
+	other, err := NewSealer(strings.Repeat("x", minKEKLength))
+	require.NoError(t, err)
+	_, err = other.Open("kid-1", sealed)
+	assert.Error(t, err, "a sealed key must not open with another KEK")
+
+	_, err = NewSealer("short")
+	assert.Error(t, err)
+}
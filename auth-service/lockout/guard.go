@@ -0,0 +1,177 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/db"
+)
+
+type Config struct {
+	// MaxFailures — скільки невдалих спроб поспіль для логіна призводять до блокування.
+	MaxFailures int
+	// LockoutDuration — тривалість блокування; також через стільки часу без
+	// невдач лічильники скидаються.
+	LockoutDuration time.Duration
+	// BackoffBase — затримка після першої невдачі; кожна наступна подвоює її.
+	BackoffBase time.Duration
+	// MaxIPFailures — скільки невдач з однієї IP адреси (за будь-якими логінами)
+	// допускається до тимчасової відмови.
+	MaxIPFailures int
+}
+
+// Block описує, чому спробу входу зараз не можна виконати.
+type Block struct {
+	// Locked — логін заблоковано (423); інакше діє затримка або ліміт IP (429).
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+// Guard обмежує підбір паролів: рахує невдалі входи окремо для логіна і для
+// IP адреси. Після кожної невдачі логін чекає експоненційно довшу затримку,
+// після MaxFailures — блокується на LockoutDuration. Лічильники ведуться й
+// для неіснуючих логінів, тож відповіді не видають, чи існує користувач.
+type Guard struct {
+	repo *db.LoginAttemptRepository
+	cfg  Config
+}
+
+func NewGuard(repo *db.LoginAttemptRepository, cfg Config) *Guard {
+	return &Guard{repo: repo, cfg: cfg}
+}
+
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Reserve перевіряє, чи можна зараз спробувати увійти, і одразу враховує
+// спробу як невдалу — ще до перевірки пароля, тож паралельні запити не
+// обходять затримку і блокування. Після вдалого входу треба викликати
+// Success, а якщо пароль так і не перевірено (помилка БД) — Release.
+func (g *Guard) Reserve(ctx context.Context, login string, ip string, now time.Time) (*Block, error) {
+	block, failures, err := g.check(ctx, login, ip, now)
+	if err != nil || block != nil {
+		return block, err
+	}
+
+	resetBefore := now.Add(-g.cfg.LockoutDuration)
+	// Ліміт лише на одну спробу більший за щойно прочитаний лічильник: якщо
+	// паралельний запит устиг зарезервувати спробу, цей має чекати затримку.
+	ok, err := g.repo.ReserveAttempt(ctx, loginKey(login), min(failures+1, g.cfg.MaxFailures), now, resetBefore)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return g.raced(ctx, login, ip, now)
+	}
+	if ip == "" {
+		return nil, nil
+	}
+	ok, err = g.repo.ReserveAttempt(ctx, ipKey(ip), g.cfg.MaxIPFailures, now, resetBefore)
+	if err != nil || !ok {
+		if refundErr := g.repo.RefundAttempt(ctx, loginKey(login)); refundErr != nil {
+			return nil, refundErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return g.raced(ctx, login, ip, now)
+	}
+	return nil, nil
+}
+
+// raced повертає Block для спроби, яку не вдалося зарезервувати, бо
+// паралельний запит змінив лічильники між перевіркою і резервуванням.
+func (g *Guard) raced(ctx context.Context, login string, ip string, now time.Time) (*Block, error) {
+	block, _, err := g.check(ctx, login, ip, now)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		block = &Block{RetryAfter: g.backoff(1)}
+	}
+	return block, nil
+}
+
+// check повертає Block, якщо спроба входу зараз заборонена, і кількість
+// чинних невдач логіна.
+func (g *Guard) check(ctx context.Context, login string, ip string, now time.Time) (*Block, int, error) {
+	failures := 0
+	attempt, err := g.repo.GetLoginAttempt(ctx, loginKey(login))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, err
+	}
+	if err == nil && attempt.Failures > 0 && now.Sub(attempt.LastFailureAt) < g.cfg.LockoutDuration {
+		failures = attempt.Failures
+		if attempt.Failures >= g.cfg.MaxFailures {
+			return &Block{Locked: true, RetryAfter: attempt.LastFailureAt.Add(g.cfg.LockoutDuration).Sub(now)}, failures, nil
+		}
+		if wait := attempt.LastFailureAt.Add(g.backoff(attempt.Failures)).Sub(now); wait > 0 {
+			return &Block{RetryAfter: wait}, failures, nil
+		}
+	}
+
+	if ip == "" {
+		return nil, failures, nil
+	}
+	attempt, err = g.repo.GetLoginAttempt(ctx, ipKey(ip))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, err
+	}
+	if err == nil && attempt.Failures >= g.cfg.MaxIPFailures {
+		if wait := attempt.LastFailureAt.Add(g.cfg.LockoutDuration).Sub(now); wait > 0 {
+			return &Block{RetryAfter: wait}, failures, nil
+		}
+	}
+	return nil, failures, nil
+}
+
+// backoff — затримка після failures невдач: BackoffBase, 2×, 4×… але не
+// довше за LockoutDuration.
+func (g *Guard) backoff(failures int) time.Duration {
+	delay := g.cfg.BackoffBase
+	for i := 1; i < failures && delay < g.cfg.LockoutDuration; i++ {
+		delay *= 2
+	}
+	return min(delay, g.cfg.LockoutDuration)
+}
+
+// Success скидає лічильник логіна після вдалого входу і повертає спробу,
+// зараховану IP адресі. Решта невдач IP не скидається, щоб один відомий
+// пароль не відкривав з цієї адреси підбір інших облікових записів.
+func (g *Guard) Success(ctx context.Context, login string, ip string) error {
+	if err := g.repo.DeleteLoginAttempt(ctx, loginKey(login)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return g.repo.RefundAttempt(ctx, ipKey(ip))
+}
+
+// Release повертає зарезервовану спробу, якщо пароль не вдалося перевірити.
+func (g *Guard) Release(ctx context.Context, login string, ip string) error {
+	if err := g.repo.RefundAttempt(ctx, loginKey(login)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return g.repo.RefundAttempt(ctx, ipKey(ip))
+}
+
+// Unlock знімає блокування і затримку з логіна.
+func (g *Guard) Unlock(ctx context.Context, login string) error {
+	return g.repo.DeleteLoginAttempt(ctx, loginKey(login))
+}
+
+// Prune видаляє лічильники, які вже скинулися б за часом.
+func (g *Guard) Prune(ctx context.Context, now time.Time) (int64, error) {
+	return g.repo.PruneLoginAttempts(ctx, now.Add(-g.cfg.LockoutDuration))
+}
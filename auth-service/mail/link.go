@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"net/url"
+	"time"
+)
+
+type VerificationConfig struct {
+	// Required — нові користувачі реєструються лише з email і не можуть
+	// увійти, доки не підтвердять його.
+	Required bool
+	// URL — сторінка підтвердження email; токен додається до неї параметром
+	// token. Якщо не задано, лист містить сам токен для
+	// POST /api/auth/email/verify.
+	URL string
+	// TTL — скільки діє токен підтвердження.
+	TTL time.Duration
+	// Interval — не частіше ніж раз на скільки користувачу надсилається лист.
+	Interval time.Duration
+}
+
+// TokenLink додає до сторінки base параметр token або повертає порожній
+// рядок, якщо base не задано.
+func TokenLink(base string, token string) (string, error) {
+	if base == "" {
+		return "", nil
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Link повертає посилання на сторінку підтвердження email з токеном або
+// порожній рядок, якщо URL не задано.
+func (c VerificationConfig) Link(token string) (string, error) {
+	return TokenLink(c.URL, token)
+}
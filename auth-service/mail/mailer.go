@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// SMTPAddr — адреса SMTP сервера host:port. Якщо не задано, листи
+	// записуються у File або в лог.
+	SMTPAddr string
+	// Username і Password — облікові дані SMTP; без Username сервер
+	// використовується без автентифікації (як MailHog).
+	Username string
+	Password string
+	// From — адреса відправника.
+	From string
+	// File — файл, у який дописуються листи, коли SMTPAddr не задано.
+	File string
+}
+
+// Message — лист з текстовим тілом.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer надсилає листи користувачам.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New створює Mailer за конфігурацією: SMTP, якщо задано SMTPAddr, інакше
+// запис у файл File або, якщо і він не заданий, у лог.
+func New(cfg Config) (Mailer, error) {
+	if cfg.SMTPAddr != "" {
+		return NewSMTPMailer(cfg), nil
+	}
+	if cfg.File != "" {
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("error opening mail file: %w", err)
+		}
+		return NewFileMailer(f, cfg.From), nil
+	}
+	return NewFileMailer(log.Writer(), cfg.From), nil
+}
+
+// FileMailer записує листи у w замість надсилання: для розробки і тестів.
+type FileMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewFileMailer(w io.Writer, from string) *FileMailer {
+	return &FileMailer{w: w, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	data, err := compose(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing mail: %w", err)
+	}
+	return nil
+}
+
+// compose формує лист у форматі RFC 5322 з тілом у UTF-8.
+func compose(from string, msg Message, date time.Time) ([]byte, error) {
+	// Переноси рядків у заголовках дозволили б дописати до листа чужі.
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("invalid mail header")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes(), nil
+}
@@ -7,27 +7,50 @@
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.basic BasicAuth
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
 	_ "ksv/rest-mikroservice/auth-service/docs"
 
 	"github.com/ianschenck/envflag"
 	"github.com/joho/godotenv"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"golang.org/x/crypto/bcrypt"
 
 	_ "github.com/swaggo/files"
 
 	"ksv/rest-mikroservice/auth-service/db"
 	"ksv/rest-mikroservice/auth-service/handlers"
+	"ksv/rest-mikroservice/auth-service/keys"
+	"ksv/rest-mikroservice/auth-service/lockout"
+	"ksv/rest-mikroservice/auth-service/mail"
+	"ksv/rest-mikroservice/auth-service/mfa"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/oidc"
+	"ksv/rest-mikroservice/auth-service/passwords"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/auth-service/utils"
 )
 
 const (
 	port = ":8081"
+
+	pruneInterval = time.Hour
 )
 
 func main() {
@@ -38,38 +61,410 @@ func main() {
 	}
 
 	var secretKey = envflag.String("JWT_SECRET_KEY", "01234567890123456789012345678901", "Cекретний ключ, що використовується для підписання JWT ")
-	if len(*secretKey) < 32 {
-		log.Fatal("JWT_SECRET_KEY must be at least 32 characters long")
-	}
+	var signingKeyFile = envflag.String("JWT_SIGNING_KEY_FILE", "", "PEM файл з приватним ключем RSA, EC або Ed25519; якщо не задано, використовується HS256 з JWT_SECRET_KEY")
+	var keyID = envflag.String("JWT_KEY_ID", "", "Ідентифікатор ключа (kid); за замовчуванням — JWK thumbprint")
+	var keyOverlap = envflag.Duration("JWT_KEY_OVERLAP_TIME", 24*time.Hour, "Скільки попередній ключ лишається дійсним для перевірки після ротації")
+	var keyEncryptionKey = envflag.String("JWT_KEY_ENCRYPTION_KEY", "", "Ключ (не коротший за 32 символи), яким шифруються приватні ключі підпису в БД")
+	var keyEncryptionKeyFile = envflag.String("JWT_KEY_ENCRYPTION_KEY_FILE", "", "Файл з ключем шифрування приватних ключів підпису; замість JWT_KEY_ENCRYPTION_KEY")
+	var keyReloadInterval = envflag.Duration("JWT_KEY_RELOAD_INTERVAL", time.Minute, "Як часто перечитувати ключі підпису з БД")
+	var maxLoginFailures = envflag.Int("LOGIN_MAX_FAILURES", 5, "Кількість невдалих входів поспіль, після якої логін блокується")
+	var lockoutDuration = envflag.Duration("LOGIN_LOCKOUT_TIME", 15*time.Minute, "Тривалість блокування логіна після невдалих входів")
+	var loginBackoff = envflag.Duration("LOGIN_BACKOFF_BASE", time.Second, "Затримка після першого невдалого входу; кожна наступна подвоюється")
+	var maxIPFailures = envflag.Int("LOGIN_MAX_IP_FAILURES", 50, "Кількість невдалих входів з однієї IP адреси до тимчасової відмови")
+	var hashAlgorithm = envflag.String("PASSWORD_HASH_ALGORITHM", "argon2id", "Алгоритм для нових хешів паролів: argon2id або bcrypt")
+	var argon2Memory = envflag.Int("ARGON2_MEMORY", 64*1024, "Пам'ять argon2id у KiB")
+	var argon2Time = envflag.Int("ARGON2_TIME", 3, "Кількість ітерацій argon2id")
+	var argon2Parallelism = envflag.Int("ARGON2_PARALLELISM", 2, "Кількість потоків argon2id")
+	var bcryptCost = envflag.Int("BCRYPT_COST", bcrypt.DefaultCost, "Вартість bcrypt")
+	var passwordMinLength = envflag.Int("PASSWORD_MIN_LENGTH", 10, "Мінімальна довжина пароля")
+	var passwordMinClasses = envflag.Int("PASSWORD_MIN_CHAR_CLASSES", 3, "Скільки класів символів (малі, великі літери, цифри, інші) має містити пароль")
+	var passwordHistory = envflag.Int("PASSWORD_HISTORY", 5, "Скільки останніх паролів не можна використати повторно")
+	var passwordBlocklist = envflag.String("PASSWORD_BLOCKLIST_FILE", "", "Файл поширених або скомпрометованих паролів, по одному на рядок; за замовчуванням — вбудований список")
+	var mfaIssuer = envflag.String("MFA_ISSUER", "rest-mikroservice", "Назва сервісу в застосунку-автентифікаторі")
+	var mfaChallengeTime = envflag.Duration("MFA_CHALLENGE_TIME", 5*time.Minute, "Скільки діє токен другого кроку входу")
+	var mfaMaxAttempts = envflag.Int("MFA_MAX_ATTEMPTS", 5, "Скільки кодів можна ввести з одним токеном другого кроку")
+	var oidcIssuer = envflag.String("OIDC_ISSUER", "http://localhost:8081", "Видавець токенів (iss) і базова адреса ендпоінтів у /.well-known/openid-configuration")
+	var jwtAudience = envflag.String("JWT_AUDIENCE", "rest-mikroservice", "Аудиторія (aud) access токенів; токени з іншою aud не приймаються")
+	var authorizationCodeTime = envflag.Duration("OIDC_CODE_TIME", time.Minute, "Скільки діє код авторизації grant authorization_code")
+	var mailSMTPAddr = envflag.String("MAIL_SMTP_ADDR", "", "SMTP сервер для листів у форматі host:port, наприклад localhost:1025 для MailHog; якщо не задано, листи пишуться в MAIL_FILE або в лог")
+	var mailSMTPUsername = envflag.String("MAIL_SMTP_USERNAME", "", "Логін SMTP; якщо не задано, автентифікація не використовується")
+	var mailSMTPPassword = envflag.String("MAIL_SMTP_PASSWORD", "", "Пароль SMTP")
+	var mailFrom = envflag.String("MAIL_FROM", "no-reply@localhost", "Адреса відправника листів")
+	var mailFile = envflag.String("MAIL_FILE", "", "Файл, у який дописуються листи, коли MAIL_SMTP_ADDR не задано")
+	var passwordResetURL = envflag.String("PASSWORD_RESET_URL", "", "Сторінка введення нового пароля; токен скидання додається параметром token. Якщо не задано, лист містить сам токен")
+	var passwordResetTime = envflag.Duration("PASSWORD_RESET_TIME", 30*time.Minute, "Скільки діє токен скидання пароля")
+	var passwordResetInterval = envflag.Duration("PASSWORD_RESET_INTERVAL", time.Minute, "Не частіше ніж раз на скільки надсилати користувачу лист скидання пароля")
+	var emailVerificationRequired = envflag.Bool("EMAIL_VERIFICATION_REQUIRED", true, "Реєстрація лише з email; увійти можна після підтвердження email за посиланням з листа")
+	var emailVerificationURL = envflag.String("EMAIL_VERIFICATION_URL", "", "Сторінка підтвердження email; токен додається параметром token. Якщо не задано, лист містить сам токен")
+	var emailVerificationTime = envflag.Duration("EMAIL_VERIFICATION_TIME", 24*time.Hour, "Скільки діє токен підтвердження email")
+	var emailVerificationInterval = envflag.Duration("EMAIL_VERIFICATION_INTERVAL", time.Minute, "Не частіше ніж раз на скільки повторно надсилати лист підтвердження email")
+	var impersonationTime = envflag.Duration("IMPERSONATION_TOKEN_TIME", 10*time.Minute, "Скільки діє токен, виданий співробітнику підтримки для входу від імені користувача")
+	var trustedProxies = envflag.String("TRUSTED_PROXIES", "127.0.0.1,::1", "IP адреси або CIDR проксі, яким довіряємо X-Forwarded-For")
 
 	envflag.Parse()
 
+	hasher, err := newPasswordHasher(*hashAlgorithm, *argon2Memory, *argon2Time, *argon2Parallelism, *bcryptCost)
+	if err != nil {
+		log.Fatalf("Failed to configure password hashing: %v", err)
+	}
+	utils.SetHasher(hasher)
+
+	proxies, err := utils.ParseTrustedProxies(*trustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to parse TRUSTED_PROXIES: %v", err)
+	}
+
+	var signingKey *token.SigningKey
+	if *signingKeyFile != "" {
+		signingKey, err = token.LoadPrivateKeyPEM(*keyID, *signingKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load signing key: %v", err)
+		}
+	} else {
+		if len(*secretKey) < 32 {
+			log.Fatal("JWT_SECRET_KEY must be at least 32 characters long")
+		}
+		id := *keyID
+		if id == "" {
+			id = token.DefaultKeyID
+		}
+		signingKey = token.NewHMACKey(id, []byte(*secretKey))
+	}
+
 	db.InitDB("./data/auth.db")
 
 	defer db.DB.Close()
 
-	router := setupRouter(*secretKey)
+	keyManager := keys.NewManager(db.NewSigningKeyRepository(db.DB), signingKey, *keyOverlap)
+	kek, err := loadKEK(*keyEncryptionKey, *keyEncryptionKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load key encryption key: %v", err)
+	}
+	if kek != "" {
+		sealer, err := keys.NewSealer(kek)
+		if err != nil {
+			log.Fatalf("Failed to configure signing key encryption: %v", err)
+		}
+		keyManager.WithSealer(sealer)
+	} else {
+		log.Println("Warning: JWT_KEY_ENCRYPTION_KEY is not set, signing keys are stored unencrypted")
+	}
+	if err := keyManager.Reload(context.Background()); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+
+	policy, err := passwords.NewPolicy(db.NewPasswordHistoryRepository(db.DB), passwords.Config{
+		MinLength:      *passwordMinLength,
+		MinCharClasses: *passwordMinClasses,
+		HistorySize:    *passwordHistory,
+	}, *passwordBlocklist)
+	if err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+
+	if len(os.Args) > 1 {
+		if err := runCommand(keyManager, policy, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	active := keyManager.KeySet().Active()
+	log.Printf("Signing tokens with %s, kid %q", active.Method.Alg(), active.ID)
+	go keyManager.Run(*keyReloadInterval)
+
+	revocations := db.NewRevocationRepository(db.DB)
+	go pruneRevokedTokens(revocations)
+	sessions := db.NewSessionRepository(db.DB)
+	go pruneSessions(sessions)
+
+	guard := lockout.NewGuard(db.NewLoginAttemptRepository(db.DB), lockout.Config{
+		MaxFailures:     *maxLoginFailures,
+		LockoutDuration: *lockoutDuration,
+		BackoffBase:     *loginBackoff,
+		MaxIPFailures:   *maxIPFailures,
+	})
+	go pruneLoginAttempts(guard)
+
+	jwtMaker := token.NewJWTMakerWithKeySet(keyManager.KeySet()).
+		WithRevocationChecker(revocations).
+		WithSessionChecker(sessions).
+		WithIssuer(*oidcIssuer, *jwtAudience)
+
+	mfaCfg := mfa.Config{
+		Issuer:       *mfaIssuer,
+		ChallengeTTL: *mfaChallengeTime,
+		MaxAttempts:  *mfaMaxAttempts,
+	}
+	go pruneMFAChallenges(db.NewMFARepository(db.DB))
+
+	oidcCfg := oidc.Config{
+		Issuer:  *oidcIssuer,
+		CodeTTL: *authorizationCodeTime,
+	}
+	go pruneAuthorizationCodes(db.NewAuthorizationCodeRepository(db.DB))
+
+	mailer, err := mail.New(mail.Config{
+		SMTPAddr: *mailSMTPAddr,
+		Username: *mailSMTPUsername,
+		Password: *mailSMTPPassword,
+		From:     *mailFrom,
+		File:     *mailFile,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure mail: %v", err)
+	}
+	if *mailSMTPAddr == "" {
+		log.Println("Warning: MAIL_SMTP_ADDR is not set, emails are written to MAIL_FILE or the log instead of being sent")
+	}
+
+	for name, value := range map[string]string{"PASSWORD_RESET_URL": *passwordResetURL, "EMAIL_VERIFICATION_URL": *emailVerificationURL} {
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || !u.IsAbs() {
+			log.Fatalf("%s must be an absolute URL", name)
+		}
+	}
+	resetCfg := passwords.ResetConfig{
+		URL:      *passwordResetURL,
+		TTL:      *passwordResetTime,
+		Interval: *passwordResetInterval,
+	}
+	verificationCfg := mail.VerificationConfig{
+		Required: *emailVerificationRequired,
+		URL:      *emailVerificationURL,
+		TTL:      *emailVerificationTime,
+		Interval: *emailVerificationInterval,
+	}
+	go pruneUserTokens(db.NewUserTokenRepository(db.DB))
+
+	router := setupRouter(jwtMaker, keyManager, guard, policy, mfaCfg, oidcCfg, mailer, resetCfg, verificationCfg, *impersonationTime, proxies)
 
 	server := http.Server{
 		Addr:    port,
 		Handler: router,
 	}
 
-	envflag.Parse()
-
 	fmt.Printf("Auth service starting on port %s...\n", port)
 	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("Failed to start service: %v", err)
 	}
 }
 
-func setupRouter(secretKey string) http.Handler {
+func setupRouter(jwtMaker *token.JWTMaker, keyManager *keys.Manager, guard *lockout.Guard, policy *passwords.Policy, mfaCfg mfa.Config, oidcCfg oidc.Config, mailer mail.Mailer, resetCfg passwords.ResetConfig, verificationCfg mail.VerificationConfig, impersonationTime time.Duration, proxies utils.TrustedProxies) http.Handler {
 	mux := http.NewServeMux()
 
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
 
-	mux.HandleFunc("POST /api/auth", handlers.NewAuthHandler(secretKey))
-	mux.HandleFunc("GET /auth/validate", handlers.NewValidateTokenHandler(secretKey))
+	mux.HandleFunc("POST /api/auth", handlers.NewAuthHandler(jwtMaker, mfaCfg, guard, proxies))
+	mux.HandleFunc("POST /api/auth/register", handlers.NewRegisterHandler(policy, mailer, verificationCfg))
+	mux.HandleFunc("POST /api/auth/refresh", handlers.NewRefreshHandler(jwtMaker, proxies))
+	mux.HandleFunc("POST /api/auth/logout", handlers.RequireAuth(jwtMaker, handlers.NewLogoutHandler(proxies)))
+	mux.HandleFunc("POST /api/auth/password", handlers.RequireAccountOwner(jwtMaker, handlers.NewChangePasswordHandler(policy, guard, proxies)))
+	mux.HandleFunc("POST /api/auth/password/forgot", handlers.NewForgotPasswordHandler(mailer, resetCfg, proxies))
+	mux.HandleFunc("POST /api/auth/password/reset", handlers.NewCompletePasswordResetHandler(policy, guard, proxies))
+	mux.HandleFunc("POST /api/auth/email/verify", handlers.NewVerifyEmailHandler(proxies))
+	mux.HandleFunc("POST /api/auth/email/resend", handlers.NewResendVerificationHandler(mailer, verificationCfg))
+	mux.HandleFunc("POST /api/auth/mfa/verify", handlers.NewMFAVerifyHandler(jwtMaker, mfaCfg, guard, proxies))
+	mux.HandleFunc("GET /api/auth/mfa", handlers.RequireUser(jwtMaker, handlers.NewMFAStatusHandler()))
+	mux.HandleFunc("POST /api/auth/mfa/setup", handlers.RequireAccountOwner(jwtMaker, handlers.NewMFASetupHandler(mfaCfg)))
+	mux.HandleFunc("POST /api/auth/mfa/enable", handlers.RequireAccountOwner(jwtMaker, handlers.NewMFAEnableHandler()))
+	mux.HandleFunc("POST /api/auth/mfa/disable", handlers.RequireAccountOwner(jwtMaker, handlers.NewMFADisableHandler(guard, proxies)))
+	mux.HandleFunc("POST /api/auth/mfa/recovery-codes", handlers.RequireAccountOwner(jwtMaker, handlers.NewRegenerateRecoveryCodesHandler(guard, proxies)))
+	mux.HandleFunc("GET /api/auth/api-keys", handlers.RequireUser(jwtMaker, handlers.NewListAPIKeysHandler()))
+	mux.HandleFunc("POST /api/auth/api-keys", handlers.RequireAccountOwner(jwtMaker, handlers.NewCreateAPIKeyHandler()))
+	mux.HandleFunc("DELETE /api/auth/api-keys/{id}", handlers.RequireAccountOwner(jwtMaker, handlers.NewDeleteAPIKeyHandler()))
+	mux.HandleFunc("GET /api/auth/sessions", handlers.RequireUser(jwtMaker, handlers.NewListSessionsHandler()))
+	mux.HandleFunc("POST /api/auth/sessions/revoke-others", handlers.RequireAccountOwner(jwtMaker, handlers.NewRevokeOtherSessionsHandler()))
+	mux.HandleFunc("DELETE /api/auth/sessions/{id}", handlers.RequireAccountOwner(jwtMaker, handlers.NewRevokeSessionHandler()))
+	mux.HandleFunc("GET /api/auth/tenants", handlers.RequireUser(jwtMaker, handlers.NewListUserTenantsHandler()))
+	mux.HandleFunc("POST /api/auth/tenant", handlers.RequireAccountOwner(jwtMaker, handlers.NewSwitchTenantHandler(jwtMaker, proxies)))
+	mux.HandleFunc("POST /api/auth/token/exchange", handlers.RequirePermission(jwtMaker, models.PermissionUsersImpersonate, handlers.NewTokenExchangeHandler(jwtMaker, impersonationTime, proxies)))
+	mux.HandleFunc("GET /auth/validate", handlers.NewValidateTokenHandler(jwtMaker))
+	mux.HandleFunc("GET /.well-known/jwks.json", handlers.NewJWKSHandler(jwtMaker))
+	mux.HandleFunc("POST /oauth/introspect", handlers.NewIntrospectHandler(jwtMaker))
+	mux.HandleFunc("POST /oauth/token", handlers.NewTokenHandler(jwtMaker, proxies))
+	mux.HandleFunc("GET /oauth/authorize", handlers.NewAuthorizeHandler())
+	mux.HandleFunc("POST /oauth/authorize", handlers.NewAuthorizeLoginHandler(oidcCfg, mfaCfg, guard, proxies))
+	mux.HandleFunc("GET /.well-known/openid-configuration", handlers.NewDiscoveryHandler(jwtMaker, oidcCfg))
+	mux.HandleFunc("GET /userinfo", handlers.RequireUser(jwtMaker, handlers.NewUserinfoHandler()))
+	mux.HandleFunc("POST /userinfo", handlers.RequireUser(jwtMaker, handlers.NewUserinfoHandler()))
+
+	mux.HandleFunc("GET /api/users", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewListUsersHandler()))
+	mux.HandleFunc("POST /api/users/import", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewImportUsersHandler(policy, proxies)))
+	mux.HandleFunc("GET /api/users/export", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewExportUsersHandler()))
+	mux.HandleFunc("GET /api/users/{id}", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewGetUserHandler()))
+	mux.HandleFunc("PATCH /api/users/{id}", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewUpdateUserHandler(proxies)))
+	mux.HandleFunc("PUT /api/users/{id}/password", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewResetPasswordHandler(policy, proxies)))
+	mux.HandleFunc("DELETE /api/users/{id}", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewDeleteUserHandler(proxies)))
+	mux.HandleFunc("POST /api/users/{id}/revoke-tokens", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewRevokeUserTokensHandler(proxies)))
+	mux.HandleFunc("GET /api/users/{id}/sessions", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewListUserSessionsHandler()))
+	mux.HandleFunc("DELETE /api/users/{id}/sessions/{sid}", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewRevokeUserSessionHandler()))
+	mux.HandleFunc("POST /api/users/{id}/disable", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewDisableUserHandler(proxies)))
+	mux.HandleFunc("POST /api/users/{id}/enable", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewEnableUserHandler(proxies)))
+	mux.HandleFunc("POST /api/users/{id}/unlock", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewUnlockUserHandler(guard, proxies)))
+	mux.HandleFunc("DELETE /api/users/{id}/mfa", handlers.RequirePermission(jwtMaker, models.PermissionUsersManage, handlers.NewResetUserMFAHandler(proxies)))
+
+	mux.HandleFunc("GET /api/tenants", handlers.RequirePermission(jwtMaker, models.PermissionTenantsManage, handlers.NewListTenantsHandler()))
+	mux.HandleFunc("POST /api/tenants", handlers.RequirePermission(jwtMaker, models.PermissionTenantsManage, handlers.NewCreateTenantHandler(proxies)))
+	mux.HandleFunc("DELETE /api/tenants/{id}", handlers.RequirePermission(jwtMaker, models.PermissionTenantsManage, handlers.NewDeleteTenantHandler(proxies)))
+	mux.HandleFunc("GET /api/tenants/{id}/members", handlers.RequireUser(jwtMaker, handlers.NewListTenantMembersHandler()))
+	mux.HandleFunc("PUT /api/tenants/{id}/members/{user}", handlers.RequireUser(jwtMaker, handlers.NewSetTenantMemberHandler(proxies)))
+	mux.HandleFunc("DELETE /api/tenants/{id}/members/{user}", handlers.RequireUser(jwtMaker, handlers.NewRemoveTenantMemberHandler(proxies)))
+
+	mux.HandleFunc("GET /api/audit", handlers.RequirePermission(jwtMaker, models.PermissionAuditRead, handlers.NewListAuditHandler()))
+	mux.HandleFunc("GET /api/audit/export", handlers.RequirePermission(jwtMaker, models.PermissionAuditRead, handlers.NewExportAuditHandler()))
+
+	mux.HandleFunc("GET /api/keys", handlers.RequirePermission(jwtMaker, models.PermissionKeysManage, handlers.NewListKeysHandler(keyManager)))
+	mux.HandleFunc("POST /api/keys", handlers.RequirePermission(jwtMaker, models.PermissionKeysManage, handlers.NewGenerateKeyHandler(keyManager)))
+	mux.HandleFunc("POST /api/keys/{kid}/promote", handlers.RequirePermission(jwtMaker, models.PermissionKeysManage, handlers.NewPromoteKeyHandler(keyManager)))
+	mux.HandleFunc("POST /api/keys/{kid}/retire", handlers.RequirePermission(jwtMaker, models.PermissionKeysManage, handlers.NewRetireKeyHandler(keyManager)))
+
+	mux.HandleFunc("GET /api/clients", handlers.RequirePermission(jwtMaker, models.PermissionClientsManage, handlers.NewListClientsHandler()))
+	mux.HandleFunc("POST /api/clients", handlers.RequirePermission(jwtMaker, models.PermissionClientsManage, handlers.NewCreateClientHandler()))
+	mux.HandleFunc("GET /api/clients/{id}", handlers.RequirePermission(jwtMaker, models.PermissionClientsManage, handlers.NewGetClientHandler()))
+	mux.HandleFunc("PUT /api/clients/{id}", handlers.RequirePermission(jwtMaker, models.PermissionClientsManage, handlers.NewUpdateClientHandler()))
+	mux.HandleFunc("POST /api/clients/{id}/rotate-secret", handlers.RequirePermission(jwtMaker, models.PermissionClientsManage, handlers.NewRotateClientSecretHandler()))
+	mux.HandleFunc("DELETE /api/clients/{id}", handlers.RequirePermission(jwtMaker, models.PermissionClientsManage, handlers.NewDeleteClientHandler()))
+
+	mux.HandleFunc("GET /api/roles", handlers.RequirePermission(jwtMaker, models.PermissionRolesManage, handlers.NewListRolesHandler()))
+	mux.HandleFunc("POST /api/roles", handlers.RequirePermission(jwtMaker, models.PermissionRolesManage, handlers.NewCreateRoleHandler()))
+	mux.HandleFunc("PUT /api/roles/{name}", handlers.RequirePermission(jwtMaker, models.PermissionRolesManage, handlers.NewUpdateRoleHandler()))
+	mux.HandleFunc("DELETE /api/roles/{name}", handlers.RequirePermission(jwtMaker, models.PermissionRolesManage, handlers.NewDeleteRoleHandler()))
+	mux.HandleFunc("GET /api/permissions", handlers.RequirePermission(jwtMaker, models.PermissionRolesManage, handlers.NewListPermissionsHandler()))
+	mux.HandleFunc("POST /api/permissions", handlers.RequirePermission(jwtMaker, models.PermissionRolesManage, handlers.NewCreatePermissionHandler()))
+	mux.HandleFunc("DELETE /api/permissions/{name}", handlers.RequirePermission(jwtMaker, models.PermissionRolesManage, handlers.NewDeletePermissionHandler()))
 
 	return mux
 }
+
+func newPasswordHasher(algorithm string, memory int, iterations int, parallelism int, cost int) (utils.Hasher, error) {
+	switch algorithm {
+	case "argon2id":
+		if memory < 8*parallelism || iterations < 1 || parallelism < 1 || parallelism > 255 {
+			return nil, fmt.Errorf("invalid argon2id parameters m=%d t=%d p=%d", memory, iterations, parallelism)
+		}
+		return utils.NewArgon2idHasher(uint32(memory), uint32(iterations), uint8(parallelism)), nil
+	case "bcrypt":
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", cost)
+		}
+		return utils.NewBcryptHasher(cost), nil
+	}
+	return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+}
+
+// loadKEK повертає ключ шифрування ключів підпису зі змінної або файлу.
+func loadKEK(value string, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// pruneRevokedTokens періодично видаляє відкликані токени з минулим строком дії.
+func pruneRevokedTokens(repo *db.RevocationRepository) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := repo.PruneExpired(context.Background(), time.Now())
+		if err != nil {
+			log.Println("Prune revoked tokens error:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Pruned %d expired revoked tokens", n)
+		}
+	}
+}
+
+// pruneLoginAttempts періодично видаляє лічильники невдалих входів, що вже скинулися.
+func pruneLoginAttempts(guard *lockout.Guard) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := guard.Prune(context.Background(), time.Now())
+		if err != nil {
+			log.Println("Prune login attempts error:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Pruned %d login attempt counters", n)
+		}
+	}
+}
+
+// pruneMFAChallenges періодично видаляє прострочені токени другого кроку входу.
+func pruneMFAChallenges(repo *db.MFARepository) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := repo.PruneChallenges(context.Background(), time.Now())
+		if err != nil {
+			log.Println("Prune MFA challenges error:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Pruned %d expired MFA challenges", n)
+		}
+	}
+}
+
+// pruneAuthorizationCodes періодично видаляє прострочені коди авторизації.
+func pruneAuthorizationCodes(repo *db.AuthorizationCodeRepository) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := repo.PruneExpired(context.Background(), time.Now())
+		if err != nil {
+			log.Println("Prune authorization codes error:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Pruned %d expired authorization codes", n)
+		}
+	}
+}
+
+// pruneSessions періодично видаляє прострочені і завершені сесії.
+func pruneSessions(repo *db.SessionRepository) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := repo.PruneExpired(context.Background(), time.Now())
+		if err != nil {
+			log.Println("Prune sessions error:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Pruned %d expired sessions", n)
+		}
+	}
+}
+
+// pruneUserTokens періодично видаляє прострочені одноразові токени з листів.
+func pruneUserTokens(repo *db.UserTokenRepository) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := repo.PruneExpired(context.Background(), time.Now())
+		if err != nil {
+			log.Println("Prune user tokens error:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Pruned %d expired user tokens", n)
+		}
+	}
+}
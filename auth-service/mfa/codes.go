@@ -0,0 +1,67 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount — скільки одноразових кодів відновлення видається.
+	RecoveryCodeCount = 10
+
+	recoveryCodeBytes   = 8
+	challengeTokenBytes = 32
+)
+
+var recoveryEncoding = secretEncoding
+
+// GenerateRecoveryCodes створює коди відновлення у вигляді xxxx-xxxx-xxxx і
+// їх хеші для зберігання в БД. Самі коди показуються користувачу один раз.
+func GenerateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([]string, 0, RecoveryCodeCount)
+	for range RecoveryCodeCount {
+		buf := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("error generating recovery code: %w", err)
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(buf))[:12]
+		code := raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode хешує код відновлення, ігноруючи регістр, пробіли і дефіси.
+func HashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewChallengeToken генерує непрозорий токен другого кроку входу і його хеш
+// для зберігання в БД.
+func NewChallengeToken() (string, string, error) {
+	buf := make([]byte, challengeTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("error generating mfa token: %w", err)
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashChallengeToken(raw), nil
+}
+
+func HashChallengeToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
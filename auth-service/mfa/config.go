@@ -0,0 +1,12 @@
+package mfa
+
+import "time"
+
+type Config struct {
+	// Issuer показується в застосунку-автентифікаторі поруч з логіном.
+	Issuer string
+	// ChallengeTTL — скільки діє токен другого кроку входу.
+	ChallengeTTL time.Duration
+	// MaxAttempts — скільки кодів можна ввести з одним токеном.
+	MaxAttempts int
+}
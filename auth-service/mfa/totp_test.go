@@ -0,0 +1,124 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret — секрет з тестових векторів RFC 4226 і RFC 6238 (SHA1).
+var rfcSecret = secretEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC4226(t *testing.T) {
+	// RFC 4226, додаток D.
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, want := range expected {
+		code, err := Code(rfcSecret, int64(counter))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "counter %d", counter)
+	}
+}
+
+func TestValidate_RFC6238(t *testing.T) {
+	// RFC 6238, додаток B (SHA1): останні 6 з 8 цифр.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		now := time.Unix(tt.unix, 0)
+		step, ok := Validate(rfcSecret, tt.code, now)
+		assert.True(t, ok, "time %d", tt.unix)
+		assert.Equal(t, Step(now), step)
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	tests := []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, tt := range tests {
+		code, err := Code(rfcSecret, current+tt.offset)
+		require.NoError(t, err)
+		step, ok := Validate(rfcSecret, code, now)
+		assert.Equal(t, tt.ok, ok, "offset %d", tt.offset)
+		if tt.ok {
+			assert.Equal(t, current+tt.offset, step)
+		}
+	}
+
+	_, ok := Validate(rfcSecret, "05047", now)
+	assert.False(t, ok, "short code")
+	_, ok = Validate("not base32!", "050471", now)
+	assert.False(t, ok, "invalid secret")
+}
+
+func TestCode_LowercaseSecret(t *testing.T) {
+	upper, err := Code(rfcSecret, 1)
+	require.NoError(t, err)
+	lower, err := Code(strings.ToLower(rfcSecret), 1)
+	require.NoError(t, err)
+	assert.Equal(t, upper, lower)
+}
+
+func TestIsTOTPCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"123456", true},
+		{"000000", true},
+		{"12345", false},
+		{"1234567", false},
+		{"12345a", false},
+		{"abcd-efgh-ijkl", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsTOTPCode(tt.code), tt.code)
+	}
+}
+
+func TestHashRecoveryCode_Normalization(t *testing.T) {
+	want := HashRecoveryCode("abcd-efgh-ijkl")
+	for _, code := range []string{"ABCD-EFGH-IJKL", "abcdefghijkl", "abcd efgh ijkl", " abcd-efgh-ijkl "} {
+		assert.Equal(t, want, HashRecoveryCode(code), code)
+	}
+	assert.NotEqual(t, want, HashRecoveryCode("abcd-efgh-ijkm"))
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+	require.Len(t, hashes, RecoveryCodeCount)
+
+	for i, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`, code)
+		assert.Equal(t, HashRecoveryCode(code), hashes[i])
+		assert.False(t, IsTOTPCode(code))
+	}
+}
@@ -2,17 +2,29 @@ package models
 
 import "time"
 
+// Стани облікового запису. Увійти може лише активний користувач: pending
+// чекає на підтвердження email, disabled вимкнено адміністратором.
+const (
+	UserStatusPending  = "pending"
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+)
+
 type User struct {
-	ID        string     `db:"id"`
-	Login     string     `db:"login"`
-	Password  string     `db:"password"`
-	IsAdmin   bool       `db:"is_admin"`
-	CreatedAt time.Time  `db:"created_at"`
-	UpdatedAt *time.Time `db:"updated_at"`
+	ID              string     `db:"id"`
+	Login           string     `db:"login"`
+	Email           string     `db:"email"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`
+	Status          string     `db:"status"`
+	Password        string     `db:"password"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       *time.Time `db:"updated_at"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code — машинний код помилки для випадків, які клієнт має розрізняти.
+	Code string `json:"code,omitempty" example:"account_pending"`
 }
 
 type SuccessResponse struct {
@@ -25,13 +37,289 @@ type AuthRequest struct {
 }
 
 type UserResponse struct {
-	ID      string `json:"id"`
-	Login   string `json:"login"`
-	IsAdmin bool   `json:"is_admin"`
+	ID     string   `json:"id"`
+	Login  string   `json:"login"`
+	Email  string   `json:"email,omitempty"`
+	Status string   `json:"status"`
+	Roles  []string `json:"roles"`
 }
 
 type AuthResponse struct {
-	Token     string       `json:"token"`
-	ExpiresAt time.Time    `json:"expires_at"`
-	User      UserResponse `json:"user"`
+	Token            string       `json:"token"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token"`
+	RefreshExpiresAt time.Time    `json:"refresh_expires_at"`
+	User             UserResponse `json:"user"`
+}
+
+type RefreshToken struct {
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	FamilyID  string     `db:"family_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// Session — сесія входу. ID збігається з сім'єю refresh токенів і
+// передається в claim sid; TokenID — jti останнього виданого access токена,
+// IssuedAt — час його видачі, ExpiresAt — кінець строку дії останнього
+// refresh токена, тобто сесії.
+type Session struct {
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	TokenID    string     `db:"token_id"`
+	ClientIP   string     `db:"client_ip"`
+	UserAgent  string     `db:"user_agent"`
+	CreatedAt  time.Time  `db:"created_at"`
+	IssuedAt   time.Time  `db:"issued_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	LastSeenAt *time.Time `db:"last_seen_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	// TenantID — організація, в якій діють токени сесії; порожній — поза
+	// організаціями.
+	TenantID string `db:"tenant_id"`
+}
+
+const (
+	KeyStatusPending  = "pending"
+	KeyStatusActive   = "active"
+	KeyStatusInactive = "inactive"
+	KeyStatusRetired  = "retired"
+)
+
+type SigningKey struct {
+	ID          string     `db:"id"`
+	Algorithm   string     `db:"algorithm"`
+	PrivateKey  string     `db:"private_key"`
+	Status      string     `db:"status"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ActivatedAt *time.Time `db:"activated_at"`
+	RetiresAt   *time.Time `db:"retires_at"`
+}
+
+// Вбудовані ролі. admin отримує всі дозволи, user призначається при реєстрації.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Дозволи, які перевіряють auth-service і політика gateway.
+const (
+	PermissionProductRead   = "product:read"
+	PermissionProductWrite  = "product:write"
+	PermissionUsersManage   = "users:manage"
+	PermissionRolesManage   = "roles:manage"
+	PermissionKeysManage    = "keys:manage"
+	PermissionClientsManage = "clients:manage"
+	PermissionAuditRead     = "audit:read"
+	// PermissionUsersImpersonate дозволяє отримати токен від імені іншого
+	// користувача через POST /api/auth/token/exchange.
+	PermissionUsersImpersonate = "users:impersonate"
+	// PermissionTenantsManage — адміністратор платформи: створює і видаляє
+	// організації та керує учасниками будь-якої з них.
+	PermissionTenantsManage = "tenants:manage"
+	// PermissionTokensIntrospect — scope OAuth клієнта, якому дозволено
+	// перевіряти чужі токени через POST /oauth/introspect.
+	PermissionTokensIntrospect = "tokens:introspect"
+)
+
+// BuiltinPermissions перевіряються в коді сервісів, тому їх не можна видалити.
+var BuiltinPermissions = []string{
+	PermissionProductRead,
+	PermissionProductWrite,
+	PermissionUsersManage,
+	PermissionRolesManage,
+	PermissionKeysManage,
+	PermissionClientsManage,
+	PermissionAuditRead,
+	PermissionUsersImpersonate,
+	PermissionTenantsManage,
+	PermissionTokensIntrospect,
+}
+
+type Role struct {
+	Name        string `db:"name"`
+	Description string `db:"description"`
+	// RequireMFA — користувачі з цією роллю не можуть увійти без другого фактора.
+	RequireMFA  bool      `db:"require_mfa"`
+	CreatedAt   time.Time `db:"created_at"`
+	Permissions []string  `db:"-"`
+}
+
+type Permission struct {
+	Name        string `db:"name"`
+	Description string `db:"description"`
+}
+
+// Ролі користувача в організації. Вони діють лише в токенах цієї організації
+// і не дають дозволів платформи: адміністратор організації керує її
+// учасниками, але не користувачами, ролями чи ключами всього сервісу.
+const (
+	TenantRoleAdmin  = "admin"
+	TenantRoleEditor = "editor"
+	TenantRoleMember = "member"
+)
+
+// TenantRolePermissions — дозволи, які роль в організації додає до токена.
+var TenantRolePermissions = map[string][]string{
+	TenantRoleAdmin:  {PermissionProductRead, PermissionProductWrite},
+	TenantRoleEditor: {PermissionProductRead, PermissionProductWrite},
+	TenantRoleMember: {PermissionProductRead},
+}
+
+// Tenant — організація. Продукти і членство прив'язані до її ID.
+type Tenant struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// TenantMember — членство користувача в організації. TenantName і Login
+// заповнюються при читанні для зручності відповіді.
+type TenantMember struct {
+	TenantID   string    `db:"tenant_id"`
+	TenantName string    `db:"tenant_name"`
+	UserID     string    `db:"user_id"`
+	Login      string    `db:"login"`
+	Role       string    `db:"role"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// LoginAttempt — лічильник невдалих входів для логіна або IP адреси.
+type LoginAttempt struct {
+	Key           string    `db:"key"`
+	Failures      int       `db:"failures"`
+	LastFailureAt time.Time `db:"last_failure_at"`
+}
+
+// UserMFA — секрет TOTP користувача. Поки Enabled = false, секрет лише
+// очікує підтвердження першим кодом.
+type UserMFA struct {
+	UserID       string     `db:"user_id"`
+	Secret       string     `db:"secret"`
+	Enabled      bool       `db:"enabled"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+	EnabledAt    *time.Time `db:"enabled_at"`
+}
+
+// MFAChallenge — незавершений вхід, що чекає на код другого фактора.
+// ID — хеш токена, виданого клієнту.
+type MFAChallenge struct {
+	ID        string    `db:"id"`
+	UserID    string    `db:"user_id"`
+	Attempts  int       `db:"attempts"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Client — OAuth клієнт: сервіс з grant client_credentials або застосунок,
+// що входить від імені користувача через grant authorization_code.
+// Scopes — дозволи, які клієнт може запросити; SecretHash — SHA-256 секрету.
+// Публічний клієнт (браузерний застосунок) не має секрету і покладається на PKCE.
+type Client struct {
+	ID              string    `db:"id"`
+	Name            string    `db:"name"`
+	SecretHash      string    `db:"secret_hash"`
+	CreatedAt       time.Time `db:"created_at"`
+	SecretRotatedAt time.Time `db:"secret_rotated_at"`
+	Public          bool      `db:"public"`
+	Scopes          []string  `db:"-"`
+	RedirectURIs    []string  `db:"-"`
+}
+
+// AuthorizationCode — одноразовий код grant authorization_code. Зберігається
+// лише хеш коду; CodeChallenge — PKCE challenge методу S256.
+type AuthorizationCode struct {
+	CodeHash      string    `db:"code_hash"`
+	ClientID      string    `db:"client_id"`
+	UserID        string    `db:"user_id"`
+	RedirectURI   string    `db:"redirect_uri"`
+	Scope         string    `db:"scope"`
+	Nonce         string    `db:"nonce"`
+	CodeChallenge string    `db:"code_challenge"`
+	AuthTime      time.Time `db:"auth_time"`
+	ExpiresAt     time.Time `db:"expires_at"`
+}
+
+// Призначення одноразових токенів користувача.
+const (
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenEmailVerification = "email_verification"
+)
+
+// UserToken — одноразовий токен, надісланий користувачу листом. Зберігається
+// лише SHA-256 хеш токена; на кожне призначення в користувача є щонайбільше
+// один дійсний токен.
+type UserToken struct {
+	TokenHash string    `db:"token_hash"`
+	UserID    string    `db:"user_id"`
+	Purpose   string    `db:"purpose"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// APIKey — довготривалий ключ користувача для скриптів. Prefix — відкрита
+// частина ключа для пошуку, KeyHash — SHA-256 усього ключа. Порожні Scopes
+// означають усі дозволи користувача.
+type APIKey struct {
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	// TenantID — організація, в якій діє ключ: та, в якій його створено.
+	TenantID string   `db:"tenant_id"`
+	Scopes   []string `db:"-"`
+}
+
+// Типи подій журналу аудиту.
+const (
+	AuditLoginSuccess         = "login.success"
+	AuditLoginFailure         = "login.failure"
+	AuditTokenRefresh         = "token.refresh"
+	AuditLogout               = "logout"
+	AuditPasswordChange       = "password.change"
+	AuditPasswordReset        = "password.reset"
+	AuditPasswordResetRequest = "password.reset_request"
+	AuditRolesChange          = "user.roles_change"
+	AuditEmailVerify          = "email.verify"
+	AuditEmailChange          = "user.email_change"
+	AuditUserDisable          = "user.disable"
+	AuditUserEnable           = "user.enable"
+	AuditUserDelete           = "user.delete"
+	AuditUserUnlock           = "user.unlock"
+	AuditUserMFAReset         = "user.mfa_reset"
+	AuditUserTokensRevoke     = "user.tokens_revoke"
+	AuditUserImport           = "user.import"
+	AuditImpersonationStart   = "impersonation.start"
+	AuditTenantCreate         = "tenant.create"
+	AuditTenantDelete         = "tenant.delete"
+	AuditTenantMemberSet      = "tenant.member_set"
+	AuditTenantMemberRemove   = "tenant.member_remove"
+	AuditTenantSwitch         = "tenant.switch"
+)
+
+// AuditEvent — запис журналу аудиту. Записи лише додаються. Actor — хто
+// виконав дію: користувач або, для токенів сервісів, "client:<id>" у
+// ActorLogin; при невдалому вході ActorLogin — введений логін. Target —
+// користувач, над яким виконано дію адміністратора. Логіни зберігаються
+// разом з ID, щоб запис лишався зрозумілим після видалення користувача.
+type AuditEvent struct {
+	ID          int64     `db:"id"`
+	Type        string    `db:"type"`
+	ActorID     string    `db:"actor_id"`
+	ActorLogin  string    `db:"actor_login"`
+	TargetID    string    `db:"target_id"`
+	TargetLogin string    `db:"target_login"`
+	ClientIP    string    `db:"client_ip"`
+	UserAgent   string    `db:"user_agent"`
+	Details     string    `db:"details"`
+	CreatedAt   time.Time `db:"created_at"`
 }
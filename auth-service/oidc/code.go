@@ -0,0 +1,69 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// ScopeOpenID вмикає видачу ID токена.
+	ScopeOpenID = "openid"
+	// ScopeProfile додає логін користувача в ID токен.
+	ScopeProfile = "profile"
+
+	// CodeChallengeS256 — єдиний підтримуваний метод PKCE (RFC 7636).
+	CodeChallengeS256 = "S256"
+
+	authorizationCodeBytes = 32
+)
+
+// SupportedScopes — scopes, які можна запитати в /oauth/authorize.
+var SupportedScopes = []string{ScopeOpenID, ScopeProfile}
+
+// NewAuthorizationCode генерує одноразовий код авторизації і його хеш для
+// зберігання в БД.
+func NewAuthorizationCode() (string, string, error) {
+	buf := make([]byte, authorizationCodeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("error generating authorization code: %w", err)
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashAuthorizationCode(raw), nil
+}
+
+func HashAuthorizationCode(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyCodeChallenge перевіряє code_verifier проти code_challenge методу S256.
+func VerifyCodeChallenge(verifier string, challenge string) bool {
+	// RFC 7636, розділ 4.1: 43-128 символів.
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// ParseScopes розбирає scope через пробіл. false — запитано непідтримуваний scope.
+func ParseScopes(scope string) ([]string, bool) {
+	scopes := []string{}
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(SupportedScopes, s) {
+			return nil, false
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, true
+}
@@ -0,0 +1,10 @@
+package oidc
+
+import "time"
+
+type Config struct {
+	// Issuer — ідентифікатор видавця (iss) і базова адреса ендпоінтів у discovery.
+	Issuer string
+	// CodeTTL — скільки діє код авторизації.
+	CodeTTL time.Duration
+}
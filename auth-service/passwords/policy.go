@@ -0,0 +1,173 @@
+package passwords
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"ksv/rest-mikroservice/auth-service/db"
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+//go:embed common_passwords.txt
+var defaultBlocklist string
+
+// maxLength обмежує довжину пароля, щоб хешування не ставало способом
+// навантажити сервіс.
+const maxLength = 128
+
+type Config struct {
+	// MinLength — мінімальна довжина пароля в символах.
+	MinLength int
+	// MinCharClasses — скільки з чотирьох класів символів (малі літери,
+	// великі літери, цифри, інші символи) має містити пароль.
+	MinCharClasses int
+	// HistorySize — скільки останніх паролів не можна використати повторно;
+	// 0 вимикає перевірку.
+	HistorySize int
+}
+
+// PolicyError перелічує вимоги політики, яким не відповідає пароль.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+// Policy перевіряє нові паролі при реєстрації, зміні та скиданні пароля.
+type Policy struct {
+	history   *db.PasswordHistoryRepository
+	cfg       Config
+	blocklist map[string]struct{}
+}
+
+// NewPolicy створює політику. blocklistFile — файл поширених паролів, по
+// одному на рядок; якщо не задано, використовується вбудований список.
+func NewPolicy(history *db.PasswordHistoryRepository, cfg Config, blocklistFile string) (*Policy, error) {
+	var src io.Reader = strings.NewReader(defaultBlocklist)
+	if blocklistFile != "" {
+		f, err := os.Open(blocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open password blocklist: %w", err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	blocklist, err := readBlocklist(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password blocklist: %w", err)
+	}
+	return &Policy{history: history, cfg: cfg, blocklist: blocklist}, nil
+}
+
+func readBlocklist(r io.Reader) (map[string]struct{}, error) {
+	blocklist := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	return blocklist, scanner.Err()
+}
+
+// Check перевіряє пароль для user. Для нового користувача достатньо заповнити
+// Login; для існуючого також перевіряється, що пароль не збігається з
+// поточним і з HistorySize попередніми.
+func (p *Policy) Check(ctx context.Context, user *models.User, password string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("Password must be at least %d characters long", p.cfg.MinLength))
+	}
+	if length > maxLength {
+		violations = append(violations, fmt.Sprintf("Password must be at most %d characters long", maxLength))
+	} else if n := utils.MaxPasswordBytes(); n > 0 && len(password) > n {
+		// bcrypt обмежує пароль 72 байтами, а не символами: без цієї
+		// перевірки прийнятий політикою пароль не вдалося б захешувати.
+		violations = append(violations, fmt.Sprintf("Password must be at most %d bytes long", n))
+	}
+	if charClasses(password) < p.cfg.MinCharClasses {
+		violations = append(violations, fmt.Sprintf("Password must contain at least %d of: lowercase letters, uppercase letters, digits, symbols", p.cfg.MinCharClasses))
+	}
+	lower := strings.ToLower(password)
+	if user.Login != "" && strings.Contains(lower, strings.ToLower(user.Login)) {
+		violations = append(violations, "Password must not contain the login")
+	}
+	if _, ok := p.blocklist[lower]; ok {
+		violations = append(violations, "Password is too common")
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	// Історію перевіряємо останньою: кожне порівняння — повне хешування.
+	reused, err := p.reused(ctx, user, password)
+	if err != nil {
+		return err
+	}
+	if reused {
+		return &PolicyError{Violations: []string{fmt.Sprintf("Password must differ from the last %d passwords", p.cfg.HistorySize)}}
+	}
+	return nil
+}
+
+func (p *Policy) reused(ctx context.Context, user *models.User, password string) (bool, error) {
+	if p.cfg.HistorySize <= 0 || user.ID == "" {
+		return false, nil
+	}
+	hashes, err := p.history.RecentPasswords(ctx, user.ID, p.cfg.HistorySize)
+	if err != nil {
+		return false, err
+	}
+	// Поточного пароля може не бути в історії, якщо його встановлено до
+	// появи політики.
+	if user.Password != "" {
+		hashes = append(hashes, user.Password)
+	}
+	for _, hash := range hashes {
+		if utils.CheckPassword(password, hash) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Record додає хеш щойно встановленого пароля в історію користувача.
+func (p *Policy) Record(ctx context.Context, userID string, hash string) error {
+	if p.cfg.HistorySize <= 0 {
+		return nil
+	}
+	return p.history.AddPassword(ctx, userID, hash, time.Now(), p.cfg.HistorySize)
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
@@ -0,0 +1,33 @@
+package passwords
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/utils"
+)
+
+func TestPolicy_CheckHasherByteLimit(t *testing.T) {
+	policy, err := NewPolicy(nil, Config{MinLength: 10}, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { utils.SetHasher(utils.NewBcryptHasher(bcrypt.DefaultCost)) })
+
+	// 40 символів, але 76 байтів: у межах maxLength, та довше, ніж приймає bcrypt.
+	password := strings.Repeat("пароль", 6) + "Abc1"
+	require.Greater(t, len(password), 72)
+	user := &models.User{Login: "alice"}
+
+	utils.SetHasher(utils.NewBcryptHasher(bcrypt.MinCost))
+	var policyErr *PolicyError
+	require.ErrorAs(t, policy.Check(context.Background(), user, password), &policyErr)
+	assert.Equal(t, []string{"Password must be at most 72 bytes long"}, policyErr.Violations)
+
+	utils.SetHasher(utils.NewArgon2idHasher(64, 1, 1))
+	assert.NoError(t, policy.Check(context.Background(), user, password))
+}
@@ -0,0 +1,24 @@
+package passwords
+
+import (
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/mail"
+)
+
+type ResetConfig struct {
+	// URL — сторінка, на якій користувач вводить новий пароль; токен
+	// додається до неї параметром token. Якщо не задано, лист містить сам
+	// токен для POST /api/auth/password/reset.
+	URL string
+	// TTL — скільки діє токен скидання пароля.
+	TTL time.Duration
+	// Interval — не частіше ніж раз на скільки користувачу надсилається лист.
+	Interval time.Duration
+}
+
+// Link повертає посилання на сторінку скидання пароля з токеном або
+// порожній рядок, якщо URL не задано.
+func (c ResetConfig) Link(token string) (string, error) {
+	return mail.TokenLink(c.URL, token)
+}
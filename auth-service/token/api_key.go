@@ -0,0 +1,46 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	apiKeyTag         = "ak_"
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+)
+
+// apiKeyPrefixLength — довжина відкритої частини ключа: тег і hex ідентифікатор.
+var apiKeyPrefixLength = len(apiKeyTag) + hex.EncodedLen(apiKeyPrefixBytes)
+
+// NewAPIKey генерує API ключ вигляду ak_<prefix>_<secret>. Повертає ключ,
+// його відкритий префікс для пошуку в БД і хеш для зберігання.
+func NewAPIKey() (string, string, string, error) {
+	buf := make([]byte, apiKeyPrefixBytes+apiKeySecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("error generating api key: %w", err)
+	}
+
+	prefix := apiKeyTag + hex.EncodeToString(buf[:apiKeyPrefixBytes])
+	raw := prefix + "_" + base64.RawURLEncoding.EncodeToString(buf[apiKeyPrefixBytes:])
+	return raw, prefix, HashAPIKey(raw), nil
+}
+
+// APIKeyPrefix повертає відкритий префікс ключа або false, якщо рядок не
+// схожий на API ключ.
+func APIKeyPrefix(raw string) (string, bool) {
+	if !strings.HasPrefix(raw, apiKeyTag) || len(raw) <= apiKeyPrefixLength+1 || raw[apiKeyPrefixLength] != '_' {
+		return "", false
+	}
+	return raw[:apiKeyPrefixLength], true
+}
+
+func HashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,6 +2,7 @@ package token
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -10,22 +11,49 @@ import (
 )
 
 type UserClaims struct {
-	ID      string `json:"id"`
-	Login   string `json:"login"`
-	IsAdmin bool   `json:"is_admin"`
+	ID          string   `json:"id"`
+	Login       string   `json:"login"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	// ClientID заповнений у токенах сервісів (grant client_credentials): такий
+	// токен не належить користувачу, ID і Login порожні, а Permissions — scopes клієнта.
+	ClientID string `json:"client_id,omitempty"`
+	// APIKeyID заповнений, якщо запит автентифіковано API ключем користувача,
+	// а не JWT; Permissions тоді обмежені scopes ключа.
+	APIKeyID string `json:"api_key_id,omitempty"`
+	// SessionID — сесія входу, до якої належить токен; збігається з сім'єю
+	// refresh токенів і зберігається при їх оновленні.
+	SessionID string `json:"sid,omitempty"`
+	// TenantID — організація, в якій діє токен, а TenantRole — роль
+	// користувача в ній; порожні, якщо токен діє поза організаціями.
+	TenantID   string `json:"tenant_id,omitempty"`
+	TenantRole string `json:"tenant_role,omitempty"`
+	// Actor заповнений у токені імперсонації: ним діє співробітник підтримки
+	// від імені користувача ID (claim act, RFC 8693, розділ 4.1).
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewUserClaims(id string, login string, isAdmin bool, duration time.Duration) (*UserClaims, error) {
+// Actor — хто насправді діє токеном. Subject — його логін, як sub у токені.
+type Actor struct {
+	ID      string `json:"id"`
+	Subject string `json:"sub"`
+}
+
+func NewUserClaims(id string, login string, roles []string, permissions []string, tenantID string, tenantRole string, sessionID string, duration time.Duration) (*UserClaims, error) {
 	tokenId, err := uuid.NewRandom()
 	if err != nil {
 		return nil, fmt.Errorf("error generation token id: %w", err)
 	}
 
 	return &UserClaims{
-		ID:      id,
-		Login:   login,
-		IsAdmin: isAdmin,
+		ID:          id,
+		Login:       login,
+		Roles:       roles,
+		Permissions: permissions,
+		TenantID:    tenantID,
+		TenantRole:  tenantRole,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        tokenId.String(),
 			Subject:   login, 
@@ -34,3 +62,80 @@ func NewUserClaims(id string, login string, isAdmin bool, duration time.Duration
 		},
 	}, nil
 }
+
+// NewClientClaims створює claims токена, виданого клієнту clientID, а не користувачу.
+func NewClientClaims(clientID string, scopes []string, duration time.Duration) (*UserClaims, error) {
+	tokenId, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generation token id: %w", err)
+	}
+
+	return &UserClaims{
+		Permissions: scopes,
+		ClientID:    clientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId.String(),
+			Subject:   clientID,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+		},
+	}, nil
+}
+
+// NewAPIKeyClaims створює claims для запиту з API ключем keyID. Такі claims
+// не підписуються: ключ перевіряється в БД при кожному запиті, тож jti
+// відсутній, а строк дії збігається зі строком дії ключа (nil — безстроковий).
+func NewAPIKeyClaims(keyID string, id string, login string, roles []string, permissions []string, createdAt time.Time, expiresAt *time.Time) *UserClaims {
+	claims := &UserClaims{
+		ID:          id,
+		Login:       login,
+		Roles:       roles,
+		Permissions: permissions,
+		APIKeyID:    keyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  login,
+			IssuedAt: jwt.NewNumericDate(createdAt),
+		},
+	}
+	if expiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*expiresAt)
+	}
+	return claims
+}
+
+// IsAPIKey повідомляє, що запит автентифіковано API ключем.
+func (c *UserClaims) IsAPIKey() bool {
+	return c.APIKeyID != ""
+}
+
+// IsImpersonated повідомляє, що токеном діє не сам користувач, а Actor.
+func (c *UserClaims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
+// IsClient повідомляє, що токен виданий сервісу, а не користувачу.
+func (c *UserClaims) IsClient() bool {
+	return c.ClientID != ""
+}
+
+// RevocationSubject — ключ, за яким RevokeAllForUser відкликає токени:
+// ID користувача або ClientRevocationKey для токенів клієнта.
+func (c *UserClaims) RevocationSubject() string {
+	if c.IsClient() {
+		return ClientRevocationKey(c.ClientID)
+	}
+	return c.ID
+}
+
+// ClientRevocationKey відокремлює ключі відкликання клієнтів від ID користувачів.
+func ClientRevocationKey(clientID string) string {
+	return "client:" + clientID
+}
+
+func (c *UserClaims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+func (c *UserClaims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
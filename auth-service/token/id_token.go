@@ -0,0 +1,35 @@
+package token
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenClaims — claims ID токена OpenID Connect. Аудиторія ID токена —
+// клієнт, якому він виданий, тож як access токен він не приймається.
+type IDTokenClaims struct {
+	Nonce             string           `json:"nonce,omitempty"`
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// CreateIDToken підписує ID токен користувача userID для клієнта clientID.
+// login передається лише для scope profile.
+func (maker *JWTMaker) CreateIDToken(userID string, login string, clientID string, nonce string, authTime time.Time, duration time.Duration) (string, error) {
+	now := time.Now()
+	claims := &IDTokenClaims{
+		Nonce:             nonce,
+		AuthTime:          jwt.NewNumericDate(authTime),
+		PreferredUsername: login,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    maker.issuer,
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
+	return maker.signClaims(claims)
+}
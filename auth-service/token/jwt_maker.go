@@ -1,43 +1,145 @@
 package token
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// RevocationChecker повідомляє, чи був токен відкликаний до закінчення строку дії.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string, userID string, issuedAt time.Time) (bool, error)
+}
+
+// SessionChecker повідомляє, чи сесія входу ще активна.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// DefaultKeyID — kid, з яким підписуються токени спільним секретом JWT_SECRET_KEY.
+const DefaultKeyID = "default"
+
 type JWTMaker struct {
-	secretKey string
+	keys        *KeySet
+	revocations RevocationChecker
+	sessions    SessionChecker
+	issuer      string
+	audience    string
 }
 
+// NewJWTMaker створює maker, що підписує токени HS256 спільним секретом.
 func NewJWTMaker(secretKey string) *JWTMaker {
-	return &JWTMaker{secretKey}
+	return NewJWTMakerWithKey(NewHMACKey(DefaultKeyID, []byte(secretKey)))
+}
+
+// NewJWTMakerWithKey створює maker з довільним ключем (HS256, RS256, ES256, EdDSA).
+func NewJWTMakerWithKey(key *SigningKey) *JWTMaker {
+	return NewJWTMakerWithKeySet(NewKeySet(key))
+}
+
+// NewJWTMakerWithKeySet створює maker, що підписує активним ключем набору і
+// приймає токени, підписані будь-яким ще не виведеним ключем.
+func NewJWTMakerWithKeySet(keys *KeySet) *JWTMaker {
+	return &JWTMaker{keys: keys}
+}
+
+// WithRevocationChecker вмикає перевірку відкликаних токенів у VerifyToken.
+func (maker *JWTMaker) WithRevocationChecker(checker RevocationChecker) *JWTMaker {
+	maker.revocations = checker
+	return maker
+}
+
+// WithSessionChecker вмикає у VerifyToken перевірку, що сесія токена (sid)
+// не завершена.
+func (maker *JWTMaker) WithSessionChecker(checker SessionChecker) *JWTMaker {
+	maker.sessions = checker
+	return maker
+}
+
+// WithIssuer задає iss і aud для виданих токенів. VerifyToken після цього
+// приймає лише токени з цим видавцем і аудиторією; порожнє значення вимикає
+// відповідну перевірку.
+func (maker *JWTMaker) WithIssuer(issuer string, audience string) *JWTMaker {
+	maker.issuer = issuer
+	maker.audience = audience
+	return maker
 }
 
-func (maker *JWTMaker) CreateToken(id string, login string, isAdmin bool, duration time.Duration) (string, *UserClaims, error) {
-	claims, err := NewUserClaims(id, login, isAdmin, duration)
+// Issuer повертає видавця токенів (iss).
+func (maker *JWTMaker) Issuer() string {
+	return maker.issuer
+}
+
+// CreateToken підписує токен сесії sessionID з ролями і дозволами
+// користувача в організації tenantID. Зміни ролей потрапляють у токени при
+// наступному вході або оновленні токенів.
+func (maker *JWTMaker) CreateToken(id string, login string, roles []string, permissions []string, tenantID string, tenantRole string, sessionID string, duration time.Duration) (string, *UserClaims, error) {
+	claims, err := NewUserClaims(id, login, roles, permissions, tenantID, tenantRole, sessionID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	return maker.sign(claims)
+}
+
+// CreateImpersonationToken підписує токен користувача id з claim act: ним
+// діє actor. Токен належить сесії actor, тож завершується разом з нею.
+func (maker *JWTMaker) CreateImpersonationToken(id string, login string, roles []string, permissions []string, tenantID string, tenantRole string, actor *Actor, sessionID string, duration time.Duration) (string, *UserClaims, error) {
+	claims, err := NewUserClaims(id, login, roles, permissions, tenantID, tenantRole, sessionID, duration)
 	if err != nil {
 		return "", nil, err
 	}
+	claims.Actor = actor
+	return maker.sign(claims)
+}
 
-	// HS256
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString([]byte(maker.secretKey))
+// CreateClientToken підписує токен клієнта з дозволеними йому scopes.
+func (maker *JWTMaker) CreateClientToken(clientID string, scopes []string, duration time.Duration) (string, *UserClaims, error) {
+	claims, err := NewClientClaims(clientID, scopes, duration)
 	if err != nil {
-		return "", nil, fmt.Errorf("error signing token: %w", err)
+		return "", nil, err
+	}
+	return maker.sign(claims)
+}
+
+func (maker *JWTMaker) sign(claims *UserClaims) (string, *UserClaims, error) {
+	claims.Issuer = maker.issuer
+	if maker.audience != "" {
+		claims.Audience = jwt.ClaimStrings{maker.audience}
+	}
+	tokenStr, err := maker.signClaims(claims)
+	if err != nil {
+		return "", nil, err
 	}
 	return tokenStr, claims, nil
 }
 
+func (maker *JWTMaker) signClaims(claims jwt.Claims) (string, error) {
+	key := maker.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	tokenStr, err := token.SignedString(key.Private)
+	if err != nil {
+		return "", fmt.Errorf("error signing token: %w", err)
+	}
+	return tokenStr, nil
+}
+
 func (maker *JWTMaker) VerifyToken(tokenStr string) (*UserClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &UserClaims{}, func(t *jwt.Token) (interface{}, error) {
-		_, ok := t.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, fmt.Errorf("invalid token signing method")
-		}
-		return []byte(maker.secretKey), nil
-	})
+	var opts []jwt.ParserOption
+	if maker.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(maker.issuer))
+	}
+	if maker.audience != "" {
+		opts = append(opts, jwt.WithAudience(maker.audience))
+	}
+	token, err := jwt.ParseWithClaims(tokenStr, &UserClaims{}, maker.keyFunc, opts...)
 
 	if err != nil {
 		return nil, fmt.Errorf("error parsing token: %w", err)
@@ -51,5 +153,70 @@ func (maker *JWTMaker) VerifyToken(tokenStr string) (*UserClaims, error) {
 		return nil, fmt.Errorf("token has expired")
 	}
 
+	if maker.revocations != nil {
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+		revoked, err := maker.revocations.IsRevoked(context.Background(), claims.RegisteredClaims.ID, claims.RevocationSubject(), issuedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error checking token revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	if maker.sessions != nil && claims.SessionID != "" {
+		active, err := maker.sessions.IsSessionActive(context.Background(), claims.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("error checking token session: %w", err)
+		}
+		if !active {
+			return nil, ErrTokenRevoked
+		}
+	}
+
 	return claims, nil
 }
+
+// keyFunc вибирає ключ перевірки за заголовком kid. Токени без kid (видані до
+// появи заголовка) перевіряються активним ключем.
+func (maker *JWTMaker) keyFunc(t *jwt.Token) (interface{}, error) {
+	key := maker.keys.Active()
+	if kid, ok := t.Header["kid"].(string); ok {
+		key, ok = maker.keys.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("invalid token signing method")
+	}
+	return key.Public, nil
+}
+
+// SigningAlgorithms повертає алгоритми ключів, дійсних для перевірки.
+func (maker *JWTMaker) SigningAlgorithms() []string {
+	algs := []string{}
+	for _, key := range maker.keys.Keys() {
+		if alg := key.Method.Alg(); !slices.Contains(algs, alg) {
+			algs = append(algs, alg)
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// JWKS повертає публічні ключі для перевірки токенів іншими сервісами.
+// Симетричні ключі не публікуються.
+func (maker *JWTMaker) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range maker.keys.Keys() {
+		if jwk, ok := key.JWK(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
@@ -0,0 +1,65 @@
+package token
+
+import (
+	"sync"
+	"time"
+)
+
+// KeySet містить активний ключ підпису та попередні ключі, які ще дійсні для
+// перевірки до своєї дати виведення. Безпечний для одночасного використання.
+type KeySet struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+func NewKeySet(active *SigningKey, others ...*SigningKey) *KeySet {
+	ks := &KeySet{}
+	ks.Replace(active, others...)
+	return ks
+}
+
+// Replace атомарно замінює вміст набору.
+func (ks *KeySet) Replace(active *SigningKey, others ...*SigningKey) {
+	keys := make(map[string]*SigningKey, len(others)+1)
+	for _, k := range others {
+		keys[k.ID] = k
+	}
+	keys[active.ID] = active
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.active = active
+	ks.keys = keys
+}
+
+func (ks *KeySet) Active() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active
+}
+
+// Key повертає ключ за kid, якщо він ще не виведений з обігу.
+func (ks *KeySet) Key(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[kid]
+	if !ok || !k.Usable(time.Now()) {
+		return nil, false
+	}
+	return k, true
+}
+
+// Keys повертає всі ключі, дійсні для перевірки.
+func (ks *KeySet) Keys() []*SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	now := time.Now()
+	keys := make([]*SigningKey, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		if k.Usable(now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,203 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// SigningKey — ключ підпису JWT разом з алгоритмом і ідентифікатором (kid).
+// Для HS256 Private і Public — це один і той самий секрет ([]byte).
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+	// RetiresAt — після цього моменту ключ не приймається для перевірки.
+	// Нульове значення означає, що дату виведення не призначено.
+	RetiresAt time.Time
+}
+
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:      id,
+		Method:  jwt.SigningMethodHS256,
+		Private: secret,
+		Public:  secret,
+	}
+}
+
+// LoadPrivateKeyPEM читає приватний ключ RSA, EC або Ed25519 з PEM файлу.
+// Якщо id порожній, kid обчислюється як JWK thumbprint (RFC 7638).
+func LoadPrivateKeyPEM(id string, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading signing key: %w", err)
+	}
+	return ParsePrivateKeyPEM(id, data)
+}
+
+func ParsePrivateKeyPEM(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("error decoding signing key: no PEM block found")
+	}
+
+	var private any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing signing key: %w", err)
+	}
+
+	return NewAsymmetricKey(id, private)
+}
+
+// NewAsymmetricKey підбирає алгоритм за типом ключа: RSA — RS256,
+// EC — ES256/ES384/ES512 залежно від кривої, Ed25519 — EdDSA.
+func NewAsymmetricKey(id string, private crypto.PrivateKey) (*SigningKey, error) {
+	key := &SigningKey{ID: id, Private: private}
+
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.Public = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			key.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %s", k.Curve.Params().Name)
+		}
+		key.Public = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.Public = k.Public()
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	if key.ID == "" {
+		jwk, err := NewJWK("", key.Method.Alg(), key.Public)
+		if err != nil {
+			return nil, err
+		}
+		key.ID, err = jwk.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// GenerateKey створює новий ключ для алгоритму HS256, RS256, ES256 або EdDSA.
+func GenerateKey(id string, alg string) (*SigningKey, error) {
+	var private crypto.PrivateKey
+	var err error
+
+	switch alg {
+	case jwt.SigningMethodHS256.Alg():
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("error generating key: %w", err)
+		}
+		if id == "" {
+			id = uuid.NewString()
+		}
+		return NewHMACKey(id, secret), nil
+	case jwt.SigningMethodRS256.Alg():
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256.Alg():
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error generating key: %w", err)
+	}
+
+	return NewAsymmetricKey(id, private)
+}
+
+// MarshalPrivateKey кодує ключ для зберігання: PKCS#8 PEM для асиметричних
+// ключів і base64 для секрету HS256.
+func MarshalPrivateKey(k *SigningKey) (string, error) {
+	if secret, ok := k.Private.([]byte); ok {
+		return base64.StdEncoding.EncodeToString(secret), nil
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return "", fmt.Errorf("error encoding signing key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// UnmarshalPrivateKey — зворотна операція до MarshalPrivateKey.
+func UnmarshalPrivateKey(id string, alg string, encoded string) (*SigningKey, error) {
+	if alg == jwt.SigningMethodHS256.Alg() {
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding signing key: %w", err)
+		}
+		return NewHMACKey(id, secret), nil
+	}
+
+	key, err := ParsePrivateKeyPEM(id, []byte(encoded))
+	if err != nil {
+		return nil, err
+	}
+	if key.Method.Alg() != alg {
+		return nil, fmt.Errorf("signing key %q is %s, expected %s", id, key.Method.Alg(), alg)
+	}
+	return key, nil
+}
+
+// Usable повідомляє, чи можна ще перевіряти цим ключем підписи на момент now.
+func (k *SigningKey) Usable(now time.Time) bool {
+	return k.RetiresAt.IsZero() || now.Before(k.RetiresAt)
+}
+
+func (k *SigningKey) IsSymmetric() bool {
+	_, ok := k.Public.([]byte)
+	return ok
+}
+
+// JWK повертає публічну частину ключа. Для симетричних ключів — false.
+func (k *SigningKey) JWK() (JWK, bool) {
+	if k.IsSymmetric() {
+		return JWK{}, false
+	}
+	jwk, err := NewJWK(k.ID, k.Method.Alg(), k.Public)
+	if err != nil {
+		return JWK{}, false
+	}
+	return jwk, true
+}
@@ -0,0 +1,61 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+const refreshTokenBytes = 32
+
+// NewRefreshToken генерує непрозорий refresh токен і його хеш для зберігання в БД.
+func NewRefreshToken() (string, string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("error generating refresh token: %w", err)
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashRefreshToken(raw), nil
+}
+
+func HashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewClientSecret генерує секрет OAuth клієнта і його хеш. Секрет випадковий
+// і довгий, тож повільний хеш на кшталт argon2id для нього не потрібен.
+func NewClientSecret() (string, string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("error generating client secret: %w", err)
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashClientSecret(raw), nil
+}
+
+func HashClientSecret(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewUserToken генерує одноразовий токен, який користувач отримує листом
+// (наприклад, для скидання пароля), і його хеш для зберігання в БД.
+func NewUserToken() (string, string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("error generating user token: %w", err)
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashUserToken(raw), nil
+}
+
+func HashUserToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
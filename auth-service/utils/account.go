@@ -0,0 +1,31 @@
+package utils
+
+import (
+	netmail "net/mail"
+	"regexp"
+	"strings"
+)
+
+const maxEmailLength = 254
+
+var loginPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{2,31}$`)
+
+// ValidLogin перевіряє логін: 3-32 символи, літери, цифри, '.', '_' або '-',
+// починається з літери чи цифри.
+func ValidLogin(login string) bool {
+	return loginPattern.MatchString(login)
+}
+
+// NormalizeEmail перевіряє адресу і приводить її до нижнього регістру.
+// Порожній рядок — відсутність email — теж коректний.
+func NormalizeEmail(email string) (string, bool) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", true
+	}
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > maxEmailLength {
+		return "", false
+	}
+	return email, true
+}
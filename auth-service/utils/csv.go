@@ -0,0 +1,12 @@
+package utils
+
+import "strings"
+
+// CSVCell захищає значення від виконання як формули в табличних редакторах:
+// логіни, email і User-Agent задають клієнти.
+func CSVCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
@@ -1,20 +1,210 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+var (
+	ErrPasswordMismatch = errors.New("password does not match")
+	ErrUnknownHash      = errors.New("unknown password hash format")
+)
+
+// Hasher хешує паролі одним алгоритмом з фіксованими параметрами.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// NeedsRehash повідомляє, що хеш створено іншим алгоритмом або з іншими
+	// параметрами, ніж у цього Hasher.
+	NeedsRehash(encoded string) bool
+	// MaxBytes — найбільша довжина пароля в байтах, яку приймає алгоритм;
+	// 0 — без обмеження.
+	MaxBytes() int
+}
+
+var hasher Hasher = NewBcryptHasher(bcrypt.DefaultCost)
 
+// SetHasher задає алгоритм для нових хешів. Перевіряти CheckPassword уміє
+// хеші всіх підтримуваних алгоритмів незалежно від цього налаштування.
+func SetHasher(h Hasher) {
+	hasher = h
+}
+
+func HashPassword(password string) (string, error) {
+	hashed, err := hasher.Hash(password)
 	if err != nil {
 		return "", fmt.Errorf("error hashing password  %w", err)
 	}
-	return string(hashed), nil
+	return hashed, nil
 }
 
+// CheckPassword визначає алгоритм за префіксом хешу (формат PHC для argon2id,
+// modular crypt для bcrypt).
 func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	switch {
+	case strings.HasPrefix(hashedPassword, argon2idPrefix):
+		return verifyArgon2id(password, hashedPassword)
+	case isBcryptHash(hashedPassword):
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	default:
+		return ErrUnknownHash
+	}
+}
+
+// NeedsRehash повідомляє, що хеш варто перерахувати поточним Hasher.
+func NeedsRehash(hashedPassword string) bool {
+	return hasher.NeedsRehash(hashedPassword)
+}
+
+// MaxPasswordBytes повертає обмеження довжини пароля в байтах для поточного
+// Hasher; 0 — без обмеження.
+func MaxPasswordBytes() int {
+	return hasher.MaxBytes()
+}
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	if !isBcryptHash(encoded) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
+
+// MaxBytes: довші паролі bcrypt відхиляє з ErrPasswordTooLong.
+func (h *BcryptHasher) MaxBytes() int {
+	return 72
+}
+
+func isBcryptHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+const (
+	argon2idPrefix = "$argon2id$"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Argon2idHasher кодує хеші у форматі PHC:
+// $argon2id$v=19$m=<KiB>,t=<ітерації>,p=<потоки>$<сіль>$<хеш>.
+type Argon2idHasher struct {
+	memory      uint32
+	time        uint32
+	parallelism uint8
+}
+
+// NewArgon2idHasher створює hasher; memory задається в KiB.
+func NewArgon2idHasher(memory uint32, time uint32, parallelism uint8) *Argon2idHasher {
+	return &Argon2idHasher{memory: memory, time: time, parallelism: parallelism}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memory, h.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, h.memory, h.time, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.memory != h.memory || p.time != h.time || p.parallelism != h.parallelism ||
+		len(p.key) != argon2KeyLength
+}
+
+func (h *Argon2idHasher) MaxBytes() int {
+	return 0
+}
+
+type argon2idParams struct {
+	memory      uint32
+	time        uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func parseArgon2id(encoded string) (*argon2idParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	var p argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	// argon2.IDKey панікує при нульових t або p.
+	if p.time == 0 || p.parallelism == 0 {
+		return nil, fmt.Errorf("invalid argon2id parameters: t=%d, p=%d", p.time, p.parallelism)
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if len(p.key) == 0 {
+		return nil, errors.New("invalid argon2id hash: empty key")
+	}
+	return &p, nil
+}
+
+func verifyArgon2id(password string, encoded string) error {
+	p, err := parseArgon2id(encoded)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.parallelism, uint32(len(p.key)))
+	if subtle.ConstantTimeCompare(key, p.key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
 }
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Параметри, з якими тести виконуються швидко.
+const (
+	testArgon2Memory = 64
+	testArgon2Time   = 1
+	testBcryptCost   = bcrypt.MinCost
+)
+
+func useHasher(t *testing.T, h Hasher) {
+	previous := hasher
+	SetHasher(h)
+	t.Cleanup(func() { SetHasher(previous) })
+}
+
+func TestArgon2idHasher_PHCRoundTrip(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2Memory, testArgon2Time, 2)
+	encoded, err := h.Hash("secret")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=2$"), encoded)
+	p, err := parseArgon2id(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(testArgon2Memory), p.memory)
+	assert.Equal(t, uint32(testArgon2Time), p.time)
+	assert.Equal(t, uint8(2), p.parallelism)
+	assert.Len(t, p.salt, argon2SaltLength)
+	assert.Len(t, p.key, argon2KeyLength)
+
+	assert.NoError(t, CheckPassword("secret", encoded))
+	assert.ErrorIs(t, CheckPassword("wrong", encoded), ErrPasswordMismatch)
+
+	other, err := h.Hash("secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, other, "each hash must use a new salt")
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2Hash := func(memory uint32, time uint32, parallelism uint8) string {
+		encoded, err := NewArgon2idHasher(memory, time, parallelism).Hash("secret")
+		require.NoError(t, err)
+		return encoded
+	}
+	bcryptHash := func(cost int) string {
+		encoded, err := NewBcryptHasher(cost).Hash("secret")
+		require.NoError(t, err)
+		return encoded
+	}
+	current := NewArgon2idHasher(testArgon2Memory, testArgon2Time, 1)
+
+	tests := []struct {
+		name    string
+		hasher  Hasher
+		encoded string
+		want    bool
+	}{
+		{"argon2id same parameters", current, argon2Hash(testArgon2Memory, testArgon2Time, 1), false},
+		{"argon2id memory changed", current, argon2Hash(2*testArgon2Memory, testArgon2Time, 1), true},
+		{"argon2id time changed", current, argon2Hash(testArgon2Memory, testArgon2Time+1, 1), true},
+		{"argon2id parallelism changed", current, argon2Hash(testArgon2Memory, testArgon2Time, 2), true},
+		{"argon2id from bcrypt", current, bcryptHash(testBcryptCost), true},
+		{"argon2id malformed", current, "$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5", true},
+		{"bcrypt same cost", NewBcryptHasher(testBcryptCost), bcryptHash(testBcryptCost), false},
+		{"bcrypt cost changed", NewBcryptHasher(testBcryptCost + 1), bcryptHash(testBcryptCost), true},
+		{"bcrypt from argon2id", NewBcryptHasher(testBcryptCost), argon2Hash(testArgon2Memory, testArgon2Time, 1), true},
+		{"bcrypt malformed", NewBcryptHasher(testBcryptCost), "$2a$xx$", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.hasher.NeedsRehash(tt.encoded))
+		})
+	}
+}
+
+func TestCheckPassword_CrossAlgorithm(t *testing.T) {
+	hashers := map[string]Hasher{
+		"bcrypt":   NewBcryptHasher(testBcryptCost),
+		"argon2id": NewArgon2idHasher(testArgon2Memory, testArgon2Time, 1),
+	}
+	for hashedWith, h := range hashers {
+		useHasher(t, h)
+		encoded, err := HashPassword("secret")
+		require.NoError(t, err)
+
+		for current, other := range hashers {
+			t.Run(fmt.Sprintf("%s hash with %s configured", hashedWith, current), func(t *testing.T) {
+				useHasher(t, other)
+				assert.NoError(t, CheckPassword("secret", encoded))
+				assert.ErrorIs(t, CheckPassword("wrong", encoded), ErrPasswordMismatch)
+				assert.Equal(t, current != hashedWith, NeedsRehash(encoded))
+			})
+		}
+	}
+}
+
+func TestCheckPassword_MalformedHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"plaintext", "secret"},
+		{"unknown algorithm", "$argon2i$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+		{"argon2id missing key", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0"},
+		{"argon2id bad version", "$argon2id$v=x$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+		{"argon2id old version", "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+		{"argon2id bad parameters", "$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+		{"argon2id zero time", "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+		{"argon2id zero parallelism", "$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+		{"argon2id bad salt", "$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5a2V5a2V5a2V5"},
+		{"argon2id bad key", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$!!!"},
+		{"argon2id empty key", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$"},
+		{"bcrypt truncated", "$2a$04$short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPassword("secret", tt.encoded)
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, ErrPasswordMismatch)
+		})
+	}
+}
+
+func TestBcryptHasher_MaxBytes(t *testing.T) {
+	h := NewBcryptHasher(testBcryptCost)
+	useHasher(t, h)
+	require.Equal(t, 72, MaxPasswordBytes())
+
+	_, err := h.Hash(strings.Repeat("a", MaxPasswordBytes()))
+	assert.NoError(t, err)
+	_, err = h.Hash(strings.Repeat("a", MaxPasswordBytes()+1))
+	assert.ErrorIs(t, err, bcrypt.ErrPasswordTooLong)
+
+	useHasher(t, NewArgon2idHasher(testArgon2Memory, testArgon2Time, 1))
+	assert.Zero(t, MaxPasswordBytes())
+}
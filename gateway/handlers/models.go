@@ -6,7 +6,6 @@ type User struct {
 	ID        string     `db:"id"`
 	Login     string     `db:"login"`
 	Password  string     `db:"password"`
-	IsAdmin   bool       `db:"is_admin"`
 	CreatedAt time.Time  `db:"created_at"`
 	UpdatedAt *time.Time `db:"updated_at"`
 }
@@ -22,6 +21,7 @@ type Product struct {
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty" example:"account_pending"`
 }
 
 type SuccessResponse struct {
@@ -33,14 +33,334 @@ type AuthRequest struct {
 	Password string `json:"password"`
 }
 
+type RegisterRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Email    string `json:"email,omitempty" example:"user@example.com"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"user@example.com"`
+}
+
+type CompletePasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" example:"user@example.com"`
+}
+
 type UserResponse struct {
-	ID      string `json:"id"`
-	Login   string `json:"login"`
-	IsAdmin bool   `json:"is_admin"`
+	ID     string   `json:"id"`
+	Login  string   `json:"login"`
+	Email  string   `json:"email,omitempty"`
+	Status string   `json:"status" example:"active"`
+	Roles  []string `json:"roles"`
+}
+
+type UserDetailsResponse struct {
+	ID              string     `json:"id"`
+	Login           string     `json:"login"`
+	Email           string     `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Status          string     `json:"status" example:"active"`
+	Roles           []string   `json:"roles"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
+type UserListResponse struct {
+	Users  []UserDetailsResponse `json:"users"`
+	Total  int                   `json:"total"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+}
+
+type UserImportRowResponse struct {
+	Row      int      `json:"row"`
+	Login    string   `json:"login"`
+	UserID   string   `json:"user_id,omitempty"`
+	Action   string   `json:"action" example:"created"`
+	Changes  []string `json:"changes,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+type UserImportResponse struct {
+	DryRun    bool                    `json:"dry_run"`
+	Created   int                     `json:"created"`
+	Updated   int                     `json:"updated"`
+	Unchanged int                     `json:"unchanged"`
+	Failed    int                     `json:"failed"`
+	Rows      []UserImportRowResponse `json:"rows"`
+}
+
+type AuditEventResponse struct {
+	ID          int64     `json:"id"`
+	Type        string    `json:"type" example:"login.success"`
+	ActorID     string    `json:"actor_id,omitempty"`
+	ActorLogin  string    `json:"actor_login,omitempty"`
+	TargetID    string    `json:"target_id,omitempty"`
+	TargetLogin string    `json:"target_login,omitempty"`
+	ClientIP    string    `json:"client_ip,omitempty" example:"203.0.113.7"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	Details     string    `json:"details,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AuditListResponse struct {
+	Events []AuditEventResponse `json:"events"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+type UpdateUserRequest struct {
+	Roles []string `json:"roles,omitempty"`
+	Email *string  `json:"email,omitempty" example:"user@example.com"`
+}
+
+type RoleResponse struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	RequireMFA  bool      `json:"require_mfa"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RoleRequest struct {
+	Name        string   `json:"name,omitempty" example:"catalog_editor"`
+	Description string   `json:"description"`
+	RequireMFA  bool     `json:"require_mfa"`
+	Permissions []string `json:"permissions" example:"product:read,product:write"`
+}
+
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type SigningKeyResponse struct {
+	ID          string     `json:"id"`
+	Algorithm   string     `json:"algorithm"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+	RetiresAt   *time.Time `json:"retires_at,omitempty"`
 }
 
 type AuthResponse struct {
-	Token     string       `json:"token"`
-	ExpiresAt time.Time    `json:"expires_at"`
-	User      UserResponse `json:"user"`
+	Token            string       `json:"token"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token"`
+	RefreshExpiresAt time.Time    `json:"refresh_expires_at"`
+	User             UserResponse `json:"user"`
+	RecoveryCodes    []string     `json:"recovery_codes,omitempty"`
+	TenantID         string       `json:"tenant_id,omitempty"`
+	TenantRole       string       `json:"tenant_role,omitempty" example:"editor"`
+}
+
+type MFASetupResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/rest-mikroservice:admin?algorithm=SHA1&digits=6&issuer=rest-mikroservice&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
+
+type MFAChallengeResponse struct {
+	MFAToken   string            `json:"mfa_token"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+	Enrollment *MFASetupResponse `json:"enrollment,omitempty"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code" example:"123456"`
+}
+
+type MFARequest struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code" example:"123456"`
+}
+
+type MFAStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+	Scope        string `json:"scope" example:"product:read"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+type TokenExchangeResponse struct {
+	AccessToken     string       `json:"access_token"`
+	IssuedTokenType string       `json:"issued_token_type" example:"urn:ietf:params:oauth:token-type:access_token"`
+	TokenType       string       `json:"token_type" example:"Bearer"`
+	ExpiresIn       int64        `json:"expires_in" example:"600"`
+	User            UserResponse `json:"user"`
+}
+
+type ClientResponse struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Scopes          []string  `json:"scopes"`
+	RedirectURIs    []string  `json:"redirect_uris"`
+	Public          bool      `json:"public"`
+	CreatedAt       time.Time `json:"created_at"`
+	SecretRotatedAt time.Time `json:"secret_rotated_at"`
+}
+
+type ClientSecretResponse struct {
+	ClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type ClientRequest struct {
+	ID           string   `json:"id,omitempty" example:"nightly-reports"`
+	Name         string   `json:"name" example:"Нічні звіти"`
+	Scopes       []string `json:"scopes" example:"product:read"`
+	RedirectURIs []string `json:"redirect_uris" example:"https://app.example.com/callback"`
+	// Public задається лише при створенні.
+	Public bool `json:"public,omitempty"`
+}
+
+type UserinfoResponse struct {
+	Subject           string   `json:"sub"`
+	PreferredUsername string   `json:"preferred_username"`
+	ID                string   `json:"id"`
+	Login             string   `json:"login"`
+	Roles             []string `json:"roles"`
+}
+
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer" example:"http://localhost:8081"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// JWK — публічний ключ підпису (RFC 7517). Заповнені поля залежать від kty:
+// n і e для RSA, crv, x і y для EC, crv і x для OKP.
+type JWK struct {
+	Kty string `json:"kty" example:"RSA"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty" example:"sig"`
+	Alg string `json:"alg,omitempty" example:"RS256"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty" example:"AQAB"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" example:"ak_3f9c0a7b12de"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	TenantID   string     `json:"tenant_id,omitempty"`
+}
+
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" example:"nightly-backup"`
+	Scopes    []string   `json:"scopes" example:"product:read"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type SessionResponse struct {
+	ID         string     `json:"id"`
+	TokenID    string     `json:"token_id"`
+	ClientIP   string     `json:"client_ip" example:"203.0.113.7"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	IssuedAt   time.Time  `json:"issued_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	Current    bool       `json:"current"`
+	TenantID   string     `json:"tenant_id,omitempty"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+type TenantRequest struct {
+	Name string `json:"name" example:"Acme"`
+}
+
+type TenantResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name" example:"Acme"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TenantMemberRequest struct {
+	Role string `json:"role" example:"editor"`
+}
+
+type TenantMemberResponse struct {
+	UserID    string    `json:"user_id"`
+	Login     string    `json:"login"`
+	Role      string    `json:"role" example:"editor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserTenantResponse struct {
+	ID      string `json:"id"`
+	Name    string `json:"name" example:"Acme"`
+	Role    string `json:"role" example:"editor"`
+	Current bool   `json:"current"`
+}
+
+type SwitchTenantRequest struct {
+	TenantID string `json:"tenant_id"`
 }
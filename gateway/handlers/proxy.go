@@ -14,23 +14,604 @@ const (
 
 // ProxyAuthService godoc
 // @Summary Авторизація користувача
-// @Description Приймає логін і пароль, перевіряє користувача та повертає JWT токен
+// @Description Приймає логін і пароль, перевіряє користувача та повертає JWT токен і refresh токен. Якщо потрібен другий фактор, повертає 202 з токеном для POST /api/auth/mfa/verify. Після невдалих спроб вхід сповільнюється, а після кількох поспіль логін тимчасово блокується.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body handlers.AuthRequest true "Дані користувача"
 // @Success 200 {object} handlers.AuthResponse
+// @Success 202 {object} handlers.MFAChallengeResponse "Потрібен код другого фактора"
 // @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
 // @Failure 401 {object} handlers.ErrorResponse "Невірні дані"
+// @Failure 403 {object} handlers.ErrorResponse "Обліковий запис не активний: code account_pending (email не підтверджено) або account_disabled"
+// @Failure 423 {object} handlers.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} handlers.ErrorResponse "Забагато спроб (див. Retry-After)"
 // @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
 // @Router /api/auth [post]
 func ProxyAuthService(w http.ResponseWriter, r *http.Request) {
 	proxyRequest(authServiceURL, w, r)
 }
 
+// ProxyRegister godoc
+// @Summary Реєстрація користувача
+// @Description Створює новий обліковий запис з роллю user. Якщо вказано email, на нього надсилається лист підтвердження; коли підтвердження обов'язкове, email потрібен, а увійти можна лише після підтвердження.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handlers.RegisterRequest true "Дані нового користувача"
+// @Success 201 {object} handlers.UserResponse
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит або пароль не відповідає політиці"
+// @Failure 409 {object} handlers.ErrorResponse "Логін або email уже зайнятий"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Router /api/auth/register [post]
+func ProxyRegister(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyRefresh godoc
+// @Summary Оновлення токенів
+// @Description Обмінює refresh токен на нову пару access/refresh токенів. Повторне використання вже обміняного refresh токена відкликає всю його сім'ю.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handlers.RefreshRequest true "Refresh токен"
+// @Success 200 {object} handlers.AuthResponse
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} handlers.ErrorResponse "Недійсний refresh токен"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Router /api/auth/refresh [post]
+func ProxyRefresh(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyLogout godoc
+// @Summary Вихід із системи
+// @Description Відкликає поточний access токен. Якщо передано refresh токен, відкликається і вся його сім'я.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handlers.LogoutRequest false "Refresh токен (необов'язково)"
+// @Success 200 {object} handlers.SuccessResponse
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/logout [post]
+func ProxyLogout(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyChangePassword godoc
+// @Summary Зміна власного пароля
+// @Description Змінює пароль поточного користувача. Потрібен чинний пароль; новий має відповідати політиці паролів. Після зміни всі токени користувача відкликаються, тож потрібно увійти знову.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handlers.ChangePasswordRequest true "Поточний і новий паролі"
+// @Success 200 {object} handlers.SuccessResponse
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит або пароль не відповідає політиці"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано або невірний поточний пароль"
+// @Failure 423 {object} handlers.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} handlers.ErrorResponse "Забагато спроб (див. Retry-After)"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/password [post]
+func ProxyChangePassword(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyForgotPassword godoc
+// @Summary Запит на скидання пароля
+// @Description Надсилає на email користувача лист з одноразовим токеном скидання пароля. Відповідь однакова, чи існує користувач з таким email, чи ні.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handlers.ForgotPasswordRequest true "Email користувача"
+// @Success 202 {object} handlers.SuccessResponse
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Router /api/auth/password/forgot [post]
+func ProxyForgotPassword(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyPasswordReset godoc
+// @Summary Скидання пароля за токеном
+// @Description Встановлює новий пароль за одноразовим токеном з листа скидання пароля. Після скидання всі токени користувача відкликаються.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handlers.CompletePasswordResetRequest true "Токен з листа і новий пароль"
+// @Success 200 {object} handlers.SuccessResponse
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит, недійсний або прострочений токен, пароль не відповідає політиці"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Router /api/auth/password/reset [post]
+func ProxyPasswordReset(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyVerifyEmail godoc
+// @Summary Підтвердження email
+// @Description Проксі-ендпоінт, який передає запити у auth-service: підтверджує email токеном з листа. Обліковий запис, що чекав на підтвердження, після нього стає активним.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handlers.VerifyEmailRequest true "Токен з листа"
+// @Success 200 {object} handlers.SuccessResponse
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит, недійсний або прострочений токен"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Router /api/auth/email/verify [post]
+func ProxyVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyResendVerification godoc
+// @Summary Повторний лист підтвердження email
+// @Description Проксі-ендпоінт, який передає запити у auth-service: надсилає новий лист підтвердження на непідтверджений email. Відповідь однакова, чи існує такий email, чи ні.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handlers.ResendVerificationRequest true "Email користувача"
+// @Success 202 {object} handlers.SuccessResponse
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Router /api/auth/email/resend [post]
+func ProxyResendVerification(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyTokenExchange godoc
+// @Summary Вхід від імені користувача
+// @Description Обмін токена за RFC 8693 для співробітників підтримки (потрібен дозвіл users:impersonate): поточний токен адміністратора обмінюється на короткостроковий access токен користувача з claim act, що містить адміністратора. Refresh токен не видається. Не можна увійти від імені неактивного користувача або користувача з дозволами, яких немає в адміністратора; токеном імперсонації не можна змінити пароль, MFA, API ключі чи сесії. Gateway логує запити з таким токеном з обома ідентичностями, а auth-service пише видачу і дії з ним у журнал аудиту від імені адміністратора.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "urn:ietf:params:oauth:grant-type:token-exchange"
+// @Param requested_subject formData string true "ID або логін користувача"
+// @Param requested_token_type formData string false "Лише urn:ietf:params:oauth:token-type:access_token"
+// @Param reason formData string false "Причина, наприклад номер звернення; записується в журнал аудиту"
+// @Success 200 {object} handlers.TokenExchangeResponse
+// @Failure 400 {object} handlers.ErrorResponse "invalid_request, unsupported_grant_type або invalid_target"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /api/auth/token/exchange [post]
+func ProxyTokenExchange(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyAPIKeys godoc
+// @Summary API ключі користувача (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: список, створення і відкликання власних API ключів. Ключ показується лише при створенні; з ним запити до gateway надсилаються із заголовком Authorization: ApiKey <ключ> або X-API-Key. Керувати ключами можна лише з JWT користувача.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+//
+// @Param id path string false "ID ключа (для DELETE)"
+// @Param request body handlers.CreateAPIKeyRequest false "Назва, scopes і строк дії (для POST)"
+//
+// @Success 200 {array} handlers.APIKeyResponse
+// @Success 201 {object} handlers.APIKeyCreatedResponse
+// @Success 200 {object} handlers.SuccessResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит або недоступний scope"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Потрібен JWT користувача"
+// @Failure 404 {object} handlers.ErrorResponse "Ключ не знайдено"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/auth/api-keys [get]
+// @Router /api/auth/api-keys [post]
+// @Router /api/auth/api-keys/{id} [delete]
+func ProxyAPIKeys(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxySessions godoc
+// @Summary Сесії входу користувача (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: список активних сесій поточного користувача, завершення однієї сесії або всіх, крім поточної. Токени завершеної сесії одразу перестають проходити перевірку.
+// @Tags sessions
+// @Produce json
+//
+// @Param id path string false "ID сесії (для DELETE)"
+//
+// @Success 200 {array} handlers.SessionResponse
+// @Success 200 {object} handlers.RevokeSessionsResponse
+// @Success 200 {object} handlers.SuccessResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Токен не належить жодній сесії"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Потрібен JWT користувача"
+// @Failure 404 {object} handlers.ErrorResponse "Сесію не знайдено"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/auth/sessions [get]
+// @Router /api/auth/sessions/{id} [delete]
+// @Router /api/auth/sessions/revoke-others [post]
+func ProxySessions(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyUserTenants godoc
+// @Summary Організації користувача (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: організації поточного користувача і перехід поточної сесії в іншу організацію. Перехід видає нові токени з роллю в організації і відкликає поточний access токен.
+// @Tags auth
+// @Accept json
+// @Produce json
+//
+// @Param request body handlers.SwitchTenantRequest false "Організація (для POST)"
+//
+// @Success 200 {array} handlers.UserTenantResponse
+// @Success 200 {object} handlers.AuthResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Потрібен JWT користувача"
+// @Failure 404 {object} handlers.ErrorResponse "Організацію не знайдено"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/auth/tenants [get]
+// @Router /api/auth/tenant [post]
+func ProxyUserTenants(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyMFAVerify godoc
+// @Summary Другий крок входу
+// @Description Обмінює токен з відповіді 202 на POST /api/auth і код TOTP (або одноразовий код відновлення) на JWT токен і refresh токен. Якщо вхід завершує налаштування MFA, у відповіді є коди відновлення — вони показуються лише один раз.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body handlers.MFAVerifyRequest true "Токен другого кроку і код"
+// @Success 200 {object} handlers.AuthResponse
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} handlers.ErrorResponse "Недійсний токен або код"
+// @Failure 423 {object} handlers.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} handlers.ErrorResponse "Забагато спроб (див. Retry-After)"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Router /api/auth/mfa/verify [post]
+func ProxyMFAVerify(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyMFA godoc
+// @Summary Керування власним MFA (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: стан, налаштування і вимкнення TOTP, нові коди відновлення.
+// @Tags mfa
+// @Accept json
+// @Produce json
+//
+// @Param request body handlers.MFARequest false "Код TOTP; для disable також пароль"
+//
+// @Success 200 {object} handlers.MFAStatusResponse
+// @Success 200 {object} handlers.MFASetupResponse
+// @Success 200 {object} handlers.RecoveryCodesResponse
+// @Success 200 {object} handlers.SuccessResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит або невірний код"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "MFA вимагає роль користувача"
+// @Failure 409 {object} handlers.ErrorResponse "MFA уже ввімкнено"
+// @Failure 423 {object} handlers.ErrorResponse "Логін тимчасово заблоковано (див. Retry-After)"
+// @Failure 429 {object} handlers.ErrorResponse "Забагато спроб (див. Retry-After)"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/auth/mfa [get]
+// @Router /api/auth/mfa/setup [post]
+// @Router /api/auth/mfa/enable [post]
+// @Router /api/auth/mfa/disable [post]
+// @Router /api/auth/mfa/recovery-codes [post]
+func ProxyMFA(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyUsers godoc
+// @Summary Адміністрування користувачів (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service, зокрема імпорт користувачів з CSV або JSON і експорт без хешів паролів. Потрібен дозвіл users:manage.
+// @Tags users
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Produce text/csv
+//
+// @Param id path string false "ID користувача (для GET, PATCH, PUT, DELETE)"
+// @Param sid path string false "ID сесії (для DELETE сесії)"
+// @Param login query string false "Пошук за логіном (для списку)"
+// @Param status query string false "Стан облікового запису: pending, active або disabled (для списку)"
+// @Param limit query int false "Кількість записів (для списку, за замовчуванням 20)"
+// @Param offset query int false "Зсув від початку списку"
+// @Param format query string false "csv або json (для import і export)"
+// @Param dry_run query bool false "Лише перевірити файл (для import)"
+// @Param generate_passwords query bool false "Згенерувати паролі новим користувачам (для import)"
+// @Param request body handlers.UpdateUserRequest false "Нові ролі та/або email (для PATCH)"
+//
+// @Success 200 {object} handlers.UserListResponse
+// @Success 200 {object} handlers.UserDetailsResponse
+// @Success 200 {array} handlers.UserDetailsResponse
+// @Success 200 {object} handlers.UserImportResponse
+// @Success 200 {array} handlers.SessionResponse
+// @Success 200 {object} handlers.SuccessResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} handlers.ErrorResponse "Користувача не знайдено"
+// @Failure 409 {object} handlers.ErrorResponse "Email уже використовується"
+// @Failure 413 {object} handlers.ErrorResponse "Файл імпорту завеликий"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/users [get]
+// @Router /api/users/import [post]
+// @Router /api/users/export [get]
+// @Router /api/users/{id} [get]
+// @Router /api/users/{id} [patch]
+// @Router /api/users/{id} [delete]
+// @Router /api/users/{id}/password [put]
+// @Router /api/users/{id}/revoke-tokens [post]
+// @Router /api/users/{id}/disable [post]
+// @Router /api/users/{id}/enable [post]
+// @Router /api/users/{id}/unlock [post]
+// @Router /api/users/{id}/mfa [delete]
+// @Router /api/users/{id}/sessions [get]
+// @Router /api/users/{id}/sessions/{sid} [delete]
+func ProxyUsers(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyAudit godoc
+// @Summary Журнал аудиту (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: сторінка подій журналу аудиту з фільтрами і вивантаження всіх відібраних подій у CSV або JSON. Потрібен дозвіл audit:read.
+// @Tags audit
+// @Produce json
+// @Produce text/csv
+//
+// @Param type query string false "Тип події, наприклад login.failure"
+// @Param actor query string false "ID або логін автора дії"
+// @Param target query string false "ID або логін користувача, над яким виконано дію"
+// @Param ip query string false "IP адреса клієнта"
+// @Param from query string false "Початок періоду (RFC 3339, включно)"
+// @Param to query string false "Кінець періоду (RFC 3339, не включно)"
+// @Param limit query int false "Кількість записів (для списку, за замовчуванням 50)"
+// @Param offset query int false "Зсув від початку списку"
+// @Param format query string false "csv або json (для export)"
+//
+// @Success 200 {object} handlers.AuditListResponse
+// @Success 200 {array} handlers.AuditEventResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Недостатньо прав"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/audit [get]
+// @Router /api/audit/export [get]
+func ProxyAudit(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyKeys godoc
+// @Summary Керування ключами підпису JWT (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: генерація, активація та виведення ключів. Потрібен дозвіл keys:manage.
+// @Tags keys
+// @Accept json
+// @Produce json
+//
+// @Param kid path string false "Ідентифікатор ключа (для promote, retire)"
+//
+// @Success 200 {array} handlers.SigningKeyResponse
+// @Success 200 {object} handlers.SigningKeyResponse
+// @Success 201 {object} handlers.SigningKeyResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} handlers.ErrorResponse "Ключ не знайдено"
+// @Failure 409 {object} handlers.ErrorResponse "Конфлікт стану ключа"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/keys [get]
+// @Router /api/keys [post]
+// @Router /api/keys/{kid}/promote [post]
+// @Router /api/keys/{kid}/retire [post]
+func ProxyKeys(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyOAuthToken godoc
+// @Summary Видача токенів OAuth
+// @Description Ендпоінт токенів OAuth 2.0. Grant client_credentials видає access токен сервісу за id і секретом клієнта (HTTP Basic або client_id і client_secret у формі). Grant authorization_code обмінює код з /oauth/authorize і code_verifier PKCE на access і refresh токени користувача, а зі scope openid — ще й ID токен; публічний клієнт передає лише client_id.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "client_credentials або authorization_code"
+// @Param scope formData string false "client_credentials: потрібні дозволи через пробіл; за замовчуванням — усі дозволені клієнту"
+// @Param code formData string false "authorization_code: код з /oauth/authorize"
+// @Param redirect_uri formData string false "authorization_code: той самий redirect_uri, що в запиті авторизації"
+// @Param code_verifier formData string false "authorization_code: PKCE code_verifier"
+// @Param client_id formData string false "ID клієнта, якщо не використовується HTTP Basic"
+// @Success 200 {object} handlers.TokenResponse
+// @Failure 400 {object} handlers.ErrorResponse "invalid_request, invalid_grant, unauthorized_client, unsupported_grant_type або invalid_scope"
+// @Failure 401 {object} handlers.ErrorResponse "invalid_client"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Security BasicAuth
+// @Router /oauth/token [post]
+func ProxyOAuthToken(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyAuthorize godoc
+// @Summary Сторінка входу (authorization code)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: сторінка входу grant authorization_code з PKCE для браузерних застосунків. GET показує форму, POST приймає логін і пароль (і код другого фактора) та повертає браузер на redirect_uri з параметрами code і state.
+// @Tags oidc
+// @Produce html
+// @Param client_id query string true "ID клієнта"
+// @Param redirect_uri query string true "Зареєстрована адреса повернення"
+// @Param response_type query string true "code"
+// @Param scope query string false "openid, profile через пробіл"
+// @Param state query string false "Значення, яке повертається застосунку без змін"
+// @Param nonce query string false "Значення для claim nonce в ID токені"
+// @Param code_challenge query string true "BASE64URL(SHA256(code_verifier))"
+// @Param code_challenge_method query string true "S256"
+// @Success 200 {string} string "Сторінка входу"
+// @Success 302 {string} string "Редирект на redirect_uri"
+// @Failure 400 {string} string "Невідомий клієнт або redirect_uri"
+// @Router /oauth/authorize [get]
+// @Router /oauth/authorize [post]
+func ProxyAuthorize(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyOpenIDConfiguration godoc
+// @Summary OpenID Connect discovery
+// @Description Проксі-ендпоінт, який передає запити у auth-service: метадані провайдера OpenID Connect
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} handlers.OpenIDConfiguration
+// @Router /.well-known/openid-configuration [get]
+func ProxyOpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyJWKS godoc
+// @Summary Публічні ключі підпису (JWKS)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: публічні ключі для локальної перевірки JWT. При підписі спільним секретом (HS256) список порожній.
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} handlers.JWKS
+// @Router /.well-known/jwks.json [get]
+func ProxyJWKS(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyUserinfo godoc
+// @Summary Дані користувача (OpenID Connect)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: дані власника access токена — sub (ID користувача), логін і ролі
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} handlers.UserinfoResponse
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Потрібен JWT користувача"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+// @Security BearerAuth
+// @Router /userinfo [get]
+// @Router /userinfo [post]
+func ProxyUserinfo(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyClients godoc
+// @Summary Керування OAuth клієнтами (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: OAuth клієнти, їх scopes, redirect URI і ротація секретів. Секрет повертається лише при створенні та ротації; публічний клієнт секрету не має. Потрібен дозвіл clients:manage.
+// @Tags clients
+// @Accept json
+// @Produce json
+//
+// @Param id path string false "ID клієнта (для GET, PUT, DELETE, rotate-secret)"
+// @Param request body handlers.ClientRequest false "Клієнт (для POST, PUT)"
+//
+// @Success 200 {array} handlers.ClientResponse
+// @Success 200 {object} handlers.ClientResponse
+// @Success 201 {object} handlers.ClientSecretResponse
+// @Success 200 {object} handlers.ClientSecretResponse
+// @Success 200 {object} handlers.SuccessResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит, невідомий дозвіл або redirect URI"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} handlers.ErrorResponse "Клієнта не знайдено"
+// @Failure 409 {object} handlers.ErrorResponse "Клієнт уже існує"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/clients [get]
+// @Router /api/clients [post]
+// @Router /api/clients/{id} [get]
+// @Router /api/clients/{id} [put]
+// @Router /api/clients/{id} [delete]
+// @Router /api/clients/{id}/rotate-secret [post]
+func ProxyClients(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyRoles godoc
+// @Summary Керування ролями і дозволами (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service: ролі, їх дозволи та довідник дозволів. Потрібен дозвіл roles:manage.
+// @Tags roles
+// @Accept json
+// @Produce json
+//
+// @Param name path string false "Назва ролі або дозволу (для PUT, DELETE)"
+// @Param request body handlers.RoleRequest false "Роль (для POST, PUT /api/roles)"
+//
+// @Success 200 {array} handlers.RoleResponse
+// @Success 200 {array} handlers.PermissionResponse
+// @Success 201 {object} handlers.RoleResponse
+// @Success 200 {object} handlers.SuccessResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} handlers.ErrorResponse "Не знайдено"
+// @Failure 409 {object} handlers.ErrorResponse "Уже існує"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/roles [get]
+// @Router /api/roles [post]
+// @Router /api/roles/{name} [put]
+// @Router /api/roles/{name} [delete]
+// @Router /api/permissions [get]
+// @Router /api/permissions [post]
+// @Router /api/permissions/{name} [delete]
+func ProxyRoles(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
+// ProxyTenants godoc
+// @Summary Керування організаціями (проксі)
+// @Description Проксі-ендпоінт, який передає запити у auth-service. Створення, список і видалення організацій потребують дозволу tenants:manage (адміністратор платформи); учасниками організації керує також її адміністратор.
+// @Tags tenants
+// @Accept json
+// @Produce json
+//
+// @Param id path string false "ID організації"
+// @Param user path string false "ID або логін користувача (для members)"
+// @Param request body handlers.TenantMemberRequest false "Роль учасника (для PUT members)"
+//
+// @Success 200 {array} handlers.TenantResponse
+// @Success 201 {object} handlers.TenantResponse
+// @Success 200 {array} handlers.TenantMemberResponse
+// @Success 200 {object} handlers.TenantMemberResponse
+// @Success 200 {object} handlers.SuccessResponse
+//
+// @Failure 400 {object} handlers.ErrorResponse "Некоректний запит"
+// @Failure 401 {object} handlers.ErrorResponse "Не авторизовано"
+// @Failure 403 {object} handlers.ErrorResponse "Недостатньо прав"
+// @Failure 404 {object} handlers.ErrorResponse "Не знайдено"
+// @Failure 409 {object} handlers.ErrorResponse "Уже існує або це останній адміністратор організації"
+// @Failure 500 {object} handlers.ErrorResponse "Помилка сервера"
+//
+// @Security BearerAuth
+// @Router /api/tenants [get]
+// @Router /api/tenants [post]
+// @Router /api/tenants/{id} [delete]
+// @Router /api/tenants/{id}/members [get]
+// @Router /api/tenants/{id}/members/{user} [put]
+// @Router /api/tenants/{id}/members/{user} [delete]
+func ProxyTenants(w http.ResponseWriter, r *http.Request) {
+	proxyRequest(authServiceURL, w, r)
+}
+
 // ProxyProductService godoc
 // @Summary Операції з продуктами (проксі)
-// @Description Проксі-ендпоінт, який передає запити у product-service.
+// @Description Проксі-ендпоінт, який передає запити у product-service. GET потребує дозволу product:read, POST, PUT і DELETE — product:write. Продукти належать організації, в якій діє токен: інших організацій не видно.
 // @Tags product
 // @Accept json
 // @Produce json
@@ -46,10 +627,12 @@ func ProxyAuthService(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} handlers.Product
 //
 // @Failure 400 {string} string "Некоректний запит"
+// @Failure 403 {object} handlers.ErrorResponse "Недостатньо прав"
 // @Failure 404 {string} string "Продукт не знайдено"
 // @Failure 500 {string} string "Помилка сервера"
 //
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /api/product [get]
 // @Router /api/product [delete]
 //
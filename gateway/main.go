@@ -9,18 +9,28 @@
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API ключ користувача; також приймається як "ApiKey" у заголовку Authorization.
+
+// @securityDefinitions.basic BasicAuth
+
 package main
 
 import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"ksv/rest-mikroservice/gateway/handlers"
 	"ksv/rest-mikroservice/gateway/middleware"
 
 	_ "ksv/rest-mikroservice/gateway/docs"
 
+	"github.com/ianschenck/envflag"
+	"github.com/joho/godotenv"
 	_ "github.com/swaggo/files"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
@@ -31,11 +41,55 @@ const (
 
 func main() {
 
-	router := setupRouter()
+	err := godotenv.Load(".env")
+	if err != nil {
+		log.Println("Warning: .env file not found, relying on system environment variables")
+	}
+
+	var authMode = envflag.String("AUTH_MODE", middleware.ModeRemote, "Режим перевірки токенів: remote, local або hybrid")
+	var secretKey = envflag.String("JWT_SECRET_KEY", "", "Спільний секрет HS256 для локальної перевірки токенів")
+	var jwksURL = envflag.String("AUTH_JWKS_URL", "http://localhost:8081/.well-known/jwks.json", "Адреса JWKS auth-service для локальної перевірки асиметричних токенів")
+	var jwksRefresh = envflag.Duration("JWKS_REFRESH_INTERVAL", 5*time.Minute, "Як часто оновлювати кеш JWKS")
+	var revocationTTL = envflag.Duration("REVOCATION_CHECK_TTL", 30*time.Second, "Скільки кешувати результат перевірки відкликання в режимі hybrid")
+	var clientID = envflag.String("AUTH_CLIENT_ID", "", "Ідентифікатор OAuth клієнта gateway зі scope tokens:introspect для /oauth/introspect auth-service; якщо не задано, використовується /auth/validate")
+	var clientSecret = envflag.String("AUTH_CLIENT_SECRET", "", "Секрет gateway для /oauth/introspect")
+	var issuer = envflag.String("AUTH_ISSUER", "http://localhost:8081", "Очікуваний iss токенів при локальній перевірці; порожнє значення вимикає перевірку")
+	var audience = envflag.String("AUTH_AUDIENCE", "rest-mikroservice", "Очікуваний aud токенів при локальній перевірці; порожнє значення вимикає перевірку")
+	var policyFile = envflag.String("POLICY_FILE", "", "JSON файл з правилами доступу; якщо не задано, діє вбудована політика")
+	var identityKey = envflag.String("IDENTITY_SIGNING_KEY", "", "Спільний секрет для підпису заголовків X-User-*, що передаються сервісам")
+
+	envflag.Parse()
+
+	verifierConfig := middleware.VerifierConfig{
+		Mode:                *authMode,
+		SecretKey:           *secretKey,
+		JWKSURL:             *jwksURL,
+		JWKSRefreshInterval: *jwksRefresh,
+		RevocationCheckTTL:  *revocationTTL,
+		ClientID:            *clientID,
+		ClientSecret:        *clientSecret,
+		Issuer:              *issuer,
+		Audience:            *audience,
+	}
+	verifier, err := middleware.NewTokenVerifier(verifierConfig)
+	if err != nil {
+		log.Fatalf("Failed to configure token verification: %v", err)
+	}
+	log.Printf("Token verification mode: %s", *authMode)
+	if *identityKey == "" {
+		log.Println("Warning: IDENTITY_SIGNING_KEY is not set, identity headers are forwarded unsigned")
+	}
+
+	policy, err := middleware.LoadPolicy(*policyFile)
+	if err != nil {
+		log.Fatalf("Failed to load access policy: %v", err)
+	}
+
+	router := middleware.PolicyMiddleware(policy)(setupRouter())
 
 	server := http.Server{
 		Addr:    port,
-		Handler: middleware.AuthMiddleware()(router),
+		Handler: middleware.AuthMiddleware(verifier, middleware.NewAPIKeyVerifier(verifierConfig), []byte(*identityKey))(router),
 	}
 
 	fmt.Printf("Gateway starting on port %s...\n", port)
@@ -51,6 +105,50 @@ func setupRouter() http.Handler {
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
 
 	mux.HandleFunc("/api/auth", handlers.ProxyAuthService)
+	mux.HandleFunc("/api/auth/register", handlers.ProxyRegister)
+	mux.HandleFunc("/api/auth/refresh", handlers.ProxyRefresh)
+	mux.HandleFunc("/api/auth/logout", handlers.ProxyLogout)
+	mux.HandleFunc("/api/auth/password", handlers.ProxyChangePassword)
+	mux.HandleFunc("/api/auth/password/forgot", handlers.ProxyForgotPassword)
+	mux.HandleFunc("/api/auth/password/reset", handlers.ProxyPasswordReset)
+	mux.HandleFunc("/api/auth/email/verify", handlers.ProxyVerifyEmail)
+	mux.HandleFunc("/api/auth/email/resend", handlers.ProxyResendVerification)
+	mux.HandleFunc("/api/auth/token/exchange", handlers.ProxyTokenExchange)
+	mux.HandleFunc("/api/auth/api-keys", handlers.ProxyAPIKeys)
+	mux.HandleFunc("/api/auth/api-keys/", handlers.ProxyAPIKeys)
+	mux.HandleFunc("/api/auth/sessions", handlers.ProxySessions)
+	mux.HandleFunc("/api/auth/sessions/", handlers.ProxySessions)
+	mux.HandleFunc("/api/auth/mfa/verify", handlers.ProxyMFAVerify)
+	mux.HandleFunc("/api/auth/mfa", handlers.ProxyMFA)
+	mux.HandleFunc("/api/auth/mfa/", handlers.ProxyMFA)
+	mux.HandleFunc("/api/auth/tenants", handlers.ProxyUserTenants)
+	mux.HandleFunc("/api/auth/tenant", handlers.ProxyUserTenants)
+
+	mux.HandleFunc("/api/users", handlers.ProxyUsers)
+	mux.HandleFunc("/api/users/", handlers.ProxyUsers)
+
+	mux.HandleFunc("/api/audit", handlers.ProxyAudit)
+	mux.HandleFunc("/api/audit/", handlers.ProxyAudit)
+
+	mux.HandleFunc("/api/keys", handlers.ProxyKeys)
+	mux.HandleFunc("/api/keys/", handlers.ProxyKeys)
+
+	mux.HandleFunc("/oauth/token", handlers.ProxyOAuthToken)
+	mux.HandleFunc("/oauth/authorize", handlers.ProxyAuthorize)
+	mux.HandleFunc("/.well-known/openid-configuration", handlers.ProxyOpenIDConfiguration)
+	mux.HandleFunc("/.well-known/jwks.json", handlers.ProxyJWKS)
+	mux.HandleFunc("/userinfo", handlers.ProxyUserinfo)
+
+	mux.HandleFunc("/api/clients", handlers.ProxyClients)
+	mux.HandleFunc("/api/clients/", handlers.ProxyClients)
+
+	mux.HandleFunc("/api/tenants", handlers.ProxyTenants)
+	mux.HandleFunc("/api/tenants/", handlers.ProxyTenants)
+
+	mux.HandleFunc("/api/roles", handlers.ProxyRoles)
+	mux.HandleFunc("/api/roles/", handlers.ProxyRoles)
+	mux.HandleFunc("/api/permissions", handlers.ProxyRoles)
+	mux.HandleFunc("/api/permissions/", handlers.ProxyRoles)
 
 	mux.HandleFunc("/api/product", handlers.ProxyProductService)
 
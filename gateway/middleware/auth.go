@@ -2,74 +2,202 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
-	_ "ksv/rest-mikroservice/auth-service/models"
+	"ksv/rest-mikroservice/auth-service/token"
+	"ksv/rest-mikroservice/internal/identity"
 )
 
 const (
 	authServiceURL = "http://localhost:8081"
 )
 
+type VerifierConfig struct {
+	// Mode — remote, local або hybrid.
+	Mode string
+	// SecretKey — спільний секрет HS256; порожній, якщо токени підписуються лише асиметрично.
+	SecretKey string
+	// JWKSURL — адреса JWKS auth-service; порожня, якщо асиметричні ключі не використовуються.
+	JWKSURL             string
+	JWKSRefreshInterval time.Duration
+	RevocationCheckTTL  time.Duration
+	// ClientID і ClientSecret — облікові дані gateway для /oauth/introspect;
+	// якщо не задані, токени перевіряються через /auth/validate.
+	ClientID     string
+	ClientSecret string
+	// Issuer і Audience — очікувані iss і aud токенів при локальній перевірці;
+	// порожні значення вимикають відповідну перевірку.
+	Issuer   string
+	Audience string
+}
+
+// NewTokenVerifier створює верифікатор для вибраного режиму:
+// remote — кожен токен перевіряє auth-service;
+// local — токени перевіряються лише в gateway, відкликання не враховується;
+// hybrid — локальна перевірка плюс кешована перевірка відкликання в auth-service.
+func NewTokenVerifier(cfg VerifierConfig) (TokenVerifier, error) {
+	remote := NewRemoteVerifier(authServiceURL, cfg.ClientID, cfg.ClientSecret)
+	if cfg.Mode == ModeRemote {
+		return remote, nil
+	}
+
+	if cfg.SecretKey == "" && cfg.JWKSURL == "" {
+		return nil, fmt.Errorf("%s mode requires JWT_SECRET_KEY or AUTH_JWKS_URL", cfg.Mode)
+	}
+	var jwks *JWKSCache
+	if cfg.JWKSURL != "" {
+		jwks = NewJWKSCache(cfg.JWKSURL, cfg.JWKSRefreshInterval)
+		if err := jwks.Refresh(context.Background()); err != nil {
+			log.Println("Warning: initial JWKS fetch failed:", err)
+		}
+	}
+	local := NewLocalVerifier(cfg.SecretKey, jwks).WithIssuer(cfg.Issuer, cfg.Audience)
+
+	switch cfg.Mode {
+	case ModeLocal:
+		return local, nil
+	case ModeHybrid:
+		return NewHybridVerifier(local, remote, cfg.RevocationCheckTTL), nil
+	}
+	return nil, fmt.Errorf("unknown auth mode %q", cfg.Mode)
+}
+
+// NewAPIKeyVerifier створює верифікатор API ключів користувачів.
+func NewAPIKeyVerifier(cfg VerifierConfig) APIKeyVerifier {
+	return NewRemoteVerifier(authServiceURL, cfg.ClientID, cfg.ClientSecret)
+}
+
+// publicPaths не потребують токена. Порівняння точне, щоб, наприклад,
+// /api/auth/logout не ставав публічним через префікс /api/auth.
+var publicPaths = map[string]bool{
+	"/api/auth":                         true,
+	"/api/auth/register":                true,
+	"/api/auth/refresh":                 true,
+	"/api/auth/mfa/verify":              true,
+	"/api/auth/password/forgot":         true,
+	"/api/auth/password/reset":          true,
+	"/api/auth/email/verify":            true,
+	"/api/auth/email/resend":            true,
+	"/oauth/token":                      true,
+	"/oauth/authorize":                  true,
+	"/.well-known/openid-configuration": true,
+	"/.well-known/jwks.json":            true,
+}
+
+var publicPrefixes = []string{
+	"/swagger/",
+}
+
+func isPublicPath(path string) bool {
+	if publicPaths[path] {
+		return true
+	}
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// logSubject повертає, від чийого імені зроблено запит; для токена
+// імперсонації — обидві ідентичності.
+func logSubject(claims *token.UserClaims) string {
+	if claims.IsImpersonated() {
+		return fmt.Sprintf("%s (impersonated by %s, id %s)", claims.Subject, claims.Actor.Subject, claims.Actor.ID)
+	}
+	return claims.Subject
+}
+
 type wrappedWriter struct {
 	http.ResponseWriter
 	statusCode int
 }
 
-func AuthMiddleware() func(http.Handler) http.Handler {
+func bearerToken(r *http.Request) (string, bool) {
+	headerParts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" || headerParts[1] == "" {
+		return "", false
+	}
+	return headerParts[1], true
+}
+
+// apiKey повертає API ключ із заголовка Authorization: ApiKey або X-API-Key.
+func apiKey(r *http.Request) (string, bool) {
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok && key != "" {
+		return key, true
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, true
+	}
+	return "", false
+}
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// ClaimsFromContext повертає claims перевіреного токена або nil для публічних шляхів.
+func ClaimsFromContext(ctx context.Context) *token.UserClaims {
+	claims, _ := ctx.Value(claimsContextKey).(*token.UserClaims)
+	return claims
+}
+
+// AuthMiddleware перевіряє Bearer токен або API ключ (Authorization: ApiKey
+// чи X-API-Key) і передає користувача далі: claims — у
+// контексті запиту, а сервісам — у заголовках X-User-* (для токенів OAuth
+// клієнтів — у X-Client-ID). Заголовки, надіслані
+// клієнтом, завжди видаляються. Якщо identitySecret не порожній, заголовки
+// підписуються HMAC.
+func AuthMiddleware(verifier TokenVerifier, apiKeys APIKeyVerifier, identitySecret []byte) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			identity.Strip(r.Header)
 
-			publicPaths := map[string]bool{
-				"/api/auth":           true,
-				"/swagger/":           true,
-				"/swagger/index.html": true,
-			}
-
-			for pub := range publicPaths {
-				if strings.HasPrefix(r.URL.Path, pub) {
-					log.Println("Public path, no token required.")
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
-
-			if publicPaths[r.URL.Path] {
+			if isPublicPath(r.URL.Path) {
 				log.Println("Public path, no token required.")
 				next.ServeHTTP(w, r)
 				log.Println(http.StatusOK, r.Method, r.URL.Path, time.Since(start))
 				return
 			}
 
-			req, err := http.NewRequest(http.MethodGet, authServiceURL+"/auth/validate", nil)
-			if err != nil {
-				http.Error(w, "Could not create auth request", http.StatusInternalServerError)
+			var claims *token.UserClaims
+			var err error
+			if key, ok := apiKey(r); ok {
+				claims, err = apiKeys.VerifyAPIKey(r.Context(), key)
+			} else if tokenStr, ok := bearerToken(r); ok {
+				claims, err = verifier.Verify(r.Context(), tokenStr)
+			} else {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				log.Println(http.StatusUnauthorized, r.Method, r.URL.Path, time.Since(start))
 				return
 			}
-
-			req.Header.Set("Authorization", r.Header.Get("Authorization"))
-
-			client := &http.Client{}
-			resp, err := client.Do(req)
 			if err != nil {
-				http.Error(w, "Error contacting auth service", http.StatusInternalServerError)
-				log.Println("Auth service error:", err)
-				return
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
+				if errors.Is(err, ErrAuthServiceFailure) {
+					http.Error(w, "Error contacting auth service", http.StatusInternalServerError)
+					log.Println("Auth service error:", err)
+					return
+				}
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				log.Println(http.StatusUnauthorized, r.Method, r.URL.Path, time.Since(start))
 				return
 			}
 
-			ctx := context.Background()
-			r = r.WithContext(ctx)
+			identity.Set(r.Header, &identity.Identity{
+				UserID:   claims.ID,
+				Login:    claims.Login,
+				Roles:    claims.Roles,
+				ClientID: claims.ClientID,
+				TenantID: claims.TenantID,
+			}, identitySecret, start)
+
+			ctx := context.WithValue(r.Context(), claimsContextKey, claims)
 
 			wrapped := &wrappedWriter{
 				ResponseWriter: w,
@@ -77,7 +205,7 @@ func AuthMiddleware() func(http.Handler) http.Handler {
 			}
 
 			next.ServeHTTP(wrapped, r.WithContext(ctx))
-			log.Println(http.StatusOK, r.Method, r.URL.Path, time.Since(start))
+			log.Println(http.StatusOK, r.Method, r.URL.Path, logSubject(claims), time.Since(start))
 		})
 	}
 }
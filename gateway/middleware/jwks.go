@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+// minJWKSRefetchInterval обмежує позапланові запити JWKS при невідомому kid,
+// щоб токени з вигаданим kid не перетворювались на навантаження на auth-service.
+const minJWKSRefetchInterval = 30 * time.Second
+
+type cachedKey struct {
+	alg    string
+	public crypto.PublicKey
+}
+
+// JWKSCache завантажує публічні ключі auth-service і оновлює їх раз на
+// refreshInterval, а також одразу, коли трапляється токен з невідомим kid.
+type JWKSCache struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]cachedKey
+	fetchedAt time.Time
+}
+
+func NewJWKSCache(url string, refreshInterval time.Duration) *JWKSCache {
+	return &JWKSCache{
+		url:             url,
+		client:          &http.Client{Timeout: 5 * time.Second},
+		refreshInterval: refreshInterval,
+		keys:            map[string]cachedKey{},
+	}
+}
+
+// Key повертає публічний ключ для kid, якщо його алгоритм збігається з alg.
+func (c *JWKSCache) Key(ctx context.Context, kid string, alg string) (crypto.PublicKey, error) {
+	key, ok := c.lookup(kid)
+	if c.claimRefresh(ok) {
+		if err := c.fetch(ctx); err != nil {
+			log.Println("JWKS refresh error:", err)
+		}
+		key, ok = c.lookup(kid)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.alg != "" && key.alg != alg {
+		return nil, fmt.Errorf("signing key %q is %s, token uses %s", kid, key.alg, alg)
+	}
+	return key.public, nil
+}
+
+// Refresh завантажує JWKS. Якщо запит не вдався, лишаються попередні ключі.
+func (c *JWKSCache) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+
+	return c.fetch(ctx)
+}
+
+func (c *JWKSCache) lookup(kid string) (cachedKey, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// claimRefresh вирішує, чи потрібно оновити ключі, і якщо так — резервує
+// оновлення за поточним запитом, щоб паралельні запити не дублювали його.
+func (c *JWKSCache) claimRefresh(known bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	since := time.Since(c.fetchedAt)
+	if since <= c.refreshInterval && (known || since <= minJWKSRefetchInterval) {
+		return false
+	}
+	c.fetchedAt = time.Now()
+	return true
+}
+
+func (c *JWKSCache) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected JWKS status %d", resp.StatusCode)
+	}
+
+	var jwks token.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("error decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]cachedKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		public, err := jwk.PublicKey()
+		if err != nil {
+			log.Printf("Skipping JWK %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = cachedKey{alg: jwk.Alg, public: public}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+	return nil
+}
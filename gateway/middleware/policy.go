@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+//go:embed default_policy.json
+var defaultPolicy []byte
+
+// PolicyRule задає, що потрібно для запиту Method до Path. Method "" або "*"
+// означає будь-який метод; Path, що закінчується на "/*", — префікс.
+// Для доступу потрібна хоча б одна з Roles (якщо задано) і всі Permissions.
+type PolicyRule struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Policy — правила доступу gateway. Дозволи користувача беруться з токена
+// (їх видає auth-service за ролями з БД); Roles може додати ролям дозволи
+// поверх цього. Дозвіл "*" дає все, "product:*" — усі дозволи з префіксом "product:".
+// Правила перевіряються по порядку, застосовується перше, що підійшло.
+// Запити, для яких правила немає, пропускаються для будь-якого
+// автентифікованого користувача.
+type Policy struct {
+	Roles map[string][]string `json:"roles"`
+	Rules []PolicyRule        `json:"rules"`
+}
+
+// LoadPolicy читає політику з JSON файлу. Якщо path порожній, повертається
+// вбудована політика за замовчуванням.
+func LoadPolicy(path string) (*Policy, error) {
+	data := defaultPolicy
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading policy: %w", err)
+		}
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("error parsing policy: %w", err)
+	}
+	for i, rule := range p.Rules {
+		if rule.Path == "" {
+			return nil, fmt.Errorf("policy rule %d has no path", i)
+		}
+		if strings.Contains(strings.TrimSuffix(rule.Path, "/*"), "*") {
+			return nil, fmt.Errorf("policy rule %d: \"*\" is only allowed as a trailing \"/*\" in path %q", i, rule.Path)
+		}
+	}
+	return &p, nil
+}
+
+func (p *Policy) match(method string, path string) *PolicyRule {
+	for i, rule := range p.Rules {
+		if rule.Method != "" && rule.Method != "*" && !strings.EqualFold(rule.Method, method) {
+			continue
+		}
+		if strings.HasSuffix(rule.Path, "/*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(rule.Path, "*")) {
+				return &p.Rules[i]
+			}
+			continue
+		}
+		if rule.Path == path {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// permissions повертає дозволи з токена разом з тими, які політика додає ролям користувача.
+func (p *Policy) permissions(claims *token.UserClaims) []string {
+	granted := append([]string{}, claims.Permissions...)
+	for _, role := range claims.Roles {
+		granted = append(granted, p.Roles[role]...)
+	}
+	return granted
+}
+
+// Authorize повертає порожній рядок, якщо доступ дозволено, або опис того,
+// чого бракує.
+func (p *Policy) Authorize(method string, path string, claims *token.UserClaims) string {
+	rule := p.match(method, path)
+	if rule == nil {
+		return ""
+	}
+
+	if len(rule.Roles) > 0 && !hasAny(claims.Roles, rule.Roles) {
+		return "Missing role: one of " + strings.Join(rule.Roles, ", ")
+	}
+
+	granted := p.permissions(claims)
+	for _, required := range rule.Permissions {
+		if !permitted(granted, required) {
+			return "Missing permission: " + required
+		}
+	}
+	return ""
+}
+
+func hasAny(have []string, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func permitted(granted []string, required string) bool {
+	for _, g := range granted {
+		if g == "*" || g == required {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(g, "*"); ok && strings.HasPrefix(required, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyMiddleware перевіряє права користувача за політикою. Має стояти після
+// AuthMiddleware, бо бере claims з контексту; публічні шляхи не перевіряються.
+func PolicyMiddleware(policy *Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := ClaimsFromContext(r.Context())
+			if claims == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if reason := policy.Authorize(r.Method, r.URL.Path, claims); reason != "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{"error": reason})
+				log.Println(http.StatusForbidden, r.Method, r.URL.Path, claims.Subject, reason)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+func TestPolicy_Match(t *testing.T) {
+	p := &Policy{Rules: []PolicyRule{
+		{Method: "GET", Path: "/api/products"},
+		{Method: "*", Path: "/api/users/*"},
+		{Path: "/api/a*b"},
+	}}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{"Exact", "GET", "/api/products", "/api/products"},
+		{"ExactMethodCaseInsensitive", "get", "/api/products", "/api/products"},
+		{"ExactOtherMethod", "POST", "/api/products", ""},
+		{"ExactIsNotPrefix", "GET", "/api/products/1", ""},
+		{"PrefixChild", "DELETE", "/api/users/42", "/api/users/*"},
+		{"PrefixNested", "GET", "/api/users/42/roles", "/api/users/*"},
+		{"PrefixNeedsSlash", "GET", "/api/users", ""},
+		{"PrefixSiblingName", "GET", "/api/usersx/1", ""},
+		{"EmbeddedStarIsLiteral", "GET", "/api/a*b", "/api/a*b"},
+		{"EmbeddedStarIsNotWildcard", "GET", "/api/axyzb", ""},
+		{"NoRule", "GET", "/api/other", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := p.match(tt.method, tt.path)
+			if tt.want == "" {
+				assert.Nil(t, rule)
+				return
+			}
+			require.NotNil(t, rule)
+			assert.Equal(t, tt.want, rule.Path)
+		})
+	}
+}
+
+func TestLoadPolicy_Wildcards(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"Exact", "/api/products", false},
+		{"TrailingPrefix", "/api/products/*", false},
+		{"EmbeddedStar", "/api/*/roles", true},
+		{"TrailingStarWithoutSlash", "/api/products*", true},
+		{"StarInPrefix", "/api/*/*", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "policy.json")
+			data := `{"rules": [{"method": "GET", "path": "` + tt.path + `"}]}`
+			require.NoError(t, os.WriteFile(file, []byte(data), 0o600))
+
+			p, err := LoadPolicy(file)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.path, p.Rules[0].Path)
+		})
+	}
+}
+
+func TestLoadPolicy_Default(t *testing.T) {
+	p, err := LoadPolicy("")
+	require.NoError(t, err)
+	assert.NotEmpty(t, p.Rules)
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	p := &Policy{
+		Roles: map[string][]string{"editor": {"product:*"}},
+		Rules: []PolicyRule{
+			{Method: "POST", Path: "/api/products/*", Permissions: []string{"product:write"}},
+			{Path: "/api/admin/*", Roles: []string{"admin"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		claims token.UserClaims
+		ok     bool
+	}{
+		{"PermissionFromToken", "/api/products/1", token.UserClaims{Permissions: []string{"product:write"}}, true},
+		{"PermissionFromPolicyRole", "/api/products/1", token.UserClaims{Roles: []string{"editor"}}, true},
+		{"MissingPermission", "/api/products/1", token.UserClaims{Permissions: []string{"product:read"}}, false},
+		{"MissingRole", "/api/admin/keys", token.UserClaims{Roles: []string{"editor"}}, false},
+		{"NoRule", "/api/other", token.UserClaims{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := p.Authorize("POST", tt.path, &tt.claims)
+			assert.Equal(t, tt.ok, reason == "", reason)
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"ksv/rest-mikroservice/auth-service/token"
+)
+
+const (
+	ModeRemote = "remote"
+	ModeLocal  = "local"
+	ModeHybrid = "hybrid"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrAuthServiceFailure = errors.New("error contacting auth service")
+)
+
+// TokenVerifier перевіряє Bearer токен. Повертає ErrInvalidToken, якщо токен
+// недійсний, і ErrAuthServiceFailure, якщо auth-service недоступний.
+type TokenVerifier interface {
+	Verify(ctx context.Context, tokenStr string) (*token.UserClaims, error)
+}
+
+// APIKeyVerifier перевіряє API ключ користувача. Ключі зберігаються лише в
+// auth-service, тож перевіряються завжди віддалено, незалежно від режиму.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*token.UserClaims, error)
+}
+
+// RemoteVerifier перевіряє кожен токен запитом до auth-service: до
+// /oauth/introspect, якщо задано облікові дані клієнта, інакше до
+// /auth/validate, який повертає claims токена.
+type RemoteVerifier struct {
+	validateURL   string
+	introspectURL string
+	clientID      string
+	clientSecret  string
+	client        *http.Client
+}
+
+func NewRemoteVerifier(authServiceURL string, clientID string, clientSecret string) *RemoteVerifier {
+	return &RemoteVerifier{
+		validateURL:   authServiceURL + "/auth/validate",
+		introspectURL: authServiceURL + "/oauth/introspect",
+		clientID:      clientID,
+		clientSecret:  clientSecret,
+		client:        &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (v *RemoteVerifier) Verify(ctx context.Context, tokenStr string) (*token.UserClaims, error) {
+	if v.clientID != "" {
+		return v.introspect(ctx, tokenStr, tokenTypeAccess)
+	}
+	return v.validate(ctx, "Bearer "+tokenStr)
+}
+
+// VerifyAPIKey перевіряє API ключ тим самим ендпоінтом auth-service, що й токени.
+func (v *RemoteVerifier) VerifyAPIKey(ctx context.Context, key string) (*token.UserClaims, error) {
+	if v.clientID != "" {
+		return v.introspect(ctx, key, tokenTypeAPIKey)
+	}
+	return v.validate(ctx, "ApiKey "+key)
+}
+
+func (v *RemoteVerifier) validate(ctx context.Context, authorization string) (*token.UserClaims, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.validateURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthServiceFailure, err)
+	}
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthServiceFailure, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var claims token.UserClaims
+		if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+			return nil, fmt.Errorf("%w: error decoding claims: %v", ErrAuthServiceFailure, err)
+		}
+		return &claims, nil
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, fmt.Errorf("%w: status %d", ErrAuthServiceFailure, resp.StatusCode)
+	}
+}
+
+// Значення token_type у відповіді /oauth/introspect.
+const (
+	tokenTypeAccess = "access_token"
+	tokenTypeAPIKey = "api_key"
+)
+
+// introspectionResponse — поля відповіді /oauth/introspect (RFC 7662), які
+// потрібні gateway.
+type introspectionResponse struct {
+	Active      bool     `json:"active"`
+	TokenType   string   `json:"token_type"`
+	Subject     string   `json:"sub"`
+	Exp         int64    `json:"exp"`
+	Iat         int64    `json:"iat"`
+	TokenID     string   `json:"jti"`
+	ID          string   `json:"id"`
+	Login       string   `json:"login"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	ClientID    string   `json:"client_id"`
+	APIKeyID    string   `json:"api_key_id"`
+
+	Actor      *token.Actor `json:"act"`
+	TenantID   string       `json:"tenant_id"`
+	TenantRole string       `json:"tenant_role"`
+}
+
+// introspect приймає лише активний токен типу tokenType.
+func (v *RemoteVerifier) introspect(ctx context.Context, tokenStr string, tokenType string) (*token.UserClaims, error) {
+	form := url.Values{}
+	form.Set("token", tokenStr)
+	form.Set("token_type_hint", tokenType)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.introspectURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthServiceFailure, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(v.clientID, v.clientSecret)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthServiceFailure, err)
+	}
+	defer resp.Body.Close()
+
+	// 401 тут означає невірні облікові дані самого gateway, а не токена.
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: introspection status %d", ErrAuthServiceFailure, resp.StatusCode)
+	}
+	var result introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: error decoding introspection: %v", ErrAuthServiceFailure, err)
+	}
+	if !result.Active || result.TokenType != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &token.UserClaims{
+		ID:          result.ID,
+		Login:       result.Login,
+		Roles:       result.Roles,
+		Permissions: result.Permissions,
+		ClientID:    result.ClientID,
+		APIKeyID:    result.APIKeyID,
+		Actor:       result.Actor,
+		TenantID:    result.TenantID,
+		TenantRole:  result.TenantRole,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       result.TokenID,
+			Subject:  result.Subject,
+			IssuedAt: jwt.NewNumericDate(time.Unix(result.Iat, 0)),
+		},
+	}
+	if result.Exp != 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Unix(result.Exp, 0))
+	}
+	return claims, nil
+}
+
+// LocalVerifier перевіряє підпис і строк дії токена без звернення до
+// auth-service: HS256 — спільним секретом, асиметричні алгоритми — ключами з JWKS.
+// Відкликані токени LocalVerifier не розпізнає.
+type LocalVerifier struct {
+	secret   []byte
+	jwks     *JWKSCache
+	issuer   string
+	audience string
+}
+
+// NewLocalVerifier створює локальний верифікатор. Будь-яке з джерел ключів
+// може бути порожнім, але не обидва.
+func NewLocalVerifier(secret string, jwks *JWKSCache) *LocalVerifier {
+	v := &LocalVerifier{jwks: jwks}
+	if secret != "" {
+		v.secret = []byte(secret)
+	}
+	return v
+}
+
+// WithIssuer вмикає перевірку iss і aud токенів; порожнє значення вимикає
+// відповідну перевірку. Без aud gateway прийняв би й ID токен OpenID Connect,
+// підписаний тим самим ключем.
+func (v *LocalVerifier) WithIssuer(issuer string, audience string) *LocalVerifier {
+	v.issuer = issuer
+	v.audience = audience
+	return v
+}
+
+func (v *LocalVerifier) Verify(ctx context.Context, tokenStr string) (*token.UserClaims, error) {
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired()}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+	parsed, err := jwt.ParseWithClaims(tokenStr, &token.UserClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return v.key(ctx, t)
+	}, opts...)
+	if err != nil {
+		log.Println("Local token verification failed:", err)
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := parsed.Claims.(*token.UserClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (v *LocalVerifier) key(ctx context.Context, t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		if v.secret == nil {
+			return nil, fmt.Errorf("HMAC tokens are not accepted: no shared secret configured")
+		}
+		return v.secret, nil
+	}
+
+	if v.jwks == nil {
+		return nil, fmt.Errorf("asymmetric tokens are not accepted: no JWKS configured")
+	}
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token has no kid header")
+	}
+	return v.jwks.Key(ctx, kid, t.Method.Alg())
+}
+
+// HybridVerifier спершу перевіряє токен локально, а потім питає auth-service,
+// чи не відкликаний він. Відповіді кешуються на revocationTTL. Якщо auth-service
+// недоступний, локально перевірений токен приймається.
+type HybridVerifier struct {
+	local         *LocalVerifier
+	remote        *RemoteVerifier
+	revocationTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]revocationEntry
+}
+
+type revocationEntry struct {
+	valid     bool
+	checkedAt time.Time
+	expiresAt time.Time
+}
+
+func NewHybridVerifier(local *LocalVerifier, remote *RemoteVerifier, revocationTTL time.Duration) *HybridVerifier {
+	return &HybridVerifier{
+		local:         local,
+		remote:        remote,
+		revocationTTL: revocationTTL,
+		cache:         map[string]revocationEntry{},
+	}
+}
+
+func (v *HybridVerifier) Verify(ctx context.Context, tokenStr string) (*token.UserClaims, error) {
+	claims, err := v.local.Verify(ctx, tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	jti := claims.RegisteredClaims.ID
+	now := time.Now()
+
+	v.mu.Lock()
+	entry, ok := v.cache[jti]
+	v.mu.Unlock()
+	if ok && now.Sub(entry.checkedAt) < v.revocationTTL {
+		if !entry.valid {
+			return nil, ErrInvalidToken
+		}
+		return claims, nil
+	}
+
+	_, err = v.remote.Verify(ctx, tokenStr)
+	switch {
+	case errors.Is(err, ErrAuthServiceFailure):
+		log.Println("Revocation check skipped:", err)
+		return claims, nil
+	case err != nil && !errors.Is(err, ErrInvalidToken):
+		return nil, err
+	}
+
+	v.mu.Lock()
+	v.pruneLocked(now)
+	v.cache[jti] = revocationEntry{
+		valid:     err == nil,
+		checkedAt: now,
+		expiresAt: claims.ExpiresAt.Time,
+	}
+	v.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// pruneLocked видаляє з кешу записи токенів, строк дії яких минув.
+func (v *HybridVerifier) pruneLocked(now time.Time) {
+	for jti, entry := range v.cache {
+		if now.After(entry.expiresAt) {
+			delete(v.cache, jti)
+		}
+	}
+}
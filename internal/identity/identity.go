@@ -0,0 +1,146 @@
+// Package identity описує заголовки, якими gateway передає сервісам
+// автентифікованого користувача або сервісу (OAuth клієнта), і їх HMAC підпис.
+package identity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderUserID    = "X-User-ID"
+	HeaderLogin     = "X-User-Login"
+	HeaderRoles     = "X-User-Roles"
+	HeaderClientID  = "X-Client-ID"
+	HeaderTenantID  = "X-Tenant-ID"
+	HeaderTimestamp = "X-User-Timestamp"
+	HeaderSignature = "X-User-Signature"
+)
+
+// Headers — усі заголовки ідентичності. Gateway видаляє їх з вхідних запитів,
+// щоб клієнт не міг видати себе за іншого користувача.
+var Headers = []string{HeaderUserID, HeaderLogin, HeaderRoles, HeaderClientID, HeaderTenantID, HeaderTimestamp, HeaderSignature}
+
+var (
+	ErrMissing          = errors.New("identity headers are missing")
+	ErrInvalidSignature = errors.New("invalid identity signature")
+	ErrExpired          = errors.New("identity signature has expired")
+)
+
+// Identity — користувач, від імені якого gateway проксіює запит. Для токенів
+// сервісів (grant client_credentials) заповнений лише ClientID. TenantID —
+// організація, в якій діє токен; порожній, якщо користувач діє поза
+// організаціями.
+type Identity struct {
+	UserID   string
+	Login    string
+	Roles    []string
+	ClientID string
+	TenantID string
+}
+
+// IsClient повідомляє, що запит зроблено сервісом, а не користувачем.
+func (id *Identity) IsClient() bool {
+	return id.ClientID != ""
+}
+
+func (id *Identity) HasRole(role string) bool {
+	for _, r := range id.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Strip видаляє заголовки ідентичності із запиту.
+func Strip(h http.Header) {
+	for _, name := range Headers {
+		h.Del(name)
+	}
+}
+
+// Set записує ідентичність у заголовки. Якщо secret не порожній, додається
+// підпис, який сервіси перевіряють функцією FromHeaders.
+func Set(h http.Header, id *Identity, secret []byte, now time.Time) {
+	Strip(h)
+	h.Set(HeaderUserID, id.UserID)
+	h.Set(HeaderLogin, id.Login)
+	h.Set(HeaderRoles, strings.Join(id.Roles, ","))
+	if id.ClientID != "" {
+		h.Set(HeaderClientID, id.ClientID)
+	}
+	if id.TenantID != "" {
+		h.Set(HeaderTenantID, id.TenantID)
+	}
+	if len(secret) == 0 {
+		return
+	}
+
+	ts := strconv.FormatInt(now.Unix(), 10)
+	h.Set(HeaderTimestamp, ts)
+	h.Set(HeaderSignature, sign(secret, id, ts))
+}
+
+// FromHeaders читає ідентичність із заголовків. Якщо secret не порожній,
+// підпис обов'язковий і має бути не старшим за maxAge.
+func FromHeaders(h http.Header, secret []byte, maxAge time.Duration, now time.Time) (*Identity, error) {
+	userID, clientID := h.Get(HeaderUserID), h.Get(HeaderClientID)
+	if userID == "" && clientID == "" {
+		return nil, ErrMissing
+	}
+
+	id := &Identity{
+		UserID:   userID,
+		Login:    h.Get(HeaderLogin),
+		ClientID: clientID,
+		TenantID: h.Get(HeaderTenantID),
+	}
+	if roles := h.Get(HeaderRoles); roles != "" {
+		id.Roles = strings.Split(roles, ",")
+	}
+	if len(secret) == 0 {
+		return id, nil
+	}
+
+	ts := h.Get(HeaderTimestamp)
+	signedAt, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	expected := sign(secret, id, ts)
+	if !hmac.Equal([]byte(expected), []byte(h.Get(HeaderSignature))) {
+		return nil, ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(signedAt, 0)); age > maxAge || age < -maxAge {
+		return nil, ErrExpired
+	}
+	return id, nil
+}
+
+// sign підписує значення заголовків, розділені переведенням рядка, яке не
+// може з'явитися в самих значеннях заголовків.
+func sign(secret []byte, id *Identity, ts string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{id.UserID, id.Login, strings.Join(id.Roles, ","), id.ClientID, id.TenantID, ts}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext повертає ідентичність із контексту або nil.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(contextKey{}).(*Identity)
+	return id
+}
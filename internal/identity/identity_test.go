@@ -0,0 +1,105 @@
+package identity
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSetFromHeaders_RoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name string
+		id   Identity
+	}{
+		{"User", Identity{UserID: "u1", Login: "alice", Roles: []string{"admin", "user"}, TenantID: "t1"}},
+		{"UserWithoutRoles", Identity{UserID: "u2", Login: "bob"}},
+		{"Client", Identity{ClientID: "billing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			Set(h, &tt.id, testSecret, now)
+			assert.NotEmpty(t, h.Get(HeaderSignature))
+
+			got, err := FromHeaders(h, testSecret, time.Minute, now.Add(30*time.Second))
+			require.NoError(t, err)
+			assert.Equal(t, tt.id, *got)
+		})
+	}
+}
+
+func TestFromHeaders_Rejects(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signed := func() http.Header {
+		h := http.Header{}
+		Set(h, &Identity{UserID: "u1", Login: "alice", Roles: []string{"user"}}, testSecret, now)
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		modify func(h http.Header)
+		secret []byte
+		at     time.Time
+		want   error
+	}{
+		{"TamperedUserID", func(h http.Header) { h.Set(HeaderUserID, "u2") }, testSecret, now, ErrInvalidSignature},
+		{"TamperedLogin", func(h http.Header) { h.Set(HeaderLogin, "root") }, testSecret, now, ErrInvalidSignature},
+		{"TamperedRoles", func(h http.Header) { h.Set(HeaderRoles, "user,admin") }, testSecret, now, ErrInvalidSignature},
+		{"AddedClientID", func(h http.Header) { h.Set(HeaderClientID, "billing") }, testSecret, now, ErrInvalidSignature},
+		{"AddedTenantID", func(h http.Header) { h.Set(HeaderTenantID, "t2") }, testSecret, now, ErrInvalidSignature},
+		{"TamperedTimestamp", func(h http.Header) { h.Set(HeaderTimestamp, "1700000001") }, testSecret, now, ErrInvalidSignature},
+		{"MissingSignature", func(h http.Header) { h.Del(HeaderSignature) }, testSecret, now, ErrInvalidSignature},
+		{"MissingTimestamp", func(h http.Header) { h.Del(HeaderTimestamp) }, testSecret, now, ErrInvalidSignature},
+		{"WrongSecret", func(h http.Header) {}, []byte("another-secret"), now, ErrInvalidSignature},
+		{"Expired", func(h http.Header) {}, testSecret, now.Add(2 * time.Minute), ErrExpired},
+		{"FromFuture", func(h http.Header) {}, testSecret, now.Add(-2 * time.Minute), ErrExpired},
+		{"NoIdentity", func(h http.Header) { h.Del(HeaderUserID) }, testSecret, now, ErrMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := signed()
+			tt.modify(h)
+			_, err := FromHeaders(h, tt.secret, time.Minute, tt.at)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestStrip_RemovesSpoofedHeaders(t *testing.T) {
+	h := http.Header{}
+	for _, name := range Headers {
+		h.Set(name, "spoofed")
+	}
+	h.Set("Authorization", "Bearer token")
+
+	Strip(h)
+	for _, name := range Headers {
+		assert.Empty(t, h.Values(name), name)
+	}
+	assert.Equal(t, "Bearer token", h.Get("Authorization"))
+}
+
+func TestSet_ReplacesSpoofedHeaders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	h := http.Header{}
+	h.Set(HeaderClientID, "spoofed")
+	h.Set(HeaderTenantID, "spoofed")
+	h.Add(HeaderRoles, "admin")
+
+	Set(h, &Identity{UserID: "u1", Login: "alice", Roles: []string{"user"}}, testSecret, now)
+	assert.Empty(t, h.Values(HeaderClientID))
+	assert.Empty(t, h.Values(HeaderTenantID))
+	assert.Equal(t, []string{"user"}, h.Values(HeaderRoles))
+
+	got, err := FromHeaders(h, testSecret, time.Minute, now)
+	require.NoError(t, err)
+	assert.Empty(t, got.ClientID)
+	assert.Empty(t, got.TenantID)
+}
@@ -23,8 +23,20 @@ func InitDB(filepath string) {
         price REAL NOT NULL,
         quantity INTEGER NOT NULL,
         created_at TIMESTAMP NOT NULL,
-        updated_at TIMESTAMP
+        updated_at TIMESTAMP,
+        tenant_id TEXT NOT NULL DEFAULT ''
     );
     `
 	DB.MustExec(schema)
+
+	// Таблиця, створена до появи організацій, отримує колонку tenant_id:
+	// наявні продукти залишаються поза організаціями.
+	var hasTenant bool
+	if err := DB.Get(&hasTenant, `SELECT COUNT(*) > 0 FROM pragma_table_info('products') WHERE name = 'tenant_id'`); err != nil {
+		log.Fatalf("Failed to inspect products table: %v", err)
+	}
+	if !hasTenant {
+		DB.MustExec(`ALTER TABLE products ADD COLUMN tenant_id TEXT NOT NULL DEFAULT ''`)
+	}
+	DB.MustExec(`CREATE INDEX IF NOT EXISTS idx_products_tenant ON products (tenant_id, created_at)`)
 }
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -10,6 +11,8 @@ import (
 	"ksv/rest-mikroservice/product-service/models"
 )
 
+// ProductRepository зберігає продукти. Кожен запит обмежений організацією
+// tenantID: продукти інших організацій для нього не існують.
 type ProductRepository struct {
 	db *DBWrapper
 }
@@ -26,8 +29,8 @@ func NewProductRepository(db *sqlx.DB) *ProductRepository {
 
 func (r *ProductRepository) CreateProduct(ctx context.Context, product *models.Product) error {
 	query := `
-        INSERT INTO products (id, name, price, quantity, created_at, updated_at)
-        VALUES (:id, :name, :price, :quantity, :created_at, :updated_at)
+        INSERT INTO products (id, name, price, quantity, created_at, updated_at, tenant_id)
+        VALUES (:id, :name, :price, :quantity, :created_at, :updated_at, :tenant_id)
     `
 	_, err := r.db.NamedExecContext(ctx, query, product)
 	if err != nil {
@@ -36,30 +39,33 @@ func (r *ProductRepository) CreateProduct(ctx context.Context, product *models.P
 	return nil
 }
 
-func (r *ProductRepository) GetProductByID(ctx context.Context, id string) (*models.Product, error) {
+func (r *ProductRepository) GetProductByID(ctx context.Context, tenantID string, id string) (*models.Product, error) {
 	var product models.Product
-	query := `SELECT * FROM products WHERE id = ?`
-	err := r.db.GetContext(ctx, &product, query, id)
+	query := `SELECT * FROM products WHERE id = ? AND tenant_id = ?`
+	err := r.db.GetContext(ctx, &product, query, id, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product by id: %w", err)
 	}
 	return &product, nil
 }
 
-func (r *ProductRepository) GetProducts(ctx context.Context, limit int) ([]models.Product, error) {
+func (r *ProductRepository) GetProducts(ctx context.Context, tenantID string, limit int) ([]models.Product, error) {
 	var products []models.Product
 	query := `
         SELECT * FROM products 
+        WHERE tenant_id = ?
         ORDER BY created_at DESC 
         LIMIT ?
     `
-	err := r.db.SelectContext(ctx, &products, query, limit)
+	err := r.db.SelectContext(ctx, &products, query, tenantID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products: %w", err)
 	}
 	return products, nil
 }
 
+// UpdateProduct оновлює продукт product.ID організації product.TenantID.
+// Якщо такого продукту немає, повертає помилку sql.ErrNoRows.
 func (r *ProductRepository) UpdateProduct(ctx context.Context, product *models.Product) error {
 	now := time.Now()
 	product.UpdatedAt = &now
@@ -70,20 +76,33 @@ func (r *ProductRepository) UpdateProduct(ctx context.Context, product *models.P
             price = :price,
             quantity = :quantity,
             updated_at = :updated_at
-        WHERE id = :id
+        WHERE id = :id AND tenant_id = :tenant_id
     `
-	_, err := r.db.NamedExecContext(ctx, query, product)
+	res, err := r.db.NamedExecContext(ctx, query, product)
 	if err != nil {
 		return fmt.Errorf("failed to update product: %w", err)
 	}
-	return nil
+	return checkAffected(res, "failed to update product")
 }
 
-func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
-	query := `DELETE FROM products WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, id)
+// DeleteProduct видаляє продукт організації tenantID. Якщо такого продукту
+// немає, повертає помилку sql.ErrNoRows.
+func (r *ProductRepository) DeleteProduct(ctx context.Context, tenantID string, id string) error {
+	query := `DELETE FROM products WHERE id = ? AND tenant_id = ?`
+	res, err := r.db.ExecContext(ctx, query, id, tenantID)
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
+	return checkAffected(res, "failed to delete product")
+}
+
+func checkAffected(res sql.Result, msg string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", msg, sql.ErrNoRows)
+	}
 	return nil
 }
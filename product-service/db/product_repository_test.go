@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -25,7 +26,8 @@ func setupTestDB(t *testing.T) *sqlx.DB {
 		price REAL NOT NULL,
 		quantity INTEGER NOT NULL,
 		created_at DATETIME NOT NULL,
-		updated_at DATETIME
+		updated_at DATETIME,
+		tenant_id TEXT NOT NULL DEFAULT ''
 	);`
 	_, err = db.Exec(schema)
 	require.NoError(t, err)
@@ -52,7 +54,7 @@ func TestProductRepository_CRUD(t *testing.T) {
 	})
 
 	t.Run("GetProductByID", func(t *testing.T) {
-		fetchedProduct, err := repo.GetProductByID(ctx, product.ID)
+		fetchedProduct, err := repo.GetProductByID(ctx, "", product.ID)
 		require.NoError(t, err)
 		assert.Equal(t, product.Name, fetchedProduct.Name)
 	})
@@ -64,16 +66,46 @@ func TestProductRepository_CRUD(t *testing.T) {
 		err := repo.UpdateProduct(ctx, product)
 		require.NoError(t, err)
 
-		updatedProduct, err := repo.GetProductByID(ctx, product.ID)
+		updatedProduct, err := repo.GetProductByID(ctx, "", product.ID)
 		require.NoError(t, err)
 		assert.Equal(t, "Updated Product", updatedProduct.Name)
 	})
 
 	t.Run("DeleteProduct", func(t *testing.T) {
-		err := repo.DeleteProduct(ctx, product.ID)
+		err := repo.DeleteProduct(ctx, "", product.ID)
 		require.NoError(t, err)
 
-		_, err = repo.GetProductByID(ctx, product.ID)
+		_, err = repo.GetProductByID(ctx, "", product.ID)
 		require.Error(t, err)
 	})
 }
+
+func TestProductRepository_TenantIsolation(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	repo := NewProductRepository(db)
+
+	acme := &models.Product{ID: uuid.NewString(), Name: "Acme Product", Price: 10, Quantity: 1, CreatedAt: time.Now(), TenantID: "tenant-acme"}
+	globex := &models.Product{ID: uuid.NewString(), Name: "Globex Product", Price: 20, Quantity: 2, CreatedAt: time.Now(), TenantID: "tenant-globex"}
+	require.NoError(t, repo.CreateProduct(ctx, acme))
+	require.NoError(t, repo.CreateProduct(ctx, globex))
+
+	products, err := repo.GetProducts(ctx, "tenant-acme", 10)
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	assert.Equal(t, acme.ID, products[0].ID)
+	assert.Equal(t, "tenant-acme", products[0].TenantID)
+
+	_, err = repo.GetProductByID(ctx, "tenant-acme", globex.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	foreign := *globex
+	foreign.TenantID = "tenant-acme"
+	foreign.Name = "Hijacked"
+	require.ErrorIs(t, repo.UpdateProduct(ctx, &foreign), sql.ErrNoRows)
+	require.ErrorIs(t, repo.DeleteProduct(ctx, "tenant-acme", globex.ID), sql.ErrNoRows)
+
+	stored, err := repo.GetProductByID(ctx, "tenant-globex", globex.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Globex Product", stored.Name)
+}
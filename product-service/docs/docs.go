@@ -17,7 +17,7 @@ const docTemplate = `{
     "paths": {
         "/api/product": {
             "get": {
-                "description": "Якщо передано id — повертає один продукт, інакше список з обмеженням limit",
+                "description": "Якщо передано id — повертає один продукт, інакше список з обмеженням limit. Видно лише продукти організації, від імені якої зроблено запит.",
                 "produces": [
                     "application/json"
                 ],
@@ -64,7 +64,7 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Оновлює дані продукту за ID",
+                "description": "Оновлює дані продукту за ID в організації, від імені якої зроблено запит",
                 "consumes": [
                     "application/json"
                 ],
@@ -106,6 +106,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "404": {
+                        "description": "Продукт не знайдено",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Помилка сервера",
                         "schema": {
@@ -115,7 +121,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Додає новий продукт у базу даних",
+                "description": "Додає новий продукт в організацію, від імені якої зроблено запит",
                 "consumes": [
                     "application/json"
                 ],
@@ -159,7 +165,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Видаляє продукт за ID",
+                "description": "Видаляє продукт за ID в організації, від імені якої зроблено запит",
                 "produces": [
                     "application/json"
                 ],
@@ -183,6 +189,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
+                    "404": {
+                        "description": "Продукт не знайдено",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Помилка сервера",
                         "schema": {
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ksv/rest-mikroservice/internal/identity"
+)
+
+// identityMaxAge — скільки часу підпис заголовків X-User-* вважається дійсним.
+const identityMaxAge = 5 * time.Minute
+
+// IdentityMiddleware читає користувача із заголовків, виставлених gateway, і
+// кладе його в контекст запиту (identity.FromContext). Якщо secret не
+// порожній, запити без дійсного підпису відхиляються; інакше заголовки
+// приймаються як є, і сервіс має бути доступний лише через gateway.
+func IdentityMiddleware(secret []byte, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/swagger/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := identity.FromHeaders(r.Header, secret, identityMaxAge, time.Now())
+		if err != nil {
+			if len(secret) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			log.Println("Rejected request identity:", err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if id.IsClient() {
+			log.Println(r.Method, r.URL.Path, "client:", id.ClientID)
+		} else {
+			log.Println(r.Method, r.URL.Path, "user:", id.Login)
+		}
+		next.ServeHTTP(w, r.WithContext(identity.NewContext(r.Context(), id)))
+	})
+}
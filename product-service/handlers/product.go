@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"ksv/rest-mikroservice/internal/identity"
 	"ksv/rest-mikroservice/product-service/db"
 	"ksv/rest-mikroservice/product-service/models"
 
@@ -21,9 +24,18 @@ func NewProductHandler(repo *db.ProductRepository) *ProductHandler {
 	return &ProductHandler{repo: repo}
 }
 
+// tenantID повертає організацію, від імені якої зроблено запит. Запити без
+// організації працюють з продуктами поза організаціями.
+func tenantID(r *http.Request) string {
+	if id := identity.FromContext(r.Context()); id != nil {
+		return id.TenantID
+	}
+	return ""
+}
+
 // Create godoc
 // @Summary Створення нового продукту
-// @Description Додає новий продукт у базу даних
+// @Description Додає новий продукт в організацію, від імені якої зроблено запит
 // @Tags product
 // @Accept json
 // @Produce json
@@ -41,6 +53,7 @@ func (h *ProductHandler) Create() http.HandlerFunc {
 		}
 		p.ID = uuid.NewString()
 		p.CreatedAt = time.Now()
+		p.TenantID = tenantID(r)
 		if err := h.repo.CreateProduct(context.Background(), &p); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -52,7 +65,7 @@ func (h *ProductHandler) Create() http.HandlerFunc {
 
 // Get godoc
 // @Summary Отримання продукту або списку продуктів
-// @Description Якщо передано id — повертає один продукт, інакше список з обмеженням limit
+// @Description Якщо передано id — повертає один продукт, інакше список з обмеженням limit. Видно лише продукти організації, від імені якої зроблено запит.
 // @Tags product
 // @Produce json
 // @Param id query string false "ID продукту"
@@ -66,7 +79,7 @@ func (h *ProductHandler) Get() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.URL.Query().Get("id")
 		if id != "" {
-			product, err := h.repo.GetProductByID(context.Background(), id)
+			product, err := h.repo.GetProductByID(context.Background(), tenantID(r), id)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusNotFound)
 				return
@@ -80,7 +93,7 @@ func (h *ProductHandler) Get() http.HandlerFunc {
 				limit = v
 			}
 		}
-		products, err := h.repo.GetProducts(context.Background(), limit)
+		products, err := h.repo.GetProducts(context.Background(), tenantID(r), limit)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -91,7 +104,7 @@ func (h *ProductHandler) Get() http.HandlerFunc {
 
 // Update godoc
 // @Summary Оновлення продукту
-// @Description Оновлює дані продукту за ID
+// @Description Оновлює дані продукту за ID в організації, від імені якої зроблено запит
 // @Tags product
 // @Accept json
 // @Produce json
@@ -99,6 +112,7 @@ func (h *ProductHandler) Get() http.HandlerFunc {
 // @Param product body models.Product true "Оновлені дані продукту"
 // @Success 200 {object} models.SuccessResponse
 // @Failure 400 {string} string "Некоректний запит"
+// @Failure 404 {string} string "Продукт не знайдено"
 // @Failure 500 {string} string "Помилка сервера"
 // @Router /api/product [put]
 func (h *ProductHandler) Update() http.HandlerFunc {
@@ -110,7 +124,12 @@ func (h *ProductHandler) Update() http.HandlerFunc {
 			return
 		}
 		p.ID = id
+		p.TenantID = tenantID(r)
 		if err := h.repo.UpdateProduct(context.Background(), &p); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -120,17 +139,22 @@ func (h *ProductHandler) Update() http.HandlerFunc {
 
 // Delete godoc
 // @Summary Видалення продукту
-// @Description Видаляє продукт за ID
+// @Description Видаляє продукт за ID в організації, від імені якої зроблено запит
 // @Tags product
 // @Produce json
 // @Param id query string true "ID продукту"
 // @Success 200 {object} models.SuccessResponse
+// @Failure 404 {string} string "Продукт не знайдено"
 // @Failure 500 {string} string "Помилка сервера"
 // @Router /api/product [delete]
 func (h *ProductHandler) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.URL.Query().Get("id")
-		if err := h.repo.DeleteProduct(context.Background(), id); err != nil {
+		if err := h.repo.DeleteProduct(context.Background(), tenantID(r), id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
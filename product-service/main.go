@@ -15,6 +15,8 @@ import (
 
 	_ "ksv/rest-mikroservice/product-service/docs"
 
+	"github.com/ianschenck/envflag"
+	"github.com/joho/godotenv"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	_ "github.com/swaggo/files"
@@ -23,6 +25,17 @@ import (
 const port = ":8082"
 
 func main() {
+	err := godotenv.Load(".env")
+	if err != nil {
+		log.Println("Warning: .env file not found, relying on system environment variables")
+	}
+
+	var identityKey = envflag.String("IDENTITY_SIGNING_KEY", "", "Спільний з gateway секрет для перевірки заголовків X-User-*")
+	envflag.Parse()
+	if *identityKey == "" {
+		log.Println("Warning: IDENTITY_SIGNING_KEY is not set, identity headers are trusted without verification")
+	}
+
 	db.InitDB("./data/products.db")
 	defer db.DB.Close()
 
@@ -34,7 +47,7 @@ func main() {
 
 	server := http.Server{
 		Addr:    port,
-		Handler: router,
+		Handler: handlers.IdentityMiddleware([]byte(*identityKey), router),
 	}
 
 	fmt.Printf("Product service starting on port %s...\n", port)
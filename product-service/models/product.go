@@ -9,6 +9,9 @@ type Product struct {
 	Quantity  int        `db:"quantity" json:"quantity"`
 	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
 	UpdatedAt *time.Time `db:"updated_at" json:"updatedAt,omitempty"`
+	// TenantID — організація, якій належить продукт; порожній для продуктів
+	// поза організаціями. Задається з ідентичності запиту, а не з тіла.
+	TenantID string `db:"tenant_id" json:"-"`
 }
 
 type ErrorResponse struct {